@@ -2,85 +2,220 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/gin-gonic/gin"
 	"github.com/jhonathanssegura/ticket-reservation/internal/awsconfig"
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
 	"github.com/jhonathanssegura/ticket-reservation/internal/db"
 	"github.com/jhonathanssegura/ticket-reservation/internal/handler"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
 	"github.com/jhonathanssegura/ticket-reservation/internal/queue"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
 	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
 )
 
 func main() {
-	cfg, err := awsconfig.LoadAWSConfig()
+	appCfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Error cargando configuración AWS: %v", err)
+		log.Fatalf("Error cargando configuración: %v", err)
 	}
 
-	queueURL := "http://localhost:4566/000000000000/ticket-queue"
-	bucketName := "ticket-bucket"
-
-	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) { o.UsePathStyle = true })
+	// Antes de crear los clientes de AWS, para que sus llamadas queden trazadas
+	shutdownTracing, err := tracing.Setup(context.TODO(), appCfg.Tracing, "ticket-reservation-api")
+	if err != nil {
+		log.Fatalf("Error configurando trazas: %v", err)
+	}
 
-	log.Println("Verificando bucket S3...")
-	_, err = s3Client.HeadBucket(context.TODO(), &s3.HeadBucketInput{
-		Bucket: aws.String(bucketName),
-	})
+	cfg, err := awsconfig.LoadAWSConfig(context.TODO(), appCfg.AWS)
 	if err != nil {
-		log.Printf("Bucket S3 '%s' no existe, creándolo...", bucketName)
-		_, err = s3Client.CreateBucket(context.TODO(), &s3.CreateBucketInput{
-			Bucket: aws.String(bucketName),
-		})
-		if err != nil {
-			log.Fatalf("Error creando bucket S3: %v", err)
-		}
-		log.Printf("Bucket S3 '%s' creado exitosamente", bucketName)
-	} else {
-		log.Printf("Bucket S3 '%s' ya existe", bucketName)
+		log.Fatalf("Error cargando configuración AWS: %v", err)
 	}
 
 	sqsClient := &queue.SQSClient{
-		Client:   sqs.NewFromConfig(cfg),
-		QueueURL: queueURL,
+		Client:        awsconfig.NewSQSClient(cfg, appCfg.AWS),
+		QueueURL:      appCfg.SQS.QueueURL,
+		DeadLetterURL: appCfg.SQS.DeadLetterURL,
+		Timeout:       appCfg.AWS.CallTimeout,
+	}
+
+	blobs, err := storage.NewBlobStore(context.TODO(), appCfg, awsconfig.NewS3Client(cfg, appCfg.AWS, appCfg.Storage.S3PathStyle))
+	if err != nil {
+		log.Fatalf("Error configurando almacenamiento: %v", err)
 	}
 
-	storageClient := &storage.S3Client{
-		Client:     s3Client,
-		BucketName: bucketName,
+	dynamoClient := db.NewDynamoClient(awsconfig.NewDynamoDBClient(cfg, appCfg.AWS), appCfg.DynamoDB.Tables())
+	dynamoClient.Timeout = appCfg.AWS.CallTimeout
+	var repo db.Repository = dynamoClient
+	if appCfg.Repository == config.RepositoryMemory {
+		log.Println("⚠️  Usando repositorio en memoria: los datos se pierden al reiniciar")
+		repo = db.NewMemoryRepository()
+	}
+
+	qrSigner, err := service.LoadQRSignerFromConfig(appCfg.QR)
+	if err != nil {
+		log.Fatalf("Error cargando claves de firma de QR: %v", err)
+	}
+	qrService := service.NewQRService(qrSigner)
+
+	handlerReserva := handler.NewReservationHandler(sqsClient, blobs, repo, qrService)
+	artifacts := service.NewTicketArtifactService(blobs, qrService)
+	handlerTicket := handler.NewTicketHandler(repo, artifacts)
+	handlerQR := handler.NewQRHandler(repo, blobs, qrService)
+	handlerDocument := handler.NewDocumentHandler(repo, blobs, qrService)
+	handlerEvent := handler.NewEventHandler(repo)
+	reconciler := service.NewArtifactReconciler(repo, artifacts)
+	reconciler.GracePeriod = appCfg.Artifacts.ReconcileGracePeriod
+	handlerAdmin := handler.NewAdminHandler(sqsClient, reconciler)
+	bundleSigner, err := service.LoadBundleSignerFromConfig(appCfg.Checkin)
+	if err != nil {
+		log.Fatalf("Error cargando clave de firma de bundles: %v", err)
+	}
+	handlerCheckin := handler.NewCheckinHandler(repo, qrService, bundleSigner)
+	handlerCheckin.MaxOfflineAge = appCfg.Checkin.MaxOfflineAge
+	passSigner, err := service.LoadPassSignerFromConfig(appCfg.Wallet.Apple)
+	if err != nil {
+		log.Fatalf("Error cargando certificados de Apple Wallet: %v", err)
+	}
+	googleWallet, err := service.LoadGoogleWalletFromConfig(appCfg.Wallet.Google)
+	if err != nil {
+		log.Fatalf("Error cargando cuenta de servicio de Google Wallet: %v", err)
 	}
+	handlerWallet := handler.NewWalletHandler(repo, qrService, passSigner, googleWallet)
+
+	idempotency := handler.Idempotency(repo, 24*time.Hour)
 
-	dynamoClient := &db.DynamoClient{
-		Client: dynamodb.NewFromConfig(cfg),
+	health := service.NewHealthChecker()
+	health.Add(appCfg.Repository, repo.Ping)
+	health.Add(appCfg.Storage.Backend, blobs.Ping)
+	if appCfg.SQS.Configured() {
+		health.Add("sqs", sqsClient.Ping)
 	}
+	handlerHealth := handler.NewHealthHandler(health)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	handlerReserva := handler.NewReservationHandler(sqsClient, storageClient, dynamoClient)
-	handlerTicket := handler.NewTicketHandler(dynamoClient)
-	handlerQR := handler.NewQRHandler(dynamoClient, storageClient)
+	sweeper := service.NewHoldSweeper(repo, time.Minute)
+	sweeper.Artifacts = artifacts
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sweeper.Run(ctx)
+	}()
+
+	ticketStats := service.NewTicketStats(repo, time.Minute)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticketStats.Run(ctx)
+	}()
 
 	r := gin.Default()
+	r.Use(metrics.Middleware(), tracing.Middleware("ticket-reservation-api"))
+
+	// Sondeos del orquestador y métricas de Prometheus, fuera de /api
+	r.GET("/healthz", handlerHealth.Liveness)
+	r.GET("/readyz", handlerHealth.Readiness)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	api := r.Group("/api")
 	{
 		// Ticket management endpoints
 		api.GET("/tickets", handlerTicket.ListTickets)
 		api.GET("/tickets/:id", handlerTicket.GetTicket)
-		api.POST("/tickets", handlerTicket.CreateTicket)
+		api.POST("/tickets", idempotency, handlerTicket.CreateTicket)
 		api.PUT("/tickets/:id", handlerTicket.UpdateTicket)
 		api.DELETE("/tickets/:id", handlerTicket.DeleteTicket)
-		// Reservation endpoint
-		api.POST("/reservations", handlerReserva.ReserveTicket)
+		// Ticket status transition endpoints
+		api.POST("/tickets/:id/confirm", handlerTicket.ConfirmTicket)
+		api.POST("/tickets/:id/cancel", handlerTicket.CancelTicket)
+		// Event management endpoints
+		api.GET("/events", handlerEvent.ListEvents)
+		api.GET("/events/:id", handlerEvent.GetEvent)
+		api.POST("/events", handlerEvent.CreateEvent)
+		api.PUT("/events/:id", handlerEvent.UpdateEvent)
+		api.DELETE("/events/:id", handlerEvent.DeleteEvent)
+		// Reservation endpoints
+		api.POST("/reservations", idempotency, handlerReserva.ReserveTicket)
+		api.POST("/reservations/:id/confirm", handlerReserva.ConfirmReservation)
 		// QR code endpoints
 		api.GET("/tickets/:id/qr", handlerQR.GetTicketQR)
 		api.GET("/tickets/:id/qr-s3", handlerQR.GetTicketQRFromS3)
+		api.GET("/tickets/:id/qr-url", handlerQR.GetTicketQRURL)
 		api.POST("/qr/validate", handlerQR.ValidateQR)
+		api.POST("/qr/decode", handlerQR.DecodeQR)
 		api.POST("/tickets/:id/qr", handlerQR.GenerateQRForTicket)
+		api.GET("/tickets/:id/pdf", handlerDocument.GetTicketPDF)
+		api.GET("/tickets/:id/pdf-url", handlerDocument.GetTicketPDFURL)
+
+		// Descarga de archivos del almacenamiento local (las URLs de S3 apuntan al bucket)
+		if local, ok := blobs.(*storage.LocalStore); ok {
+			api.GET("/blobs/*key", handler.NewBlobHandler(local).ServeBlob)
+		}
+		api.GET("/tickets/:id/pkpass", handlerWallet.GetApplePass)
+		api.GET("/tickets/:id/google-wallet", handlerWallet.GetGoogleWalletJWT)
+		// Check-in endpoints
+		api.POST("/checkins", handlerCheckin.CheckIn)
+		api.POST("/checkins/sync", handlerCheckin.SyncCheckIns)
+		api.GET("/checkins/bundle-keys", handlerCheckin.BundleKeys)
+		api.GET("/events/:id/checkin-bundle", handlerCheckin.ExportBundle)
+	}
+
+	// Administración: requiere ADMIN_TOKEN
+	admin := api.Group("/admin", handler.AdminAuth(appCfg.Server.AdminToken))
+	{
+		// Dead-letter queue admin endpoints
+		admin.GET("/dlq/messages", handlerAdmin.ListDeadLetters)
+		admin.GET("/dlq/messages/:id", handlerAdmin.GetDeadLetter)
+		admin.POST("/dlq/messages/:id/redrive", handlerAdmin.RedriveDeadLetter)
+		admin.POST("/dlq/redrive", handlerAdmin.RedriveAllDeadLetters)
+		admin.DELETE("/dlq/messages", handlerAdmin.PurgeDeadLetters)
+		admin.POST("/artifacts/reconcile", handlerAdmin.ReconcileArtifacts)
+	}
+	if appCfg.Server.AdminToken == "" {
+		log.Println("⚠️  ADMIN_TOKEN no definido: los endpoints /api/admin quedan deshabilitados")
 	}
 
-	log.Println("🚀 Iniciando servidor en puerto 8080...")
-	r.Run(":8080")
+	srv := &http.Server{
+		Addr:              appCfg.Server.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Printf("🚀 Iniciando servidor en %s...", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error iniciando servidor: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("Deteniendo servidor, esperando peticiones en curso...")
+
+	// Shutdown deja de aceptar conexiones y espera a las peticiones en curso.
+	// Vencido el plazo, Close corta las conexiones y cancela sus contextos.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), appCfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Las peticiones en curso no terminaron a tiempo: %v", err)
+		srv.Close()
+	}
+	wg.Wait()
+	// Envía los spans que quedan en el buffer antes de salir
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Printf("Error enviando trazas pendientes: %v", err)
+	}
+	log.Println("Servidor detenido")
 }
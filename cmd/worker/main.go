@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/awsconfig"
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
+	"github.com/jhonathanssegura/ticket-reservation/internal/queue"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
+	"github.com/jhonathanssegura/ticket-reservation/internal/worker"
+)
+
+func main() {
+	appCfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error cargando configuración: %v", err)
+	}
+	if appCfg.SQS.QueueURL == "" {
+		log.Fatal("Error cargando configuración: el worker requiere sqs.queue_url")
+	}
+	// Los QR que genera el worker los valida la API, así que no puede usar una
+	// clave temporal propia
+	if appCfg.QR.SigningKeys == "" {
+		log.Fatal("Error cargando configuración: el worker requiere qr.signing_keys")
+	}
+
+	// Antes de crear los clientes de AWS, para que sus llamadas queden trazadas
+	shutdownTracing, err := tracing.Setup(context.Background(), appCfg.Tracing, "ticket-reservation-worker")
+	if err != nil {
+		log.Fatalf("Error configurando trazas: %v", err)
+	}
+
+	cfg, err := awsconfig.LoadAWSConfig(context.Background(), appCfg.AWS)
+	if err != nil {
+		log.Fatalf("Error cargando configuración AWS: %v", err)
+	}
+
+	sqsClient := &queue.SQSClient{
+		Client:            awsconfig.NewSQSClient(cfg, appCfg.AWS),
+		QueueURL:          appCfg.SQS.QueueURL,
+		DeadLetterURL:     appCfg.SQS.DeadLetterURL,
+		VisibilityTimeout: appCfg.SQS.VisibilityTimeout,
+		Timeout:           appCfg.AWS.CallTimeout,
+	}
+
+	blobs, err := storage.NewBlobStore(context.Background(), appCfg, awsconfig.NewS3Client(cfg, appCfg.AWS, appCfg.Storage.S3PathStyle))
+	if err != nil {
+		log.Fatalf("Error configurando almacenamiento: %v", err)
+	}
+
+	dynamoClient := db.NewDynamoClient(awsconfig.NewDynamoDBClient(cfg, appCfg.AWS), appCfg.DynamoDB.Tables())
+	dynamoClient.Timeout = appCfg.AWS.CallTimeout
+
+	qrSigner, err := service.LoadQRSignerFromConfig(appCfg.QR)
+	if err != nil {
+		log.Fatalf("Error cargando claves de firma de QR: %v", err)
+	}
+	artifacts := service.NewTicketArtifactService(blobs, service.NewQRService(qrSigner))
+	reservationWorker := worker.NewReservationWorker(sqsClient, dynamoClient, artifacts, service.NewLogNotifier(),
+		time.Duration(appCfg.SQS.VisibilityTimeout)*time.Second)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	relay := worker.NewOutboxRelay(dynamoClient, sqsClient, 2*time.Second)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		relay.Run(ctx)
+	}()
+
+	// Reconciliación periódica de archivos, por ejemplo ARTIFACT_RECONCILE_INTERVAL=1h
+	if appCfg.Artifacts.ReconcileInterval > 0 {
+		reconciler := service.NewArtifactReconciler(dynamoClient, artifacts)
+		reconciler.GracePeriod = appCfg.Artifacts.ReconcileGracePeriod
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reconciler.Run(ctx, appCfg.Artifacts.ReconcileInterval, appCfg.Artifacts.ReconcileRepair)
+		}()
+	}
+
+	// Métricas de Prometheus del worker, por ejemplo WORKER_METRICS_ADDR=:9090
+	var metricsSrv *http.Server
+	if appCfg.Metrics.WorkerAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsSrv = &http.Server{Addr: appCfg.Metrics.WorkerAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Error iniciando servidor de métricas: %v", err)
+			}
+		}()
+	}
+
+	log.Println("🚀 Iniciando worker de reservas...")
+	reservationWorker.Run(ctx)
+	wg.Wait()
+	if metricsSrv != nil {
+		metricsSrv.Close()
+	}
+	// Envía los spans que quedan en el buffer antes de salir
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Error enviando trazas pendientes: %v", err)
+	}
+	log.Println("Worker detenido")
+}
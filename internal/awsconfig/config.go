@@ -4,15 +4,47 @@ import (
 	"context"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	awscfg "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
 )
 
-func LoadAWSConfig() (aws.Config, error) {
-	return config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolver(aws.EndpointResolverFunc(
-			func(service, region string) (aws.Endpoint, error) {
-				return aws.Endpoint{URL: "http://localhost:4566"}, nil
-			})),
-	)
+// LoadAWSConfig carga credenciales y región. Los endpoints se aplican al crear
+// cada cliente, así un servicio puede apuntar a LocalStack y otro a AWS.
+func LoadAWSConfig(ctx context.Context, cfg config.AWSConfig) (aws.Config, error) {
+	return awscfg.LoadDefaultConfig(ctx, awscfg.WithRegion(cfg.Region))
+}
+
+// baseEndpoint devuelve nil si el servicio debe usar el endpoint de AWS
+func baseEndpoint(cfg config.AWSConfig, service string) *string {
+	if endpoint := cfg.EndpointFor(service); endpoint != "" {
+		return aws.String(endpoint)
+	}
+	return nil
+}
+
+func NewDynamoDBClient(awsCfg aws.Config, cfg config.AWSConfig) *dynamodb.Client {
+	return dynamodb.NewFromConfig(awsCfg, func(o *dynamodb.Options) {
+		o.BaseEndpoint = baseEndpoint(cfg, "dynamodb")
+		o.APIOptions = append(o.APIOptions, metrics.AddAWSMiddleware, tracing.AddAWSMiddleware)
+	})
+}
+
+func NewS3Client(awsCfg aws.Config, cfg config.AWSConfig, pathStyle bool) *s3.Client {
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.BaseEndpoint = baseEndpoint(cfg, "s3")
+		o.APIOptions = append(o.APIOptions, metrics.AddAWSMiddleware, tracing.AddAWSMiddleware)
+		o.UsePathStyle = pathStyle
+	})
+}
+
+func NewSQSClient(awsCfg aws.Config, cfg config.AWSConfig) *sqs.Client {
+	return sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+		o.BaseEndpoint = baseEndpoint(cfg, "sqs")
+		o.APIOptions = append(o.APIOptions, metrics.AddAWSMiddleware, tracing.AddAWSMiddleware)
+	})
 }
@@ -0,0 +1,569 @@
+// Package config carga la configuración de la API y del worker: valores por
+// defecto pensados para LocalStack, un archivo YAML opcional (CONFIG_FILE) y
+// por último variables de entorno, que tienen prioridad.
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	RepositoryDynamoDB = "dynamodb"
+	RepositoryMemory   = "memory"
+
+	StorageS3    = "s3"
+	StorageLocal = "local"
+
+	TracingNone   = "none"
+	TracingOTLP   = "otlp"
+	TracingStdout = "stdout"
+
+	// LocalStackEndpoint es el endpoint por defecto para desarrollo local
+	LocalStackEndpoint = "http://localhost:4566"
+)
+
+type Config struct {
+	Server     ServerConfig    `yaml:"server"`
+	AWS        AWSConfig       `yaml:"aws"`
+	DynamoDB   DynamoDBConfig  `yaml:"dynamodb"`
+	SQS        SQSConfig       `yaml:"sqs"`
+	Storage    StorageConfig   `yaml:"storage"`
+	Tracing    TracingConfig   `yaml:"tracing"`
+	Metrics    MetricsConfig   `yaml:"metrics"`
+	QR         QRConfig        `yaml:"qr"`
+	Checkin    CheckinConfig   `yaml:"checkin"`
+	Wallet     WalletConfig    `yaml:"wallet"`
+	Artifacts  ArtifactsConfig `yaml:"artifacts"`
+	Repository string          `yaml:"repository"`
+}
+
+type ServerConfig struct {
+	Addr string `yaml:"addr"`
+	// ShutdownTimeout es cuánto se espera a las peticiones en curso al recibir SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// AdminToken es el token Bearer que piden los endpoints /api/admin; vacío
+	// los deshabilita
+	AdminToken string `yaml:"admin_token"`
+}
+
+// AWSConfig indica la región y los endpoints de cada servicio. Endpoint aplica a
+// todos los servicios salvo que Endpoints defina uno propio; vacío en ambos
+// significa usar los endpoints reales de AWS.
+type AWSConfig struct {
+	Region    string          `yaml:"region"`
+	Endpoint  string          `yaml:"endpoint"`
+	Endpoints EndpointsConfig `yaml:"endpoints"`
+	// CallTimeout limita cada llamada a DynamoDB, S3 y SQS
+	CallTimeout time.Duration `yaml:"call_timeout"`
+}
+
+type EndpointsConfig struct {
+	DynamoDB string `yaml:"dynamodb"`
+	S3       string `yaml:"s3"`
+	SQS      string `yaml:"sqs"`
+}
+
+type DynamoDBConfig struct {
+	TicketsTable     string `yaml:"tickets_table"`
+	EventsTable      string `yaml:"events_table"`
+	OutboxTable      string `yaml:"outbox_table"`
+	IdempotencyTable string `yaml:"idempotency_table"`
+}
+
+// Tables convierte los nombres al tipo que usa db.DynamoClient
+func (d DynamoDBConfig) Tables() db.TableNames {
+	return db.TableNames{
+		Tickets:     d.TicketsTable,
+		Events:      d.EventsTable,
+		Outbox:      d.OutboxTable,
+		Idempotency: d.IdempotencyTable,
+	}
+}
+
+// SQSConfig son las colas de reservas. La API no publica en ellas (lo hace el
+// relay de outbox del worker), así que puede arrancar sin colas; el worker las requiere.
+type SQSConfig struct {
+	QueueURL      string `yaml:"queue_url"`
+	DeadLetterURL string `yaml:"dead_letter_url"`
+	// VisibilityTimeout en segundos: cuánto queda oculto un mensaje recibido y
+	// cuánto lo extiende el worker mientras lo procesa
+	VisibilityTimeout int32 `yaml:"visibility_timeout"`
+}
+
+// Configured indica si hay alguna cola configurada
+func (s SQSConfig) Configured() bool {
+	return s.QueueURL != "" || s.DeadLetterURL != ""
+}
+
+type StorageConfig struct {
+	Backend string `yaml:"backend"`
+	Bucket  string `yaml:"bucket"`
+	// S3PathStyle usa URLs http://host/bucket/key, necesario en LocalStack
+	S3PathStyle   bool          `yaml:"s3_path_style"`
+	PresignExpiry time.Duration `yaml:"presign_expiry"`
+	LocalDir      string        `yaml:"local_dir"`
+	PublicURL     string        `yaml:"public_url"`
+	// SigningKey en base64; si está vacía se genera una al arrancar
+	SigningKey string `yaml:"signing_key"`
+}
+
+// TracingConfig elige a dónde se envían las trazas de OpenTelemetry
+type TracingConfig struct {
+	Exporter string `yaml:"exporter"`
+	// OTLPEndpoint es la URL del colector OTLP/HTTP; vacía usa
+	// OTEL_EXPORTER_OTLP_ENDPOINT o http://localhost:4318
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+	// File es el archivo donde escribe el exporter stdout; vacío es la salida estándar
+	File string `yaml:"file"`
+	// SampleRatio es la fracción de trazas nuevas que se guardan, entre 0 y 1
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// MetricsConfig ajusta la exposición de las métricas de Prometheus. La API las
+// sirve en /metrics de su propio servidor.
+type MetricsConfig struct {
+	// WorkerAddr es la dirección del servidor de métricas del worker, por
+	// ejemplo ":9090"; vacía no las expone
+	WorkerAddr string `yaml:"worker_addr"`
+}
+
+// QRConfig tiene las claves HMAC con que se firman los QR. La API y el worker
+// deben usar las mismas.
+type QRConfig struct {
+	// SigningKeys es la lista "id:base64,id2:base64"
+	SigningKeys string `yaml:"signing_keys"`
+	// ActiveKeyID es la clave con que se firman los QR nuevos; puede omitirse
+	// si hay una sola
+	ActiveKeyID string `yaml:"active_key_id"`
+	// AllowTemporaryKey permite arrancar la API sin SigningKeys con una clave
+	// aleatoria que cambia en cada arranque. Solo para desarrollo.
+	AllowTemporaryKey bool `yaml:"allow_temporary_key"`
+}
+
+// Keys interpreta SigningKeys
+func (q QRConfig) Keys() (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(q.SigningKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("clave de firma sin ID: %q", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("clave de firma %q no es base64 válido: %v", id, err)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// CheckinConfig configura los bundles de check-in y la sincronización de los
+// lectores sin conexión
+type CheckinConfig struct {
+	// BundleKey es la semilla Ed25519 en base64; vacía usa una clave temporal
+	BundleKey   string `yaml:"bundle_key"`
+	BundleKeyID string `yaml:"bundle_key_id"`
+	// MaxOfflineAge es la antigüedad máxima de un escaneo al sincronizarse
+	MaxOfflineAge time.Duration `yaml:"max_offline_age"`
+}
+
+// WalletConfig habilita los pases de Apple Wallet y Google Wallet. Cada uno
+// queda deshabilitado si no se indica su archivo de credenciales.
+type WalletConfig struct {
+	Apple  AppleWalletConfig  `yaml:"apple"`
+	Google GoogleWalletConfig `yaml:"google"`
+}
+
+// AppleWalletConfig son los certificados del Pass Type ID y el intermedio WWDR
+type AppleWalletConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	WWDRFile     string `yaml:"wwdr_file"`
+	PassTypeID   string `yaml:"pass_type_id"`
+	TeamID       string `yaml:"team_id"`
+	Organization string `yaml:"organization"`
+}
+
+// GoogleWalletConfig es la cuenta de servicio del emisor y los orígenes
+// autorizados a mostrar el botón "Guardar en Google Wallet"
+type GoogleWalletConfig struct {
+	KeyFile  string   `yaml:"key_file"`
+	IssuerID string   `yaml:"issuer_id"`
+	Origins  []string `yaml:"origins"`
+}
+
+// ArtifactsConfig ajusta la reconciliación de los QR y PDF guardados
+type ArtifactsConfig struct {
+	// ReconcileInterval es cada cuánto reconcilia el worker; 0 la desactiva
+	ReconcileInterval time.Duration `yaml:"reconcile_interval"`
+	// ReconcileRepair borra los huérfanos y regenera los faltantes en vez de solo informarlos
+	ReconcileRepair bool `yaml:"reconcile_repair"`
+	// ReconcileGracePeriod deja fuera los archivos y tickets más nuevos
+	ReconcileGracePeriod time.Duration `yaml:"reconcile_grace_period"`
+}
+
+// Default devuelve la configuración de desarrollo con LocalStack y los recursos
+// que crea scripts/aws-config.sh
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			ShutdownTimeout: 15 * time.Second,
+		},
+		AWS: AWSConfig{
+			Region:      "us-east-1",
+			Endpoint:    LocalStackEndpoint,
+			CallTimeout: 5 * time.Second,
+		},
+		DynamoDB: DynamoDBConfig{
+			TicketsTable:     "tickets",
+			EventsTable:      "events",
+			OutboxTable:      "outbox",
+			IdempotencyTable: "idempotency",
+		},
+		SQS: SQSConfig{
+			QueueURL:          LocalStackEndpoint + "/000000000000/ticket-queue",
+			DeadLetterURL:     LocalStackEndpoint + "/000000000000/ticket-queue-dlq",
+			VisibilityTimeout: 30,
+		},
+		Storage: StorageConfig{
+			Backend:       StorageS3,
+			Bucket:        "ticket-bucket",
+			S3PathStyle:   true,
+			PresignExpiry: 15 * time.Minute,
+			LocalDir:      "data/blobs",
+			PublicURL:     "http://localhost:8080/api/blobs",
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingNone,
+			SampleRatio: 1,
+		},
+		Checkin: CheckinConfig{
+			BundleKeyID:   "default",
+			MaxOfflineAge: 12 * time.Hour,
+		},
+		Artifacts: ArtifactsConfig{
+			ReconcileGracePeriod: 10 * time.Minute,
+		},
+		Repository: RepositoryDynamoDB,
+	}
+}
+
+// Load carga la configuración desde el archivo indicado en CONFIG_FILE (si está
+// definida) y las variables de entorno, y la valida
+func Load() (*Config, error) {
+	return LoadFile(os.Getenv("CONFIG_FILE"))
+}
+
+// LoadFile es como Load pero con la ruta del archivo YAML explícita; path vacío
+// significa usar solo los valores por defecto y el entorno
+func LoadFile(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error abriendo archivo de configuración: %v", err)
+		}
+		defer file.Close()
+
+		decoder := yaml.NewDecoder(file)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("archivo de configuración %s inválido: %v", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// applyEnv sobrescribe los valores con las variables de entorno definidas. Una
+// variable definida pero vacía también cuenta: AWS_ENDPOINT_URL= quita el
+// endpoint de LocalStack para usar AWS.
+func (c *Config) applyEnv() error {
+	vars := map[string]*string{
+		"SERVER_ADDR":                &c.Server.Addr,
+		"ADMIN_TOKEN":                &c.Server.AdminToken,
+		"AWS_REGION":                 &c.AWS.Region,
+		"AWS_ENDPOINT_URL":           &c.AWS.Endpoint,
+		"AWS_ENDPOINT_URL_DYNAMODB":  &c.AWS.Endpoints.DynamoDB,
+		"AWS_ENDPOINT_URL_S3":        &c.AWS.Endpoints.S3,
+		"AWS_ENDPOINT_URL_SQS":       &c.AWS.Endpoints.SQS,
+		"DYNAMODB_TICKETS_TABLE":     &c.DynamoDB.TicketsTable,
+		"DYNAMODB_EVENTS_TABLE":      &c.DynamoDB.EventsTable,
+		"DYNAMODB_OUTBOX_TABLE":      &c.DynamoDB.OutboxTable,
+		"DYNAMODB_IDEMPOTENCY_TABLE": &c.DynamoDB.IdempotencyTable,
+		"SQS_QUEUE_URL":              &c.SQS.QueueURL,
+		"SQS_DEAD_LETTER_URL":        &c.SQS.DeadLetterURL,
+		"S3_BUCKET":                  &c.Storage.Bucket,
+		"STORAGE_BACKEND":            &c.Storage.Backend,
+		"STORAGE_LOCAL_DIR":          &c.Storage.LocalDir,
+		"STORAGE_PUBLIC_URL":         &c.Storage.PublicURL,
+		"STORAGE_SIGNING_KEY":        &c.Storage.SigningKey,
+		"TRACING_EXPORTER":           &c.Tracing.Exporter,
+		"TRACING_OTLP_ENDPOINT":      &c.Tracing.OTLPEndpoint,
+		"TRACING_FILE":               &c.Tracing.File,
+		"WORKER_METRICS_ADDR":        &c.Metrics.WorkerAddr,
+		"QR_SIGNING_KEYS":            &c.QR.SigningKeys,
+		"QR_SIGNING_KEY_ID":          &c.QR.ActiveKeyID,
+		"CHECKIN_BUNDLE_KEY":         &c.Checkin.BundleKey,
+		"CHECKIN_BUNDLE_KEY_ID":      &c.Checkin.BundleKeyID,
+		"PKPASS_CERT_FILE":           &c.Wallet.Apple.CertFile,
+		"PKPASS_KEY_FILE":            &c.Wallet.Apple.KeyFile,
+		"PKPASS_WWDR_FILE":           &c.Wallet.Apple.WWDRFile,
+		"PKPASS_TYPE_ID":             &c.Wallet.Apple.PassTypeID,
+		"PKPASS_TEAM_ID":             &c.Wallet.Apple.TeamID,
+		"PKPASS_ORGANIZATION":        &c.Wallet.Apple.Organization,
+		"GOOGLE_WALLET_KEY_FILE":     &c.Wallet.Google.KeyFile,
+		"GOOGLE_WALLET_ISSUER_ID":    &c.Wallet.Google.IssuerID,
+		"TICKETS_REPOSITORY":         &c.Repository,
+	}
+	for name, target := range vars {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = value
+		}
+	}
+
+	if value, ok := os.LookupEnv("SQS_VISIBILITY_TIMEOUT"); ok {
+		seconds, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("SQS_VISIBILITY_TIMEOUT inválido: %q", value)
+		}
+		c.SQS.VisibilityTimeout = int32(seconds)
+	}
+	if value, ok := os.LookupEnv("S3_USE_PATH_STYLE"); ok {
+		pathStyle, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("S3_USE_PATH_STYLE inválido: %q", value)
+		}
+		c.Storage.S3PathStyle = pathStyle
+	}
+	if value, ok := os.LookupEnv("GOOGLE_WALLET_ORIGINS"); ok {
+		c.Wallet.Google.Origins = nil
+		for _, origin := range strings.Split(value, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				c.Wallet.Google.Origins = append(c.Wallet.Google.Origins, origin)
+			}
+		}
+	}
+	if value, ok := os.LookupEnv("QR_ALLOW_TEMPORARY_KEY"); ok {
+		allow, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("QR_ALLOW_TEMPORARY_KEY inválido: %q", value)
+		}
+		c.QR.AllowTemporaryKey = allow
+	}
+	if value, ok := os.LookupEnv("ARTIFACT_RECONCILE_REPAIR"); ok {
+		repair, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("ARTIFACT_RECONCILE_REPAIR inválido: %q", value)
+		}
+		c.Artifacts.ReconcileRepair = repair
+	}
+	if value, ok := os.LookupEnv("TRACING_SAMPLE_RATIO"); ok {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("TRACING_SAMPLE_RATIO inválido: %q", value)
+		}
+		c.Tracing.SampleRatio = ratio
+	}
+	durations := map[string]*time.Duration{
+		"SERVER_SHUTDOWN_TIMEOUT":         &c.Server.ShutdownTimeout,
+		"AWS_CALL_TIMEOUT":                &c.AWS.CallTimeout,
+		"STORAGE_PRESIGN_EXPIRY":          &c.Storage.PresignExpiry,
+		"CHECKIN_MAX_OFFLINE_AGE":         &c.Checkin.MaxOfflineAge,
+		"ARTIFACT_RECONCILE_INTERVAL":     &c.Artifacts.ReconcileInterval,
+		"ARTIFACT_RECONCILE_GRACE_PERIOD": &c.Artifacts.ReconcileGracePeriod,
+	}
+	for name, target := range durations {
+		if value, ok := os.LookupEnv(name); ok {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%s inválida: %q", name, value)
+			}
+			*target = duration
+		}
+	}
+
+	// Vacío equivale al valor por defecto, como antes de existir este paquete
+	if c.Storage.Backend == "" {
+		c.Storage.Backend = StorageS3
+	}
+	if c.Repository == "" {
+		c.Repository = RepositoryDynamoDB
+	}
+	if c.Tracing.Exporter == "" {
+		c.Tracing.Exporter = TracingNone
+	}
+	return nil
+}
+
+// Validate revisa la configuración completa y devuelve todos los problemas juntos
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr es requerido")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout debe ser positivo")
+	check(c.Server.AdminToken == "" || len(c.Server.AdminToken) >= 16, "server.admin_token debe tener al menos 16 caracteres")
+	check(c.AWS.Region != "", "aws.region es requerido")
+	check(c.AWS.CallTimeout > 0, "aws.call_timeout debe ser positivo")
+	for _, endpoint := range []struct{ name, value string }{
+		{"aws.endpoint", c.AWS.Endpoint},
+		{"aws.endpoints.dynamodb", c.AWS.Endpoints.DynamoDB},
+		{"aws.endpoints.s3", c.AWS.Endpoints.S3},
+		{"aws.endpoints.sqs", c.AWS.Endpoints.SQS},
+	} {
+		check(endpoint.value == "" || validURL(endpoint.value), "%s no es una URL válida: %q", endpoint.name, endpoint.value)
+	}
+
+	switch c.Repository {
+	case RepositoryDynamoDB:
+		check(c.DynamoDB.TicketsTable != "", "dynamodb.tickets_table es requerido")
+		check(c.DynamoDB.EventsTable != "", "dynamodb.events_table es requerido")
+		check(c.DynamoDB.OutboxTable != "", "dynamodb.outbox_table es requerido")
+		check(c.DynamoDB.IdempotencyTable != "", "dynamodb.idempotency_table es requerido")
+	case RepositoryMemory:
+	default:
+		check(false, "repository desconocido: %q (use '%s' o '%s')", c.Repository, RepositoryDynamoDB, RepositoryMemory)
+	}
+
+	check(c.SQS.QueueURL == "" || validURL(c.SQS.QueueURL), "sqs.queue_url no es una URL válida: %q", c.SQS.QueueURL)
+	check(c.SQS.DeadLetterURL == "" || validURL(c.SQS.DeadLetterURL), "sqs.dead_letter_url no es una URL válida: %q", c.SQS.DeadLetterURL)
+	check(c.SQS.VisibilityTimeout > 0 && c.SQS.VisibilityTimeout <= 43200, "sqs.visibility_timeout debe estar entre 1 y 43200 segundos")
+
+	check(c.Storage.PresignExpiry > 0, "storage.presign_expiry debe ser positiva")
+	switch c.Storage.Backend {
+	case StorageS3:
+		check(c.Storage.Bucket != "", "storage.bucket es requerido con el backend s3")
+	case StorageLocal:
+		check(c.Storage.LocalDir != "", "storage.local_dir es requerido con el backend local")
+		check(validURL(c.Storage.PublicURL), "storage.public_url no es una URL válida: %q", c.Storage.PublicURL)
+	default:
+		check(false, "storage.backend desconocido: %q (use '%s' o '%s')", c.Storage.Backend, StorageS3, StorageLocal)
+	}
+
+	switch c.Tracing.Exporter {
+	case TracingNone, TracingStdout:
+	case TracingOTLP:
+		check(c.Tracing.OTLPEndpoint == "" || validURL(c.Tracing.OTLPEndpoint), "tracing.otlp_endpoint no es una URL válida: %q", c.Tracing.OTLPEndpoint)
+	default:
+		check(false, "tracing.exporter desconocido: %q (use '%s', '%s' o '%s')", c.Tracing.Exporter, TracingNone, TracingOTLP, TracingStdout)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio debe estar entre 0 y 1")
+	check(c.Metrics.WorkerAddr == "" || validListenAddr(c.Metrics.WorkerAddr), "metrics.worker_addr no es una dirección válida: %q", c.Metrics.WorkerAddr)
+
+	c.validateQR(check)
+
+	if c.Checkin.BundleKey != "" {
+		seed, err := base64.StdEncoding.DecodeString(c.Checkin.BundleKey)
+		check(err == nil, "checkin.bundle_key no es base64 válido")
+		check(err != nil || len(seed) == ed25519.SeedSize, "checkin.bundle_key debe tener %d bytes", ed25519.SeedSize)
+		check(c.Checkin.BundleKeyID != "", "checkin.bundle_key_id es requerido")
+	}
+	check(c.Checkin.MaxOfflineAge > 0, "checkin.max_offline_age debe ser positiva")
+
+	if apple := c.Wallet.Apple; apple.CertFile != "" {
+		check(apple.KeyFile != "", "wallet.apple.key_file es requerido con wallet.apple.cert_file")
+		check(apple.WWDRFile != "", "wallet.apple.wwdr_file es requerido con wallet.apple.cert_file")
+		check(apple.PassTypeID != "", "wallet.apple.pass_type_id es requerido con wallet.apple.cert_file")
+		check(apple.TeamID != "", "wallet.apple.team_id es requerido con wallet.apple.cert_file")
+	}
+	if google := c.Wallet.Google; google.KeyFile != "" {
+		check(google.IssuerID != "", "wallet.google.issuer_id es requerido con wallet.google.key_file")
+		for _, origin := range google.Origins {
+			check(validURL(origin), "wallet.google.origins tiene una URL inválida: %q", origin)
+		}
+	}
+
+	check(c.Artifacts.ReconcileInterval >= 0, "artifacts.reconcile_interval no puede ser negativa")
+	check(c.Artifacts.ReconcileGracePeriod >= 0, "artifacts.reconcile_grace_period no puede ser negativa")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("configuración inválida: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validateQR revisa las claves de firma de QR; vacías se usa una temporal
+func (c *Config) validateQR(check func(bool, string, ...interface{})) {
+	keys, err := c.QR.Keys()
+	if err != nil {
+		check(false, "qr.signing_keys inválidas: %v", err)
+		return
+	}
+	if len(keys) == 0 {
+		check(c.QR.ActiveKeyID == "", "qr.active_key_id requiere qr.signing_keys")
+		return
+	}
+	for id, key := range keys {
+		check(id != "" && !strings.Contains(id, "."), "qr.signing_keys tiene un ID inválido: %q", id)
+		check(len(key) >= 32, "la clave de QR %q debe tener al menos 32 bytes", id)
+	}
+	if c.QR.ActiveKeyID != "" {
+		_, ok := keys[c.QR.ActiveKeyID]
+		check(ok, "qr.active_key_id %q no está en qr.signing_keys", c.QR.ActiveKeyID)
+	} else {
+		check(len(keys) == 1, "qr.active_key_id es requerido con más de una clave de QR")
+	}
+}
+
+// EndpointFor devuelve el endpoint de service ("dynamodb", "s3" o "sqs"), o ""
+// para usar el de AWS
+func (a AWSConfig) EndpointFor(service string) string {
+	var endpoint string
+	switch service {
+	case "dynamodb":
+		endpoint = a.Endpoints.DynamoDB
+	case "s3":
+		endpoint = a.Endpoints.S3
+	case "sqs":
+		endpoint = a.Endpoints.SQS
+	}
+	if endpoint != "" {
+		return endpoint
+	}
+	return a.Endpoint
+}
+
+// validListenAddr indica si value tiene la forma host:puerto que acepta
+// http.Server, con el host opcional
+func validListenAddr(value string) bool {
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return false
+	}
+	number, err := strconv.Atoi(port)
+	return err == nil && number >= 0 && number <= 65535
+}
+
+func validURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
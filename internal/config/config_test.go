@@ -0,0 +1,327 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearEnv quita las variables que lee la configuración mientras dura el test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"CONFIG_FILE", "SERVER_ADDR", "ADMIN_TOKEN", "AWS_REGION", "AWS_ENDPOINT_URL", "AWS_ENDPOINT_URL_DYNAMODB",
+		"AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL_SQS", "DYNAMODB_TICKETS_TABLE", "DYNAMODB_EVENTS_TABLE",
+		"DYNAMODB_OUTBOX_TABLE", "DYNAMODB_IDEMPOTENCY_TABLE", "SQS_QUEUE_URL", "SQS_DEAD_LETTER_URL",
+		"SQS_VISIBILITY_TIMEOUT", "SERVER_SHUTDOWN_TIMEOUT", "AWS_CALL_TIMEOUT", "S3_BUCKET", "S3_USE_PATH_STYLE", "STORAGE_BACKEND", "STORAGE_PRESIGN_EXPIRY",
+		"STORAGE_LOCAL_DIR", "STORAGE_PUBLIC_URL", "STORAGE_SIGNING_KEY", "TICKETS_REPOSITORY",
+		"TRACING_EXPORTER", "TRACING_OTLP_ENDPOINT", "TRACING_FILE", "TRACING_SAMPLE_RATIO", "WORKER_METRICS_ADDR",
+		"QR_SIGNING_KEYS", "QR_SIGNING_KEY_ID", "QR_ALLOW_TEMPORARY_KEY", "CHECKIN_BUNDLE_KEY", "CHECKIN_BUNDLE_KEY_ID", "CHECKIN_MAX_OFFLINE_AGE",
+		"PKPASS_CERT_FILE", "PKPASS_KEY_FILE", "PKPASS_WWDR_FILE", "PKPASS_TYPE_ID", "PKPASS_TEAM_ID", "PKPASS_ORGANIZATION",
+		"GOOGLE_WALLET_KEY_FILE", "GOOGLE_WALLET_ISSUER_ID", "GOOGLE_WALLET_ORIGINS",
+		"ARTIFACT_RECONCILE_INTERVAL", "ARTIFACT_RECONCILE_REPAIR", "ARTIFACT_RECONCILE_GRACE_PERIOD",
+	} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_DefaultsTargetLocalStack(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Equal(t, "us-east-1", cfg.AWS.Region)
+	assert.Equal(t, LocalStackEndpoint, cfg.AWS.EndpointFor("dynamodb"))
+	assert.Equal(t, "tickets", cfg.DynamoDB.Tables().Tickets)
+	assert.Equal(t, "http://localhost:4566/000000000000/ticket-queue", cfg.SQS.QueueURL)
+	assert.Equal(t, "ticket-bucket", cfg.Storage.Bucket)
+	assert.Equal(t, RepositoryDynamoDB, cfg.Repository)
+}
+
+func TestLoadFile_YAMLThenEnv(t *testing.T) {
+	clearEnv(t)
+	path := writeConfigFile(t, `
+server:
+  addr: ":9090"
+aws:
+  region: eu-west-1
+  endpoint: ""
+  endpoints:
+    sqs: http://sqs.internal:9324
+dynamodb:
+  tickets_table: prod-tickets
+sqs:
+  queue_url: https://sqs.eu-west-1.amazonaws.com/123456789012/tickets
+  dead_letter_url: https://sqs.eu-west-1.amazonaws.com/123456789012/tickets-dlq
+storage:
+  bucket: prod-ticket-files
+  s3_path_style: false
+  presign_expiry: 5m
+`)
+	t.Setenv("DYNAMODB_EVENTS_TABLE", "prod-events")
+	t.Setenv("SERVER_ADDR", ":7070")
+	t.Setenv("AWS_CALL_TIMEOUT", "2s")
+
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, ":7070", cfg.Server.Addr)
+	assert.Equal(t, "eu-west-1", cfg.AWS.Region)
+	assert.Equal(t, "", cfg.AWS.EndpointFor("dynamodb"), "sin endpoint se usa AWS")
+	assert.Equal(t, "", cfg.AWS.EndpointFor("s3"))
+	assert.Equal(t, "http://sqs.internal:9324", cfg.AWS.EndpointFor("sqs"))
+	assert.Equal(t, "prod-tickets", cfg.DynamoDB.TicketsTable)
+	assert.Equal(t, "prod-events", cfg.DynamoDB.EventsTable)
+	assert.Equal(t, "outbox", cfg.DynamoDB.OutboxTable, "lo no indicado conserva el valor por defecto")
+	assert.Equal(t, "prod-ticket-files", cfg.Storage.Bucket)
+	assert.False(t, cfg.Storage.S3PathStyle)
+	assert.Equal(t, 5*time.Minute, cfg.Storage.PresignExpiry)
+	assert.Equal(t, 2*time.Second, cfg.AWS.CallTimeout)
+	assert.Equal(t, 15*time.Second, cfg.Server.ShutdownTimeout)
+}
+
+func TestLoad_EmptyEndpointEnvUsesAWS(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	t.Setenv("AWS_ENDPOINT_URL_S3", "http://localhost:4566")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "", cfg.AWS.EndpointFor("dynamodb"))
+	assert.Equal(t, "http://localhost:4566", cfg.AWS.EndpointFor("s3"))
+}
+
+func TestLoadFile_UnknownField(t *testing.T) {
+	clearEnv(t)
+	path := writeConfigFile(t, "storage:\n  bucked: typo\n")
+
+	_, err := LoadFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bucked")
+}
+
+func TestLoad_InvalidEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("SQS_VISIBILITY_TIMEOUT", "treinta")
+
+	_, err := Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SQS_VISIBILITY_TIMEOUT")
+}
+
+func TestValidate_ReportsAllProblems(t *testing.T) {
+	cfg := Default()
+	cfg.AWS.Region = ""
+	cfg.AWS.Endpoints.DynamoDB = "localhost:8000"
+	cfg.SQS.QueueURL = "ticket-queue"
+	cfg.Storage.Backend = "gcs"
+	cfg.Repository = "postgres"
+	cfg.SQS.VisibilityTimeout = 0
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	for _, want := range []string{"aws.region", "aws.endpoints.dynamodb", "sqs.queue_url", "sqs.visibility_timeout", "storage.backend", "repository"} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestValidate_QueuesAreOptional(t *testing.T) {
+	cfg := Default()
+	cfg.SQS.QueueURL = ""
+	cfg.SQS.DeadLetterURL = ""
+	require.NoError(t, cfg.Validate())
+	assert.False(t, cfg.SQS.Configured())
+
+	cfg.SQS.DeadLetterURL = "http://localhost:4566/000000000000/ticket-queue-dlq"
+	assert.True(t, cfg.SQS.Configured())
+}
+
+func TestValidate_MemoryRepositoryDoesNotNeedTables(t *testing.T) {
+	cfg := Default()
+	cfg.Repository = RepositoryMemory
+	cfg.DynamoDB = DynamoDBConfig{}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Repository = RepositoryDynamoDB
+	assert.Error(t, cfg.Validate())
+}
+
+func TestValidate_LocalStorage(t *testing.T) {
+	cfg := Default()
+	cfg.Storage.Backend = StorageLocal
+	cfg.Storage.Bucket = ""
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Storage.PublicURL = "/api/blobs"
+	assert.Error(t, cfg.Validate())
+}
+
+func TestLoad_Tracing(t *testing.T) {
+	clearEnv(t)
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, TracingNone, cfg.Tracing.Exporter)
+
+	t.Setenv("TRACING_EXPORTER", TracingOTLP)
+	t.Setenv("TRACING_OTLP_ENDPOINT", "http://otel-collector:4318")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "http://otel-collector:4318", cfg.Tracing.OTLPEndpoint)
+	assert.Equal(t, 0.25, cfg.Tracing.SampleRatio)
+
+	t.Setenv("TRACING_EXPORTER", "jaeger")
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tracing.exporter")
+	assert.Contains(t, err.Error(), "tracing.sample_ratio")
+}
+
+func TestLoad_WorkerMetricsAddr(t *testing.T) {
+	clearEnv(t)
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Metrics.WorkerAddr)
+
+	t.Setenv("WORKER_METRICS_ADDR", ":9090")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Metrics.WorkerAddr)
+
+	for _, addr := range []string{"9090", "localhost:http", ":70000"} {
+		t.Setenv("WORKER_METRICS_ADDR", addr)
+		_, err = Load()
+		require.Error(t, err, addr)
+		assert.Contains(t, err.Error(), "metrics.worker_addr")
+	}
+}
+
+func TestLoad_Checkin(t *testing.T) {
+	clearEnv(t)
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 12*time.Hour, cfg.Checkin.MaxOfflineAge)
+
+	t.Setenv("CHECKIN_MAX_OFFLINE_AGE", "2h")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, cfg.Checkin.MaxOfflineAge)
+
+	t.Setenv("CHECKIN_MAX_OFFLINE_AGE", "0s")
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checkin.max_offline_age")
+}
+
+func TestLoad_QRSigningKeys(t *testing.T) {
+	clearEnv(t)
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("a"), 32))
+
+	t.Setenv("QR_ALLOW_TEMPORARY_KEY", "true")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.True(t, cfg.QR.AllowTemporaryKey)
+
+	t.Setenv("QR_SIGNING_KEYS", "k1:"+key+", k2:"+key)
+	t.Setenv("QR_SIGNING_KEY_ID", "k2")
+	cfg, err = Load()
+	require.NoError(t, err)
+	keys, err := cfg.QR.Keys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	t.Setenv("QR_SIGNING_KEY_ID", "")
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "qr.active_key_id")
+
+	t.Setenv("QR_SIGNING_KEYS", "sin-id")
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "qr.signing_keys")
+
+	t.Setenv("QR_SIGNING_KEYS", "k1:"+base64.StdEncoding.EncodeToString([]byte("corta")))
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "al menos 32 bytes")
+}
+
+func TestLoad_BundleKey(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CHECKIN_BUNDLE_KEY", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("a"), 32)))
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "default", cfg.Checkin.BundleKeyID)
+
+	t.Setenv("CHECKIN_BUNDLE_KEY", base64.StdEncoding.EncodeToString([]byte("corta")))
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checkin.bundle_key")
+}
+
+func TestLoad_Wallet(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("GOOGLE_WALLET_KEY_FILE", "/secrets/google.json")
+	t.Setenv("GOOGLE_WALLET_ISSUER_ID", "3388000000012345678")
+	t.Setenv("GOOGLE_WALLET_ORIGINS", "https://tickets.example.com, https://app.example.com")
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://tickets.example.com", "https://app.example.com"}, cfg.Wallet.Google.Origins)
+
+	t.Setenv("PKPASS_CERT_FILE", "/secrets/pass.pem")
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wallet.apple.key_file")
+	assert.Contains(t, err.Error(), "wallet.apple.pass_type_id")
+}
+
+func TestLoad_ArtifactReconcile(t *testing.T) {
+	clearEnv(t)
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Zero(t, cfg.Artifacts.ReconcileInterval)
+	assert.Equal(t, 10*time.Minute, cfg.Artifacts.ReconcileGracePeriod)
+
+	t.Setenv("ARTIFACT_RECONCILE_INTERVAL", "1h")
+	t.Setenv("ARTIFACT_RECONCILE_REPAIR", "true")
+	t.Setenv("ARTIFACT_RECONCILE_GRACE_PERIOD", "30m")
+	cfg, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, cfg.Artifacts.ReconcileInterval)
+	assert.True(t, cfg.Artifacts.ReconcileRepair)
+	assert.Equal(t, 30*time.Minute, cfg.Artifacts.ReconcileGracePeriod)
+
+	t.Setenv("ARTIFACT_RECONCILE_REPAIR", "quizás")
+	_, err = Load()
+	assert.Error(t, err)
+
+	t.Setenv("ARTIFACT_RECONCILE_REPAIR", "false")
+	t.Setenv("ARTIFACT_RECONCILE_INTERVAL", "-1h")
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "artifacts.reconcile_interval")
+}
+
+func TestLoadFile_Example(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := LoadFile("../../config.example.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "", cfg.AWS.EndpointFor("s3"))
+	assert.False(t, cfg.Storage.S3PathStyle)
+}
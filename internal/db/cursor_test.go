@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	key := map[string]types.AttributeValue{
+		"id":         &types.AttributeValueMemberS{Value: "b0f1c7de-0000-4000-8000-000000000001"},
+		"event_id":   &types.AttributeValueMemberS{Value: "b0f1c7de-0000-4000-8000-000000000002"},
+		"created_at": &types.AttributeValueMemberS{Value: "2025-01-01T10:00:00Z"},
+	}
+
+	cursor, err := encodeCursor(key)
+	require.NoError(t, err)
+	assert.NotEmpty(t, cursor)
+
+	decoded, err := decodeCursor(cursor)
+	require.NoError(t, err)
+	assert.Equal(t, key, decoded)
+}
+
+func TestCursor_Empty(t *testing.T) {
+	cursor, err := encodeCursor(nil)
+	require.NoError(t, err)
+	assert.Empty(t, cursor)
+
+	key, err := decodeCursor("")
+	require.NoError(t, err)
+	assert.Nil(t, key)
+}
+
+func TestCursor_Invalid(t *testing.T) {
+	_, err := decodeCursor("%%%")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = decodeCursor("bm90LWpzb24")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -15,62 +15,278 @@ import (
 	"github.com/jhonathanssegura/ticket-reservation/internal/model"
 )
 
+const (
+	TicketsEventIndex        = "event_id-index"
+	TicketsEmailIndex        = "email-index"
+	TicketsStatusExpiryIndex = "status-expires_at-index"
+	// OutboxPendingIndex es un índice disperso: solo los mensajes pendientes
+	// tienen el atributo pending, que se quita al publicarlos o apartarlos
+	OutboxPendingIndex = "pending-created_at-index"
+)
+
+var (
+	ErrInvalidTransition    = errors.New("ticket status transition not allowed")
+	ErrTicketStatusConflict = errors.New("ticket status changed concurrently or transition not allowed")
+)
+
+// TableNames son los nombres de las tablas de DynamoDB que usa el repositorio
+type TableNames struct {
+	Tickets     string
+	Events      string
+	Outbox      string
+	Idempotency string
+}
+
+// DefaultTableNames devuelve los nombres que crea scripts/aws-config.sh
+func DefaultTableNames() TableNames {
+	return TableNames{
+		Tickets:     "tickets",
+		Events:      "events",
+		Outbox:      "outbox",
+		Idempotency: "idempotency",
+	}
+}
+
+// DefaultCallTimeout es el tiempo máximo de cada llamada al repositorio si no se configura otro
+const DefaultCallTimeout = 5 * time.Second
+
 type DynamoClient struct {
 	Client *dynamodb.Client
+	Tables TableNames
+	// Timeout limita cada llamada del repositorio, además de la cancelación
+	// del contexto del llamador
+	Timeout time.Duration
 }
 
-func (d *DynamoClient) SaveTicket(ticket model.Ticket) error {
-	fmt.Printf("Guardando ticket: ID=%s, EventID=%s, UserID=%s, Email=%s\n",
-		ticket.ID.String(), ticket.EventID.String(), ticket.UserID.String(), ticket.Email)
-
-	item := map[string]types.AttributeValue{
-		"id":          &types.AttributeValueMemberS{Value: ticket.ID.String()},
-		"event_id":    &types.AttributeValueMemberS{Value: ticket.EventID.String()},
-		"user_id":     &types.AttributeValueMemberS{Value: ticket.UserID.String()},
-		"email":       &types.AttributeValueMemberS{Value: ticket.Email},
-		"name":        &types.AttributeValueMemberS{Value: ticket.Name},
-		"ticket_code": &types.AttributeValueMemberS{Value: ticket.TicketCode},
-		"status":      &types.AttributeValueMemberS{Value: ticket.Status},
-		"price":       &types.AttributeValueMemberN{Value: fmt.Sprintf("%.2f", ticket.Price)},
-		"reserved_at": &types.AttributeValueMemberS{Value: ticket.ReservedAt.Format(time.RFC3339)},
-		"created_at":  &types.AttributeValueMemberS{Value: ticket.CreatedAt.Format(time.RFC3339)},
-		"updated_at":  &types.AttributeValueMemberS{Value: ticket.UpdatedAt.Format(time.RFC3339)},
+// NewDynamoClient usa los nombres por defecto para las tablas que no se indiquen
+func NewDynamoClient(client *dynamodb.Client, tables TableNames) *DynamoClient {
+	defaults := DefaultTableNames()
+	if tables.Tickets == "" {
+		tables.Tickets = defaults.Tickets
 	}
+	if tables.Events == "" {
+		tables.Events = defaults.Events
+	}
+	if tables.Outbox == "" {
+		tables.Outbox = defaults.Outbox
+	}
+	if tables.Idempotency == "" {
+		tables.Idempotency = defaults.Idempotency
+	}
+	return &DynamoClient{Client: client, Tables: tables, Timeout: DefaultCallTimeout}
+}
 
-	if ticket.CheckedInAt != nil {
-		item["checked_in_at"] = &types.AttributeValueMemberS{Value: ticket.CheckedInAt.Format(time.RFC3339)}
+// callContext deriva del contexto del llamador el contexto de una llamada a DynamoDB
+func (d *DynamoClient) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.Timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, d.Timeout)
+}
 
-	if ticket.CheckedInBy != nil {
-		item["checked_in_by"] = &types.AttributeValueMemberS{Value: ticket.CheckedInBy.String()}
+// Ping comprueba que todas las tablas existen y están activas
+func (d *DynamoClient) Ping(ctx context.Context) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+
+	for _, table := range []string{d.Tables.Tickets, d.Tables.Events, d.Tables.Outbox, d.Tables.Idempotency} {
+		result, err := d.Client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(table),
+		})
+		if err != nil {
+			return fmt.Errorf("error consultando tabla '%s': %w", table, err)
+		}
+		if status := result.Table.TableStatus; status != types.TableStatusActive {
+			return fmt.Errorf("la tabla '%s' está en estado %s", table, status)
+		}
 	}
+	return nil
+}
+
+func (d *DynamoClient) SaveTicket(ctx context.Context, ticket model.Ticket) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	fmt.Printf("Guardando ticket: ID=%s, EventID=%s, UserID=%s, Email=%s\n",
+		ticket.ID.String(), ticket.EventID.String(), ticket.UserID.String(), ticket.Email)
 
-	_, err := d.Client.PutItem(context.TODO(), &dynamodb.PutItemInput{
-		TableName: aws.String("tickets"),
-		Item:      item,
+	_, err := d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.Tables.Tickets),
+		Item:                marshalTicket(ticket),
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
 	})
 
 	if err != nil {
 		var errorMsg string
 		switch {
 		case strings.Contains(err.Error(), "ResourceNotFoundException"):
-			errorMsg = "La tabla 'tickets' no existe en DynamoDB. Verifique que LocalStack esté ejecutándose y la tabla haya sido creada."
+			errorMsg = fmt.Sprintf("La tabla '%s' no existe en DynamoDB. Verifique que la tabla haya sido creada.", d.Tables.Tickets)
 		case strings.Contains(err.Error(), "RequestCanceled"):
-			errorMsg = "Error de conexión con DynamoDB. Verifique que LocalStack esté ejecutándose en http://localhost:4566."
+			errorMsg = "Error de conexión con DynamoDB. Verifique el endpoint configurado."
 		case strings.Contains(err.Error(), "ConditionalCheckFailedException"):
 			errorMsg = "El ticket ya existe en la base de datos."
 		default:
 			errorMsg = fmt.Sprintf("Error guardando ticket en DynamoDB: %v", err)
 		}
-		return fmt.Errorf(errorMsg)
+		return errors.New(errorMsg)
 	}
 
 	return nil
 }
 
-func (d *DynamoClient) GetTicketByID(ticketID string) (*model.Ticket, error) {
-	result, err := d.Client.GetItem(context.TODO(), &dynamodb.GetItemInput{
-		TableName: aws.String("tickets"),
+// UpdateTicket sobrescribe el ticket solo si su estado en DynamoDB sigue siendo
+// expectedStatus, de modo que una escritura concurrente no se pierda.
+func (d *DynamoClient) UpdateTicket(ctx context.Context, ticket model.Ticket, expectedStatus string) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	_, err := d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.Tables.Tickets),
+		Item:                marshalTicket(ticket),
+		ConditionExpression: aws.String("attribute_exists(id) AND #status = :expected"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":expected": &types.AttributeValueMemberS{Value: expectedStatus},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrTicketStatusConflict
+		}
+		return fmt.Errorf("error actualizando ticket en DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// TransitionTicketStatus cambia el estado del ticket de from a to. La escritura
+// está condicionada al estado anterior, por lo que una transición ilegal o
+// concurrente devuelve ErrTicketStatusConflict en lugar de sobrescribir.
+func (d *DynamoClient) TransitionTicketStatus(ctx context.Context, ticketID, from, to string, now time.Time) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	if !model.CanTransitionTicket(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+
+	condition := "#status = :from"
+	update := "SET #status = :to, updated_at = :now"
+	values := map[string]types.AttributeValue{
+		":from": &types.AttributeValueMemberS{Value: from},
+		":to":   &types.AttributeValueMemberS{Value: to},
+		":now":  &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+	}
+
+	// Una reserva solo se confirma dentro de su plazo de retención
+	if from == model.TicketStatusReserved && to == model.TicketStatusConfirmed {
+		condition += " AND (attribute_not_exists(expires_at) OR expires_at > :now)"
+		update += " REMOVE expires_at"
+	}
+
+	_, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.Tables.Tickets),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: ticketID},
+		},
+		UpdateExpression:    aws.String(update),
+		ConditionExpression: aws.String(condition),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrTicketStatusConflict
+		}
+		return fmt.Errorf("error cambiando estado del ticket: %w", err)
+	}
+	return nil
+}
+
+// CheckInTicket marca un ticket confirmado como usado y registra quién lo escaneó
+// y cuándo. La condición sobre el estado garantiza que dos escaneos simultáneos
+// del mismo QR no puedan dar acceso dos veces: el segundo recibe ErrTicketStatusConflict.
+func (d *DynamoClient) CheckInTicket(ctx context.Context, ticketID string, scannerID uuid.UUID, gate string, now time.Time) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	update := "SET #status = :used, checked_in_at = :now, checked_in_by = :scanner, updated_at = :now"
+	values := map[string]types.AttributeValue{
+		":used":      &types.AttributeValueMemberS{Value: model.TicketStatusUsed},
+		":confirmed": &types.AttributeValueMemberS{Value: model.TicketStatusConfirmed},
+		":now":       &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+		":scanner":   &types.AttributeValueMemberS{Value: scannerID.String()},
+	}
+	if gate != "" {
+		update += ", check_in_gate = :gate"
+		values[":gate"] = &types.AttributeValueMemberS{Value: gate}
+	}
+
+	_, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.Tables.Tickets),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: ticketID},
+		},
+		UpdateExpression:    aws.String(update),
+		ConditionExpression: aws.String("#status = :confirmed"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrTicketStatusConflict
+		}
+		return fmt.Errorf("error registrando ingreso del ticket: %w", err)
+	}
+	return nil
+}
+
+// RecordOfflineCheckIn registra un ingreso escaneado sin conexión en scannedAt.
+// Si el ticket ya tiene un ingreso, solo lo reemplaza uno anterior (o del mismo
+// instante con un scanner_id menor), de modo que el resultado no depende del orden
+// en que los lectores sincronizan. Si no gana devuelve ErrTicketStatusConflict.
+func (d *DynamoClient) RecordOfflineCheckIn(ctx context.Context, ticketID string, scannerID uuid.UUID, gate string, scannedAt time.Time) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	_, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.Tables.Tickets),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: ticketID},
+		},
+		UpdateExpression: aws.String("SET #status = :used, checked_in_at = :at, checked_in_by = :scanner, check_in_gate = :gate, updated_at = :now"),
+		ConditionExpression: aws.String("#status = :confirmed OR (#status = :used AND (checked_in_at > :at OR " +
+			"(checked_in_at = :at AND checked_in_by > :scanner)))"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":used":      &types.AttributeValueMemberS{Value: model.TicketStatusUsed},
+			":confirmed": &types.AttributeValueMemberS{Value: model.TicketStatusConfirmed},
+			":at":        &types.AttributeValueMemberS{Value: scannedAt.UTC().Format(time.RFC3339)},
+			":scanner":   &types.AttributeValueMemberS{Value: scannerID.String()},
+			":gate":      &types.AttributeValueMemberS{Value: gate},
+			":now":       &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrTicketStatusConflict
+		}
+		return fmt.Errorf("error registrando ingreso sin conexión: %w", err)
+	}
+	return nil
+}
+
+func (d *DynamoClient) GetTicketByID(ctx context.Context, ticketID string) (*model.Ticket, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	result, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.Tables.Tickets),
 		Key: map[string]types.AttributeValue{
 			"id": &types.AttributeValueMemberS{Value: ticketID},
 		},
@@ -80,7 +296,7 @@ func (d *DynamoClient) GetTicketByID(ticketID string) (*model.Ticket, error) {
 	}
 
 	if result.Item == nil {
-		return nil, errors.New("ticket not found")
+		return nil, ErrTicketNotFound
 	}
 
 	ticket, err := d.unmarshalTicket(result.Item)
@@ -91,63 +307,259 @@ func (d *DynamoClient) GetTicketByID(ticketID string) (*model.Ticket, error) {
 	return ticket, nil
 }
 
-func (d *DynamoClient) GetTickets(userEmail, eventID string, limit int) ([]model.Ticket, error) {
-	scanInput := &dynamodb.ScanInput{
-		TableName: aws.String("tickets"),
-		Limit:     aws.Int32(int32(limit)),
+// GetTickets devuelve una página de hasta limit tickets y el cursor de la
+// siguiente página (vacío si no hay más). Con filtros usa Query sobre los índices
+// event_id-index o email-index en lugar de recorrer la tabla entera.
+func (d *DynamoClient) GetTickets(ctx context.Context, userEmail, eventID string, limit int, cursor string) ([]model.Ticket, string, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
 	}
 
-	if userEmail != "" || eventID != "" {
-		filterExpressions := []string{}
-		expressionAttributeNames := make(map[string]string)
-		expressionAttributeValues := make(map[string]types.AttributeValue)
+	if eventID != "" {
+		eventUUID, err := uuid.Parse(eventID)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid event ID format: %v", err)
+		}
+		eventID = eventUUID.String()
+	}
 
+	var fetch func(startKey map[string]types.AttributeValue, pageSize int32) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error)
+
+	switch {
+	case eventID != "":
+		queryInput := &dynamodb.QueryInput{
+			TableName:              aws.String(d.Tables.Tickets),
+			IndexName:              aws.String(TicketsEventIndex),
+			KeyConditionExpression: aws.String("#event_id = :event_id"),
+			ExpressionAttributeNames: map[string]string{
+				"#event_id": "event_id",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":event_id": &types.AttributeValueMemberS{Value: eventID},
+			},
+		}
 		if userEmail != "" {
-			filterExpressions = append(filterExpressions, "#email = :email")
-			expressionAttributeNames["#email"] = "email"
-			expressionAttributeValues[":email"] = &types.AttributeValueMemberS{Value: userEmail}
+			queryInput.FilterExpression = aws.String("#email = :email")
+			queryInput.ExpressionAttributeNames["#email"] = "email"
+			queryInput.ExpressionAttributeValues[":email"] = &types.AttributeValueMemberS{Value: userEmail}
+		}
+		fetch = d.queryPage(ctx, queryInput)
+	case userEmail != "":
+		fetch = d.queryPage(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(d.Tables.Tickets),
+			IndexName:              aws.String(TicketsEmailIndex),
+			KeyConditionExpression: aws.String("#email = :email"),
+			ExpressionAttributeNames: map[string]string{
+				"#email": "email",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":email": &types.AttributeValueMemberS{Value: userEmail},
+			},
+		})
+	default:
+		fetch = func(startKey map[string]types.AttributeValue, pageSize int32) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+			result, err := d.Client.Scan(ctx, &dynamodb.ScanInput{
+				TableName:         aws.String(d.Tables.Tickets),
+				Limit:             aws.Int32(pageSize),
+				ExclusiveStartKey: startKey,
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			return result.Items, result.LastEvaluatedKey, nil
+		}
+	}
+
+	// Limit se aplica antes del FilterExpression, así que se piden páginas hasta
+	// completar limit. Cada página pide como mucho los que faltan para que el
+	// LastEvaluatedKey coincida con el último ticket devuelto.
+	var tickets []model.Ticket
+	for len(tickets) < limit {
+		items, lastKey, err := fetch(startKey, int32(limit-len(tickets)))
+		if err != nil {
+			return nil, "", err
 		}
 
-		if eventID != "" {
-			filterExpressions = append(filterExpressions, "#event_id = :event_id")
-			expressionAttributeNames["#event_id"] = "event_id"
-			eventUUID, err := uuid.Parse(eventID)
+		for _, item := range items {
+			ticket, err := d.unmarshalTicket(item)
 			if err != nil {
-				return nil, fmt.Errorf("invalid event ID format: %v", err)
+				return nil, "", err
 			}
-			expressionAttributeValues[":event_id"] = &types.AttributeValueMemberS{Value: eventUUID.String()}
+			tickets = append(tickets, *ticket)
 		}
 
-		scanInput.FilterExpression = aws.String(strings.Join(filterExpressions, " AND "))
-		scanInput.ExpressionAttributeNames = expressionAttributeNames
-		scanInput.ExpressionAttributeValues = expressionAttributeValues
+		startKey = lastKey
+		if startKey == nil {
+			break
+		}
 	}
 
-	result, err := d.Client.Scan(context.TODO(), scanInput)
+	nextCursor, err := encodeCursor(startKey)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	return tickets, nextCursor, nil
+}
 
-	var tickets []model.Ticket
-	for _, item := range result.Items {
-		ticket, err := d.unmarshalTicket(item)
+func (d *DynamoClient) queryPage(ctx context.Context, input *dynamodb.QueryInput) func(map[string]types.AttributeValue, int32) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+	return func(startKey map[string]types.AttributeValue, pageSize int32) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+		page := *input
+		page.Limit = aws.Int32(pageSize)
+		page.ExclusiveStartKey = startKey
+		result, err := d.Client.Query(ctx, &page)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		tickets = append(tickets, *ticket)
+		return result.Items, result.LastEvaluatedKey, nil
 	}
+}
 
-	return tickets, nil
+// CancelTicket cancela el ticket desde su estado actual y, si descontó un
+// asiento, lo devuelve al evento en la misma transacción. Si el ticket cambió
+// de estado en paralelo devuelve ErrTicketStatusConflict y no toca el evento.
+func (d *DynamoClient) CancelTicket(ctx context.Context, ticket model.Ticket, now time.Time) error {
+	if !model.CanTransitionTicket(ticket.Status, model.TicketStatusCancelled) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, ticket.Status, model.TicketStatusCancelled)
+	}
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+
+	condition, values := ticketSeatCondition(ticket)
+	values[":cancelled"] = &types.AttributeValueMemberS{Value: model.TicketStatusCancelled}
+	values[":released"] = &types.AttributeValueMemberBOOL{Value: false}
+	values[":now"] = &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)}
+
+	items := []types.TransactWriteItem{
+		{
+			Update: &types.Update{
+				TableName: aws.String(d.Tables.Tickets),
+				Key: map[string]types.AttributeValue{
+					"id": &types.AttributeValueMemberS{Value: ticket.ID.String()},
+				},
+				UpdateExpression:          aws.String("SET #status = :cancelled, seat_held = :released, updated_at = :now"),
+				ConditionExpression:       aws.String(condition),
+				ExpressionAttributeNames:  map[string]string{"#status": "status"},
+				ExpressionAttributeValues: values,
+			},
+		},
+	}
+	if ticket.SeatHeld {
+		items = append(items, d.releaseSeatItem(ticket.EventID.String(), now))
+	}
+	return d.writeTicketTransaction(ctx, items, "error cancelando ticket")
 }
 
-func (d *DynamoClient) DeleteTicket(ticketID string) error {
-	_, err := d.Client.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
-		TableName: aws.String("tickets"),
-		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: ticketID},
+// DeleteTicket elimina el ticket si sigue en el estado leído y, si tenía un
+// asiento, lo devuelve al evento en la misma transacción.
+func (d *DynamoClient) DeleteTicket(ctx context.Context, ticket model.Ticket) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+
+	condition, values := ticketSeatCondition(ticket)
+	items := []types.TransactWriteItem{
+		{
+			Delete: &types.Delete{
+				TableName: aws.String(d.Tables.Tickets),
+				Key: map[string]types.AttributeValue{
+					"id": &types.AttributeValueMemberS{Value: ticket.ID.String()},
+				},
+				ConditionExpression:       aws.String(condition),
+				ExpressionAttributeNames:  map[string]string{"#status": "status"},
+				ExpressionAttributeValues: values,
+			},
 		},
-	})
-	return err
+	}
+	if ticket.SeatHeld {
+		items = append(items, d.releaseSeatItem(ticket.EventID.String(), time.Now()))
+	}
+	return d.writeTicketTransaction(ctx, items, "error eliminando ticket")
+}
+
+// ticketSeatCondition exige que el ticket siga en el estado y con el asiento
+// que se leyeron, para que el asiento no se devuelva dos veces.
+func ticketSeatCondition(ticket model.Ticket) (string, map[string]types.AttributeValue) {
+	values := map[string]types.AttributeValue{
+		":from": &types.AttributeValueMemberS{Value: ticket.Status},
+		":held": &types.AttributeValueMemberBOOL{Value: true},
+	}
+	if ticket.SeatHeld {
+		return "#status = :from AND seat_held = :held", values
+	}
+	return "#status = :from AND (attribute_not_exists(seat_held) OR seat_held <> :held)", values
+}
+
+// writeTicketTransaction ejecuta una transacción cuyo primer elemento es el
+// ticket y el segundo, si lo hay, la devolución de su asiento. Si falla la
+// condición del ticket devuelve ErrTicketStatusConflict. Si falla la del evento
+// es que ya no existe y no hay asiento que devolver: se escribe solo el ticket,
+// para que la cancelación no quede bloqueada para siempre.
+func (d *DynamoClient) writeTicketTransaction(ctx context.Context, items []types.TransactWriteItem, errorMsg string) error {
+	_, err := d.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		switch conditionFailedAt(err) {
+		case 0:
+			return ErrTicketStatusConflict
+		case 1:
+			return d.writeTicketTransaction(ctx, items[:1], errorMsg)
+		}
+		return fmt.Errorf("%s: %w", errorMsg, err)
+	}
+	return nil
+}
+
+// conditionFailedAt devuelve la posición del elemento de una transacción
+// cancelada cuya condición falló, o -1 si la transacción falló por otro motivo
+func conditionFailedAt(err error) int {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return -1
+	}
+	for i, reason := range canceled.CancellationReasons {
+		if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+			return i
+		}
+	}
+	return -1
+}
+
+func marshalTicket(ticket model.Ticket) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"id":          &types.AttributeValueMemberS{Value: ticket.ID.String()},
+		"event_id":    &types.AttributeValueMemberS{Value: ticket.EventID.String()},
+		"user_id":     &types.AttributeValueMemberS{Value: ticket.UserID.String()},
+		"email":       &types.AttributeValueMemberS{Value: ticket.Email},
+		"name":        &types.AttributeValueMemberS{Value: ticket.Name},
+		"ticket_code": &types.AttributeValueMemberS{Value: ticket.TicketCode},
+		"seat":        &types.AttributeValueMemberS{Value: ticket.Seat},
+		"status":      &types.AttributeValueMemberS{Value: ticket.Status},
+		"seat_held":   &types.AttributeValueMemberBOOL{Value: ticket.SeatHeld},
+		"price":       &types.AttributeValueMemberN{Value: fmt.Sprintf("%.2f", ticket.Price)},
+		"reserved_at": &types.AttributeValueMemberS{Value: ticket.ReservedAt.UTC().Format(time.RFC3339)},
+		"created_at":  &types.AttributeValueMemberS{Value: ticket.CreatedAt.UTC().Format(time.RFC3339)},
+		"updated_at":  &types.AttributeValueMemberS{Value: ticket.UpdatedAt.UTC().Format(time.RFC3339)},
+	}
+
+	if ticket.ExpiresAt != nil {
+		item["expires_at"] = &types.AttributeValueMemberS{Value: ticket.ExpiresAt.UTC().Format(time.RFC3339)}
+	}
+
+	// RecordOfflineCheckIn compara checked_in_at como texto, así que todas las
+	// horas tienen que guardarse en la misma zona
+	if ticket.CheckedInAt != nil {
+		item["checked_in_at"] = &types.AttributeValueMemberS{Value: ticket.CheckedInAt.UTC().Format(time.RFC3339)}
+	}
+
+	if ticket.CheckedInBy != nil {
+		item["checked_in_by"] = &types.AttributeValueMemberS{Value: ticket.CheckedInBy.String()}
+	}
+
+	if ticket.CheckInGate != "" {
+		item["check_in_gate"] = &types.AttributeValueMemberS{Value: ticket.CheckInGate}
+	}
+	return item
 }
 
 func (d *DynamoClient) unmarshalTicket(item map[string]types.AttributeValue) (*model.Ticket, error) {
@@ -189,10 +601,18 @@ func (d *DynamoClient) unmarshalTicket(item map[string]types.AttributeValue) (*m
 		ticket.TicketCode = ticketCodeVal.Value
 	}
 
+	if seatVal, ok := item["seat"].(*types.AttributeValueMemberS); ok {
+		ticket.Seat = seatVal.Value
+	}
+
 	if statusVal, ok := item["status"].(*types.AttributeValueMemberS); ok {
 		ticket.Status = statusVal.Value
 	}
 
+	if seatHeldVal, ok := item["seat_held"].(*types.AttributeValueMemberBOOL); ok {
+		ticket.SeatHeld = seatHeldVal.Value
+	}
+
 	if priceVal, ok := item["price"].(*types.AttributeValueMemberN); ok {
 		price, err := strconv.ParseFloat(priceVal.Value, 64)
 		if err != nil {
@@ -209,6 +629,34 @@ func (d *DynamoClient) unmarshalTicket(item map[string]types.AttributeValue) (*m
 		ticket.ReservedAt = reservedAt
 	}
 
+	if expiresAtVal, ok := item["expires_at"].(*types.AttributeValueMemberS); ok {
+		expiresAt, err := time.Parse(time.RFC3339, expiresAtVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_at time: %v", err)
+		}
+		ticket.ExpiresAt = &expiresAt
+	}
+
+	if checkedInAtVal, ok := item["checked_in_at"].(*types.AttributeValueMemberS); ok {
+		checkedInAt, err := time.Parse(time.RFC3339, checkedInAtVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checked_in_at time: %v", err)
+		}
+		ticket.CheckedInAt = &checkedInAt
+	}
+
+	if checkedInByVal, ok := item["checked_in_by"].(*types.AttributeValueMemberS); ok {
+		checkedInBy, err := uuid.Parse(checkedInByVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checked_in_by: %v", err)
+		}
+		ticket.CheckedInBy = &checkedInBy
+	}
+
+	if gateVal, ok := item["check_in_gate"].(*types.AttributeValueMemberS); ok {
+		ticket.CheckInGate = gateVal.Value
+	}
+
 	if createdAtVal, ok := item["created_at"].(*types.AttributeValueMemberS); ok {
 		createdAt, err := time.Parse(time.RFC3339, createdAtVal.Value)
 		if err != nil {
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalTicket_StoresTimesInUTC(t *testing.T) {
+	local := time.FixedZone("ART", -3*60*60)
+	checkedIn := time.Date(2026, 12, 31, 21, 30, 0, 0, local)
+	scanner := uuid.New()
+	ticket := model.Ticket{
+		ID:          uuid.New(),
+		EventID:     uuid.New(),
+		Status:      model.TicketStatusUsed,
+		ReservedAt:  checkedIn,
+		CreatedAt:   checkedIn,
+		UpdatedAt:   checkedIn,
+		CheckedInAt: &checkedIn,
+		CheckedInBy: &scanner,
+	}
+
+	item := marshalTicket(ticket)
+	for _, attr := range []string{"reserved_at", "created_at", "updated_at", "checked_in_at"} {
+		value, ok := item[attr].(*types.AttributeValueMemberS)
+		require.True(t, ok, attr)
+		assert.Equal(t, "2027-01-01T00:30:00Z", value.Value, attr)
+	}
+}
+
+func TestMarshalOutbox_OnlyPendingInIndex(t *testing.T) {
+	msg := model.OutboxMessage{ID: uuid.New(), Status: model.OutboxStatusPending, CreatedAt: time.Now()}
+	item := marshalOutbox(msg)
+	assert.Equal(t, &types.AttributeValueMemberS{Value: model.OutboxStatusPending}, item["pending"])
+
+	expiresAt := time.Unix(1_900_000_000, 0)
+	msg.Status = model.OutboxStatusProcessed
+	msg.ExpiresAt = &expiresAt
+	item = marshalOutbox(msg)
+	assert.NotContains(t, item, "pending")
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "1900000000"}, item["expires_at"])
+
+	decoded, err := unmarshalOutbox(item)
+	require.NoError(t, err)
+	assert.True(t, expiresAt.Equal(*decoded.ExpiresAt))
+}
@@ -0,0 +1,329 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+var (
+	ErrEventNotFound         = errors.New("event not found")
+	ErrEventUnavailable      = errors.New("event is not open or has no available capacity")
+	ErrEventCapacityConflict = errors.New("event capacity cannot be lower than the tickets already issued")
+	ErrEventHasTickets       = errors.New("event has tickets issued")
+)
+
+func (d *DynamoClient) SaveEvent(ctx context.Context, event model.Event) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	_, err := d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(d.Tables.Events),
+		Item:                marshalEvent(event),
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("el evento %s ya existe", event.ID)
+		}
+		return fmt.Errorf("error guardando evento en DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// UpdateEvent actualiza los datos descriptivos del evento y ajusta la capacidad en
+// capacityDelta sin pisar las reservas hechas en paralelo.
+func (d *DynamoClient) UpdateEvent(ctx context.Context, event model.Event, capacityDelta int) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	minAvailable := 0
+	if capacityDelta < 0 {
+		minAvailable = -capacityDelta
+	}
+
+	_, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.Tables.Events),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: event.ID.String()},
+		},
+		UpdateExpression: aws.String("SET #name = :name, venue = :venue, start_time = :start_time, end_time = :end_time, " +
+			"timezone = :timezone, hold_minutes = :hold_minutes, rotating_qr = :rotating_qr, logo_key = :logo_key, ticket_template = :ticket_template, #status = :status, updated_at = :updated_at, " +
+			"total_capacity = total_capacity + :delta, available_capacity = available_capacity + :delta"),
+		ConditionExpression: aws.String("attribute_exists(id) AND available_capacity >= :min_available"),
+		ExpressionAttributeNames: map[string]string{
+			"#name":   "name",
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":name":            &types.AttributeValueMemberS{Value: event.Name},
+			":venue":           &types.AttributeValueMemberS{Value: event.Venue},
+			":start_time":      &types.AttributeValueMemberS{Value: event.StartTime.Format(time.RFC3339)},
+			":end_time":        &types.AttributeValueMemberS{Value: event.EndTime.Format(time.RFC3339)},
+			":timezone":        &types.AttributeValueMemberS{Value: event.Timezone},
+			":hold_minutes":    &types.AttributeValueMemberN{Value: strconv.Itoa(event.HoldMinutes)},
+			":rotating_qr":     &types.AttributeValueMemberBOOL{Value: event.RotatingQR},
+			":logo_key":        &types.AttributeValueMemberS{Value: event.LogoKey},
+			":ticket_template": &types.AttributeValueMemberS{Value: marshalTicketTemplate(event.TicketTemplate)},
+			":status":          &types.AttributeValueMemberS{Value: event.Status},
+			":updated_at":      &types.AttributeValueMemberS{Value: event.UpdatedAt.UTC().Format(time.RFC3339)},
+			":delta":           &types.AttributeValueMemberN{Value: strconv.Itoa(capacityDelta)},
+			":min_available":   &types.AttributeValueMemberN{Value: strconv.Itoa(minAvailable)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrEventCapacityConflict
+		}
+		return fmt.Errorf("error actualizando evento en DynamoDB: %w", err)
+	}
+	return nil
+}
+
+func (d *DynamoClient) GetEventByID(ctx context.Context, eventID string) (*model.Event, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	result, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.Tables.Events),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: eventID},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, ErrEventNotFound
+	}
+
+	return unmarshalEvent(result.Item)
+}
+
+// GetEvents devuelve una página de hasta limit eventos y el cursor de la
+// siguiente página (vacío si no hay más), igual que GetTickets.
+func (d *DynamoClient) GetEvents(ctx context.Context, status string, limit int, cursor string) ([]model.Event, string, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	scanInput := dynamodb.ScanInput{
+		TableName: aws.String(d.Tables.Events),
+	}
+	if status != "" {
+		scanInput.FilterExpression = aws.String("#status = :status")
+		scanInput.ExpressionAttributeNames = map[string]string{"#status": "status"}
+		scanInput.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: status},
+		}
+	}
+
+	// Limit se aplica antes del FilterExpression, así que se piden páginas hasta
+	// completar limit, cada una con los que faltan para que el LastEvaluatedKey
+	// coincida con el último evento devuelto
+	var events []model.Event
+	for len(events) < limit {
+		page := scanInput
+		page.Limit = aws.Int32(int32(limit - len(events)))
+		page.ExclusiveStartKey = startKey
+		result, err := d.Client.Scan(ctx, &page)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, item := range result.Items {
+			event, err := unmarshalEvent(item)
+			if err != nil {
+				return nil, "", err
+			}
+			events = append(events, *event)
+		}
+
+		startKey = result.LastEvaluatedKey
+		if startKey == nil {
+			break
+		}
+	}
+
+	nextCursor, err := encodeCursor(startKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return events, nextCursor, nil
+}
+
+// DeleteEvent elimina el evento solo si no tiene asientos descontados, en la
+// misma escritura: una reserva que llegue en paralelo hace fallar la condición
+// y el evento no se borra. Devuelve ErrEventHasTickets en ese caso.
+func (d *DynamoClient) DeleteEvent(ctx context.Context, eventID string) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	_, err := d.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.Tables.Events),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: eventID},
+		},
+		ConditionExpression:                 aws.String("attribute_exists(id) AND available_capacity = total_capacity"),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			if ccf.Item == nil {
+				return ErrEventNotFound
+			}
+			return ErrEventHasTickets
+		}
+		return fmt.Errorf("error eliminando evento en DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// releaseSeatItem devuelve un asiento al evento como parte de una transacción
+func (d *DynamoClient) releaseSeatItem(eventID string, now time.Time) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(d.Tables.Events),
+			Key: map[string]types.AttributeValue{
+				"id": &types.AttributeValueMemberS{Value: eventID},
+			},
+			UpdateExpression:    aws.String("SET available_capacity = available_capacity + :one, updated_at = :now"),
+			ConditionExpression: aws.String("attribute_exists(id)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":one": &types.AttributeValueMemberN{Value: "1"},
+				":now": &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+			},
+		},
+	}
+}
+
+func marshalEvent(event model.Event) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":                 &types.AttributeValueMemberS{Value: event.ID.String()},
+		"name":               &types.AttributeValueMemberS{Value: event.Name},
+		"venue":              &types.AttributeValueMemberS{Value: event.Venue},
+		"start_time":         &types.AttributeValueMemberS{Value: event.StartTime.Format(time.RFC3339)},
+		"end_time":           &types.AttributeValueMemberS{Value: event.EndTime.Format(time.RFC3339)},
+		"timezone":           &types.AttributeValueMemberS{Value: event.Timezone},
+		"total_capacity":     &types.AttributeValueMemberN{Value: strconv.Itoa(event.TotalCapacity)},
+		"available_capacity": &types.AttributeValueMemberN{Value: strconv.Itoa(event.AvailableCapacity)},
+		"hold_minutes":       &types.AttributeValueMemberN{Value: strconv.Itoa(event.HoldMinutes)},
+		"rotating_qr":        &types.AttributeValueMemberBOOL{Value: event.RotatingQR},
+		"logo_key":           &types.AttributeValueMemberS{Value: event.LogoKey},
+		"ticket_template":    &types.AttributeValueMemberS{Value: marshalTicketTemplate(event.TicketTemplate)},
+		"status":             &types.AttributeValueMemberS{Value: event.Status},
+		"created_at":         &types.AttributeValueMemberS{Value: event.CreatedAt.UTC().Format(time.RFC3339)},
+		"updated_at":         &types.AttributeValueMemberS{Value: event.UpdatedAt.UTC().Format(time.RFC3339)},
+	}
+}
+
+// marshalTicketTemplate guarda la plantilla como JSON; vacío si el evento usa la de por defecto
+func marshalTicketTemplate(template *model.TicketTemplate) string {
+	if template == nil {
+		return ""
+	}
+	data, err := json.Marshal(template)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func unmarshalEvent(item map[string]types.AttributeValue) (*model.Event, error) {
+	event := &model.Event{}
+
+	if idVal, ok := item["id"].(*types.AttributeValueMemberS); ok {
+		id, err := uuid.Parse(idVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event ID: %v", err)
+		}
+		event.ID = id
+	}
+
+	if nameVal, ok := item["name"].(*types.AttributeValueMemberS); ok {
+		event.Name = nameVal.Value
+	}
+
+	if venueVal, ok := item["venue"].(*types.AttributeValueMemberS); ok {
+		event.Venue = venueVal.Value
+	}
+
+	if timezoneVal, ok := item["timezone"].(*types.AttributeValueMemberS); ok {
+		event.Timezone = timezoneVal.Value
+	}
+
+	if statusVal, ok := item["status"].(*types.AttributeValueMemberS); ok {
+		event.Status = statusVal.Value
+	}
+
+	if totalVal, ok := item["total_capacity"].(*types.AttributeValueMemberN); ok {
+		total, err := strconv.Atoi(totalVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid total_capacity: %v", err)
+		}
+		event.TotalCapacity = total
+	}
+
+	if availableVal, ok := item["available_capacity"].(*types.AttributeValueMemberN); ok {
+		available, err := strconv.Atoi(availableVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid available_capacity: %v", err)
+		}
+		event.AvailableCapacity = available
+	}
+
+	if holdVal, ok := item["hold_minutes"].(*types.AttributeValueMemberN); ok {
+		hold, err := strconv.Atoi(holdVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hold_minutes: %v", err)
+		}
+		event.HoldMinutes = hold
+	}
+
+	if rotatingVal, ok := item["rotating_qr"].(*types.AttributeValueMemberBOOL); ok {
+		event.RotatingQR = rotatingVal.Value
+	}
+
+	if logoVal, ok := item["logo_key"].(*types.AttributeValueMemberS); ok {
+		event.LogoKey = logoVal.Value
+	}
+
+	if templateVal, ok := item["ticket_template"].(*types.AttributeValueMemberS); ok && templateVal.Value != "" {
+		var template model.TicketTemplate
+		if err := json.Unmarshal([]byte(templateVal.Value), &template); err != nil {
+			return nil, fmt.Errorf("invalid ticket_template: %v", err)
+		}
+		event.TicketTemplate = &template
+	}
+
+	timeFields := map[string]*time.Time{
+		"start_time": &event.StartTime,
+		"end_time":   &event.EndTime,
+		"created_at": &event.CreatedAt,
+		"updated_at": &event.UpdatedAt,
+	}
+	for name, field := range timeFields {
+		if val, ok := item[name].(*types.AttributeValueMemberS); ok {
+			parsed, err := time.Parse(time.RFC3339, val.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s time: %v", name, err)
+			}
+			*field = parsed
+		}
+	}
+
+	return event, nil
+}
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+var (
+	ErrIdempotencyKeyExists   = errors.New("idempotency key already exists")
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+)
+
+// AcquireIdempotencyKey registra la clave en estado in_progress hasta
+// record.LockExpiresAt. Devuelve ErrIdempotencyKeyExists si otra petición ya la
+// registró y no ha vencido ni ella ni su plazo en curso: si el proceso que la
+// tenía murió sin liberarla, un reintento la toma al vencer el plazo.
+// La tabla tiene TTL sobre expires_at, pero DynamoDB borra los items con retraso,
+// así que los vencidos se tratan como inexistentes.
+func (d *DynamoClient) AcquireIdempotencyKey(ctx context.Context, record model.IdempotencyRecord) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	_, err := d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.Tables.Idempotency),
+		Item:      marshalIdempotencyRecord(record),
+		ConditionExpression: aws.String("attribute_not_exists(id) OR expires_at < :now OR " +
+			"(#status = :in_progress AND lock_expires_at < :now)"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":         &types.AttributeValueMemberN{Value: strconv.FormatInt(record.CreatedAt.Unix(), 10)},
+			":in_progress": &types.AttributeValueMemberS{Value: model.IdempotencyStatusInProgress},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrIdempotencyKeyExists
+		}
+		return fmt.Errorf("error registrando clave de idempotencia: %w", err)
+	}
+	return nil
+}
+
+func (d *DynamoClient) GetIdempotencyRecord(ctx context.Context, key string, now time.Time) (*model.IdempotencyRecord, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	result, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.Tables.Idempotency),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: key},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+
+	record, err := unmarshalIdempotencyRecord(result.Item)
+	if err != nil {
+		return nil, err
+	}
+	if !record.ExpiresAt.After(now) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	return record, nil
+}
+
+// CompleteIdempotencyKey guarda la respuesta de la petición que tenía la clave.
+// Si otro reintento la tomó al vencer el plazo de record, la respuesta no se guarda.
+func (d *DynamoClient) CompleteIdempotencyKey(ctx context.Context, record model.IdempotencyRecord, statusCode int, contentType, body string) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	_, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.Tables.Idempotency),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: record.Key},
+		},
+		UpdateExpression:    aws.String("SET #status = :completed, status_code = :code, content_type = :content_type, response_body = :body REMOVE lock_expires_at"),
+		ConditionExpression: aws.String("#status = :in_progress AND lock_expires_at = :lock"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":completed":    &types.AttributeValueMemberS{Value: model.IdempotencyStatusCompleted},
+			":in_progress":  &types.AttributeValueMemberS{Value: model.IdempotencyStatusInProgress},
+			":lock":         &types.AttributeValueMemberN{Value: strconv.FormatInt(record.LockExpiresAt.Unix(), 10)},
+			":code":         &types.AttributeValueMemberN{Value: strconv.Itoa(statusCode)},
+			":content_type": &types.AttributeValueMemberS{Value: contentType},
+			":body":         &types.AttributeValueMemberS{Value: body},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error guardando respuesta idempotente: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey borra una clave que no llegó a completarse para que el
+// cliente pueda reintentar. Solo la borra si sigue siendo de record.
+func (d *DynamoClient) ReleaseIdempotencyKey(ctx context.Context, record model.IdempotencyRecord) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	_, err := d.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.Tables.Idempotency),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: record.Key},
+		},
+		ConditionExpression: aws.String("#status = :in_progress AND lock_expires_at = :lock"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":in_progress": &types.AttributeValueMemberS{Value: model.IdempotencyStatusInProgress},
+			":lock":        &types.AttributeValueMemberN{Value: strconv.FormatInt(record.LockExpiresAt.Unix(), 10)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil
+		}
+		return fmt.Errorf("error liberando clave de idempotencia: %w", err)
+	}
+	return nil
+}
+
+func marshalIdempotencyRecord(record model.IdempotencyRecord) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"id":           &types.AttributeValueMemberS{Value: record.Key},
+		"request_hash": &types.AttributeValueMemberS{Value: record.RequestHash},
+		"status":       &types.AttributeValueMemberS{Value: record.Status},
+		"created_at":   &types.AttributeValueMemberS{Value: record.CreatedAt.UTC().Format(time.RFC3339)},
+		// TTL de DynamoDB: segundos desde epoch
+		"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(record.ExpiresAt.Unix(), 10)},
+	}
+
+	if !record.LockExpiresAt.IsZero() {
+		item["lock_expires_at"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(record.LockExpiresAt.Unix(), 10)}
+	}
+
+	if record.StatusCode != 0 {
+		item["status_code"] = &types.AttributeValueMemberN{Value: strconv.Itoa(record.StatusCode)}
+		item["content_type"] = &types.AttributeValueMemberS{Value: record.ContentType}
+		item["response_body"] = &types.AttributeValueMemberS{Value: record.ResponseBody}
+	}
+
+	return item
+}
+
+func unmarshalIdempotencyRecord(item map[string]types.AttributeValue) (*model.IdempotencyRecord, error) {
+	record := &model.IdempotencyRecord{}
+
+	if idVal, ok := item["id"].(*types.AttributeValueMemberS); ok {
+		record.Key = idVal.Value
+	}
+
+	if hashVal, ok := item["request_hash"].(*types.AttributeValueMemberS); ok {
+		record.RequestHash = hashVal.Value
+	}
+
+	if statusVal, ok := item["status"].(*types.AttributeValueMemberS); ok {
+		record.Status = statusVal.Value
+	}
+
+	if codeVal, ok := item["status_code"].(*types.AttributeValueMemberN); ok {
+		code, err := strconv.Atoi(codeVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status_code: %v", err)
+		}
+		record.StatusCode = code
+	}
+
+	if contentTypeVal, ok := item["content_type"].(*types.AttributeValueMemberS); ok {
+		record.ContentType = contentTypeVal.Value
+	}
+
+	if bodyVal, ok := item["response_body"].(*types.AttributeValueMemberS); ok {
+		record.ResponseBody = bodyVal.Value
+	}
+
+	if createdAtVal, ok := item["created_at"].(*types.AttributeValueMemberS); ok {
+		createdAt, err := time.Parse(time.RFC3339, createdAtVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_at time: %v", err)
+		}
+		record.CreatedAt = createdAt
+	}
+
+	if expiresAtVal, ok := item["expires_at"].(*types.AttributeValueMemberN); ok {
+		expiresAt, err := strconv.ParseInt(expiresAtVal.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_at: %v", err)
+		}
+		record.ExpiresAt = time.Unix(expiresAt, 0)
+	}
+
+	if lockVal, ok := item["lock_expires_at"].(*types.AttributeValueMemberN); ok {
+		lockExpiresAt, err := strconv.ParseInt(lockVal.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lock_expires_at: %v", err)
+		}
+		record.LockExpiresAt = time.Unix(lockExpiresAt, 0)
+	}
+
+	return record, nil
+}
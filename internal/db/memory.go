@@ -0,0 +1,593 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+// MemoryRepository es una implementación de Repository en memoria, segura para
+// uso concurrente. Permite ejecutar la API y sus pruebas sin LocalStack.
+type MemoryRepository struct {
+	mu      sync.RWMutex
+	tickets map[string]model.Ticket
+	events  map[string]model.Event
+	outbox  map[string]model.OutboxMessage
+	keys    map[string]model.IdempotencyRecord
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		tickets: make(map[string]model.Ticket),
+		events:  make(map[string]model.Event),
+		outbox:  make(map[string]model.OutboxMessage),
+		keys:    make(map[string]model.IdempotencyRecord),
+	}
+}
+
+// Ping siempre está disponible: los datos viven en el proceso
+func (m *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryRepository) SaveTicket(ctx context.Context, ticket model.Ticket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.tickets[ticket.ID.String()]; exists {
+		return errors.New("El ticket ya existe en la base de datos.")
+	}
+	m.tickets[ticket.ID.String()] = ticket
+	return nil
+}
+
+func (m *MemoryRepository) GetTicketByID(ctx context.Context, ticketID string) (*model.Ticket, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ticket, ok := m.tickets[ticketID]
+	if !ok {
+		return nil, ErrTicketNotFound
+	}
+	return &ticket, nil
+}
+
+// GetTickets pagina igual que DynamoClient: el cursor apunta al último ticket
+// devuelto y la siguiente página empieza justo después de él.
+func (m *MemoryRepository) GetTickets(ctx context.Context, userEmail, eventID string, limit int, cursor string) ([]model.Ticket, string, error) {
+	var afterID string
+	if cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(raw) == 0 {
+			return nil, "", ErrInvalidCursor
+		}
+		afterID = string(raw)
+	}
+
+	if eventID != "" {
+		eventUUID, err := uuid.Parse(eventID)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid event ID format: %v", err)
+		}
+		eventID = eventUUID.String()
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	all := m.sortedTickets()
+	start := 0
+	if afterID != "" {
+		start = -1
+		for i, ticket := range all {
+			if ticket.ID.String() == afterID {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+
+	var tickets []model.Ticket
+	for i := start; i < len(all); i++ {
+		ticket := all[i]
+		if userEmail != "" && ticket.Email != userEmail {
+			continue
+		}
+		if eventID != "" && ticket.EventID.String() != eventID {
+			continue
+		}
+		tickets = append(tickets, ticket)
+		if len(tickets) >= limit {
+			if i < len(all)-1 {
+				return tickets, base64.RawURLEncoding.EncodeToString([]byte(ticket.ID.String())), nil
+			}
+			break
+		}
+	}
+	return tickets, "", nil
+}
+
+func (m *MemoryRepository) DeleteTicket(ctx context.Context, ticket model.Ticket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.releaseTicketSeat(ticket, time.Now()); err != nil {
+		return err
+	}
+	delete(m.tickets, ticket.ID.String())
+	return nil
+}
+
+func (m *MemoryRepository) CancelTicket(ctx context.Context, ticket model.Ticket, now time.Time) error {
+	if !model.CanTransitionTicket(ticket.Status, model.TicketStatusCancelled) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, ticket.Status, model.TicketStatusCancelled)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.releaseTicketSeat(ticket, now); err != nil {
+		return err
+	}
+	current := m.tickets[ticket.ID.String()]
+	current.Status = model.TicketStatusCancelled
+	current.SeatHeld = false
+	current.UpdatedAt = now
+	m.tickets[ticket.ID.String()] = current
+	return nil
+}
+
+// releaseTicketSeat comprueba, como la condición de DynamoClient, que el ticket
+// sigue en el estado y con el asiento leídos, y devuelve el asiento al evento si
+// todavía existe. Debe llamarse con el lock tomado.
+func (m *MemoryRepository) releaseTicketSeat(ticket model.Ticket, now time.Time) error {
+	current, ok := m.tickets[ticket.ID.String()]
+	if !ok || current.Status != ticket.Status || current.SeatHeld != ticket.SeatHeld {
+		return ErrTicketStatusConflict
+	}
+	if !current.SeatHeld {
+		return nil
+	}
+
+	event, ok := m.events[current.EventID.String()]
+	if !ok {
+		return nil
+	}
+	event.AvailableCapacity++
+	event.UpdatedAt = now
+	m.events[event.ID.String()] = event
+	return nil
+}
+
+func (m *MemoryRepository) UpdateTicket(ctx context.Context, ticket model.Ticket, expectedStatus string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.tickets[ticket.ID.String()]
+	if !ok || current.Status != expectedStatus {
+		return ErrTicketStatusConflict
+	}
+	m.tickets[ticket.ID.String()] = ticket
+	return nil
+}
+
+func (m *MemoryRepository) TransitionTicketStatus(ctx context.Context, ticketID, from, to string, now time.Time) error {
+	if !model.CanTransitionTicket(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ticket, ok := m.tickets[ticketID]
+	if !ok || ticket.Status != from {
+		return ErrTicketStatusConflict
+	}
+
+	if from == model.TicketStatusReserved && to == model.TicketStatusConfirmed {
+		if ticket.ExpiresAt != nil && !ticket.ExpiresAt.After(now) {
+			return ErrTicketStatusConflict
+		}
+		ticket.ExpiresAt = nil
+	}
+
+	ticket.Status = to
+	ticket.UpdatedAt = now
+	m.tickets[ticketID] = ticket
+	return nil
+}
+
+func (m *MemoryRepository) CheckInTicket(ctx context.Context, ticketID string, scannerID uuid.UUID, gate string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ticket, ok := m.tickets[ticketID]
+	if !ok || ticket.Status != model.TicketStatusConfirmed {
+		return ErrTicketStatusConflict
+	}
+
+	ticket.Status = model.TicketStatusUsed
+	ticket.CheckedInAt = &now
+	ticket.CheckedInBy = &scannerID
+	ticket.CheckInGate = gate
+	ticket.UpdatedAt = now
+	m.tickets[ticketID] = ticket
+	return nil
+}
+
+func (m *MemoryRepository) RecordOfflineCheckIn(ctx context.Context, ticketID string, scannerID uuid.UUID, gate string, scannedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ticket, ok := m.tickets[ticketID]
+	if !ok {
+		return ErrTicketStatusConflict
+	}
+
+	// Misma regla que DynamoClient: gana el ingreso más antiguo, con precisión de
+	// segundos, y a igualdad el scanner_id menor
+	scannedAt = scannedAt.UTC().Truncate(time.Second)
+	switch ticket.Status {
+	case model.TicketStatusConfirmed:
+	case model.TicketStatusUsed:
+		if ticket.CheckedInAt == nil || ticket.CheckedInBy == nil {
+			return ErrTicketStatusConflict
+		}
+		current := ticket.CheckedInAt.UTC().Truncate(time.Second)
+		if !current.After(scannedAt) && !(current.Equal(scannedAt) && ticket.CheckedInBy.String() > scannerID.String()) {
+			return ErrTicketStatusConflict
+		}
+	default:
+		return ErrTicketStatusConflict
+	}
+
+	ticket.Status = model.TicketStatusUsed
+	ticket.CheckedInAt = &scannedAt
+	ticket.CheckedInBy = &scannerID
+	ticket.CheckInGate = gate
+	ticket.UpdatedAt = time.Now()
+	m.tickets[ticketID] = ticket
+	return nil
+}
+
+func (m *MemoryRepository) ExpireReservation(ctx context.Context, ticket model.Ticket, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.tickets[ticket.ID.String()]
+	if !ok || !isExpiredReservation(current, now) || current.SeatHeld != ticket.SeatHeld {
+		return ErrReservationNotPending
+	}
+	if err := m.releaseTicketSeat(current, now); err != nil {
+		return err
+	}
+
+	current.Status = model.TicketStatusCancelled
+	current.SeatHeld = false
+	current.UpdatedAt = now
+	m.tickets[ticket.ID.String()] = current
+	return nil
+}
+
+func (m *MemoryRepository) GetExpiredReservations(ctx context.Context, now time.Time, limit int) ([]model.Ticket, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tickets []model.Ticket
+	for _, ticket := range m.sortedTickets() {
+		if !isExpiredReservation(ticket, now) {
+			continue
+		}
+		tickets = append(tickets, ticket)
+		if len(tickets) >= limit {
+			break
+		}
+	}
+	return tickets, nil
+}
+
+func (m *MemoryRepository) SaveEvent(ctx context.Context, event model.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.events[event.ID.String()]; exists {
+		return fmt.Errorf("el evento %s ya existe", event.ID)
+	}
+	m.events[event.ID.String()] = event
+	return nil
+}
+
+func (m *MemoryRepository) UpdateEvent(ctx context.Context, event model.Event, capacityDelta int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.events[event.ID.String()]
+	if !ok || current.AvailableCapacity+capacityDelta < 0 {
+		return ErrEventCapacityConflict
+	}
+
+	event.TotalCapacity = current.TotalCapacity + capacityDelta
+	event.AvailableCapacity = current.AvailableCapacity + capacityDelta
+	event.CreatedAt = current.CreatedAt
+	m.events[event.ID.String()] = event
+	return nil
+}
+
+func (m *MemoryRepository) GetEventByID(ctx context.Context, eventID string) (*model.Event, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	event, ok := m.events[eventID]
+	if !ok {
+		return nil, ErrEventNotFound
+	}
+	return &event, nil
+}
+
+// GetEvents pagina igual que GetTickets: el cursor apunta al último evento devuelto
+func (m *MemoryRepository) GetEvents(ctx context.Context, status string, limit int, cursor string) ([]model.Event, string, error) {
+	var afterID string
+	if cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(raw) == 0 {
+			return nil, "", ErrInvalidCursor
+		}
+		afterID = string(raw)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	all := make([]model.Event, 0, len(m.events))
+	for _, event := range m.events {
+		all = append(all, event)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].ID.String() < all[j].ID.String()
+		}
+		return all[i].CreatedAt.Before(all[j].CreatedAt)
+	})
+
+	start := 0
+	if afterID != "" {
+		start = -1
+		for i, event := range all {
+			if event.ID.String() == afterID {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+
+	var events []model.Event
+	for i := start; i < len(all); i++ {
+		event := all[i]
+		if status != "" && event.Status != status {
+			continue
+		}
+		events = append(events, event)
+		if len(events) >= limit {
+			if i < len(all)-1 {
+				return events, base64.RawURLEncoding.EncodeToString([]byte(event.ID.String())), nil
+			}
+			break
+		}
+	}
+	return events, "", nil
+}
+
+func (m *MemoryRepository) DeleteEvent(ctx context.Context, eventID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event, ok := m.events[eventID]
+	if !ok {
+		return ErrEventNotFound
+	}
+	if event.AvailableCapacity != event.TotalCapacity {
+		return ErrEventHasTickets
+	}
+	delete(m.events, eventID)
+	return nil
+}
+
+func (m *MemoryRepository) CreateReservation(ctx context.Context, ticket model.Ticket, outbox model.OutboxMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event, ok := m.events[ticket.EventID.String()]
+	if !ok || event.Status != model.EventStatusOpen || event.AvailableCapacity < 1 {
+		return ErrEventUnavailable
+	}
+	if _, exists := m.tickets[ticket.ID.String()]; exists {
+		return fmt.Errorf("error guardando reserva: el ticket %s ya existe", ticket.ID)
+	}
+
+	event.AvailableCapacity--
+	event.UpdatedAt = time.Now()
+	m.events[event.ID.String()] = event
+	ticket.SeatHeld = true
+	m.tickets[ticket.ID.String()] = ticket
+	m.outbox[outbox.ID.String()] = outbox
+	return nil
+}
+
+func (m *MemoryRepository) GetPendingOutbox(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var messages []model.OutboxMessage
+	for _, msg := range m.outbox {
+		if msg.Status == model.OutboxStatusPending {
+			messages = append(messages, msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].CreatedAt.Before(messages[j].CreatedAt) })
+
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (m *MemoryRepository) GetOutboxMessage(ctx context.Context, id string) (*model.OutboxMessage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	msg, ok := m.outbox[id]
+	if !ok {
+		return nil, ErrOutboxNotFound
+	}
+	return &msg, nil
+}
+
+func (m *MemoryRepository) MarkOutboxSent(ctx context.Context, id string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg, ok := m.outbox[id]
+	if !ok || msg.Status != model.OutboxStatusPending {
+		return nil
+	}
+	msg.Status = model.OutboxStatusSent
+	msg.SentAt = &now
+	msg.Attempts++
+	m.outbox[id] = msg
+	return nil
+}
+
+func (m *MemoryRepository) RecordOutboxAttempt(ctx context.Context, id string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg, ok := m.outbox[id]
+	if !ok {
+		return 0, ErrOutboxNotFound
+	}
+	msg.Attempts++
+	m.outbox[id] = msg
+	return msg.Attempts, nil
+}
+
+func (m *MemoryRepository) ParkOutbox(ctx context.Context, id string, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg, ok := m.outbox[id]
+	if !ok || msg.Status != model.OutboxStatusPending {
+		return nil
+	}
+	msg.Status = model.OutboxStatusFailed
+	msg.LastError = reason
+	m.outbox[id] = msg
+	return nil
+}
+
+func (m *MemoryRepository) MarkOutboxProcessed(ctx context.Context, id string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg, ok := m.outbox[id]
+	if !ok || msg.Status == model.OutboxStatusProcessed {
+		return ErrOutboxAlreadyProcessed
+	}
+	msg.Status = model.OutboxStatusProcessed
+	msg.ProcessedAt = &now
+	expiresAt := now.Add(model.OutboxRetention)
+	msg.ExpiresAt = &expiresAt
+	m.outbox[id] = msg
+	return nil
+}
+
+func (m *MemoryRepository) AcquireIdempotencyKey(ctx context.Context, record model.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.keys[record.Key]; ok && existing.ExpiresAt.After(record.CreatedAt) {
+		// Como en DynamoClient, una clave en curso cuyo plazo venció se puede tomar
+		lapsed := existing.Status == model.IdempotencyStatusInProgress && existing.LockExpiresAt.Unix() < record.CreatedAt.Unix()
+		if !lapsed {
+			return ErrIdempotencyKeyExists
+		}
+	}
+	m.keys[record.Key] = record
+	return nil
+}
+
+func (m *MemoryRepository) GetIdempotencyRecord(ctx context.Context, key string, now time.Time) (*model.IdempotencyRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	record, ok := m.keys[key]
+	if !ok || !record.ExpiresAt.After(now) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	return &record, nil
+}
+
+func (m *MemoryRepository) CompleteIdempotencyKey(ctx context.Context, owner model.IdempotencyRecord, statusCode int, contentType, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.keys[owner.Key]
+	if !ok || !ownsIdempotencyKey(record, owner) {
+		return fmt.Errorf("error guardando respuesta idempotente: la clave %s no está en curso", owner.Key)
+	}
+	record.Status = model.IdempotencyStatusCompleted
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.ResponseBody = body
+	record.LockExpiresAt = time.Time{}
+	m.keys[owner.Key] = record
+	return nil
+}
+
+func (m *MemoryRepository) ReleaseIdempotencyKey(ctx context.Context, owner model.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if record, ok := m.keys[owner.Key]; ok && ownsIdempotencyKey(record, owner) {
+		delete(m.keys, owner.Key)
+	}
+	return nil
+}
+
+// ownsIdempotencyKey indica si la clave sigue en curso a nombre de owner, con la
+// misma precisión de segundos que DynamoClient
+func ownsIdempotencyKey(record, owner model.IdempotencyRecord) bool {
+	return record.Status == model.IdempotencyStatusInProgress && record.LockExpiresAt.Unix() == owner.LockExpiresAt.Unix()
+}
+
+// sortedTickets devuelve los tickets ordenados por fecha de creación. Debe
+// llamarse con el lock tomado.
+func (m *MemoryRepository) sortedTickets() []model.Ticket {
+	tickets := make([]model.Ticket, 0, len(m.tickets))
+	for _, ticket := range m.tickets {
+		tickets = append(tickets, ticket)
+	}
+	sort.Slice(tickets, func(i, j int) bool {
+		if tickets[i].CreatedAt.Equal(tickets[j].CreatedAt) {
+			return tickets[i].ID.String() < tickets[j].ID.String()
+		}
+		return tickets[i].CreatedAt.Before(tickets[j].CreatedAt)
+	})
+	return tickets
+}
+
+func isExpiredReservation(ticket model.Ticket, now time.Time) bool {
+	return ticket.Status == model.TicketStatusReserved && ticket.ExpiresAt != nil && !ticket.ExpiresAt.After(now)
+}
@@ -0,0 +1,354 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOpenEvent(t *testing.T, repo *MemoryRepository, capacity int) model.Event {
+	t.Helper()
+	now := time.Now()
+	event := model.Event{
+		ID:                uuid.New(),
+		Name:              "Concierto",
+		Venue:             "Estadio",
+		StartTime:         now.Add(24 * time.Hour),
+		EndTime:           now.Add(27 * time.Hour),
+		Timezone:          "UTC",
+		TotalCapacity:     capacity,
+		AvailableCapacity: capacity,
+		Status:            model.EventStatusOpen,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	require.NoError(t, repo.SaveEvent(context.Background(), event))
+	return event
+}
+
+func newReservation(eventID uuid.UUID, expiresAt time.Time) (model.Ticket, model.OutboxMessage) {
+	now := time.Now()
+	ticket := model.Ticket{
+		ID:         uuid.New(),
+		EventID:    eventID,
+		UserID:     uuid.New(),
+		Email:      "test@example.com",
+		Status:     model.TicketStatusReserved,
+		ReservedAt: now,
+		ExpiresAt:  &expiresAt,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	outbox := model.OutboxMessage{
+		ID:          uuid.New(),
+		AggregateID: ticket.ID.String(),
+		Status:      model.OutboxStatusPending,
+		CreatedAt:   now,
+	}
+	return ticket, outbox
+}
+
+func TestMemoryRepository_CreateReservationDoesNotOversell(t *testing.T) {
+	repo := NewMemoryRepository()
+	event := newOpenEvent(t, repo, 5)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, soldOut := 0, 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticket, outbox := newReservation(event.ID, time.Now().Add(time.Minute))
+			err := repo.CreateReservation(context.Background(), ticket, outbox)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			} else if errors.Is(err, ErrEventUnavailable) {
+				soldOut++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, succeeded)
+	assert.Equal(t, 15, soldOut)
+
+	stored, err := repo.GetEventByID(context.Background(), event.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.AvailableCapacity)
+
+	pending, err := repo.GetPendingOutbox(context.Background(), 100)
+	require.NoError(t, err)
+	assert.Len(t, pending, 5)
+}
+
+func TestMemoryRepository_TransitionRequiresPreviousStatus(t *testing.T) {
+	repo := NewMemoryRepository()
+	event := newOpenEvent(t, repo, 1)
+	ticket, outbox := newReservation(event.ID, time.Now().Add(time.Minute))
+	require.NoError(t, repo.CreateReservation(context.Background(), ticket, outbox))
+
+	now := time.Now()
+	require.NoError(t, repo.TransitionTicketStatus(context.Background(), ticket.ID.String(), model.TicketStatusReserved, model.TicketStatusConfirmed, now))
+
+	err := repo.TransitionTicketStatus(context.Background(), ticket.ID.String(), model.TicketStatusReserved, model.TicketStatusCancelled, now)
+	assert.ErrorIs(t, err, ErrTicketStatusConflict)
+
+	err = repo.TransitionTicketStatus(context.Background(), ticket.ID.String(), model.TicketStatusUsed, model.TicketStatusConfirmed, now)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	stored, err := repo.GetTicketByID(context.Background(), ticket.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusConfirmed, stored.Status)
+	assert.Nil(t, stored.ExpiresAt)
+}
+
+func TestMemoryRepository_ExpiredHoldCannotBeConfirmed(t *testing.T) {
+	repo := NewMemoryRepository()
+	event := newOpenEvent(t, repo, 1)
+	ticket, outbox := newReservation(event.ID, time.Now().Add(-time.Second))
+	require.NoError(t, repo.CreateReservation(context.Background(), ticket, outbox))
+
+	err := repo.TransitionTicketStatus(context.Background(), ticket.ID.String(), model.TicketStatusReserved, model.TicketStatusConfirmed, time.Now())
+	assert.ErrorIs(t, err, ErrTicketStatusConflict)
+
+	expired, err := repo.GetExpiredReservations(context.Background(), time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+
+	require.NoError(t, repo.ExpireReservation(context.Background(), expired[0], time.Now()))
+	assert.ErrorIs(t, repo.ExpireReservation(context.Background(), expired[0], time.Now()), ErrReservationNotPending)
+
+	stored, err := repo.GetEventByID(context.Background(), event.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.AvailableCapacity)
+}
+
+func TestMemoryRepository_CancelAndDeleteReleaseSeatOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	event := newOpenEvent(t, repo, 2)
+	available := func() int {
+		stored, err := repo.GetEventByID(ctx, event.ID.String())
+		require.NoError(t, err)
+		return stored.AvailableCapacity
+	}
+
+	cancelled, outbox := newReservation(event.ID, time.Now().Add(time.Hour))
+	require.NoError(t, repo.CreateReservation(ctx, cancelled, outbox))
+	deleted, outbox := newReservation(event.ID, time.Now().Add(time.Hour))
+	require.NoError(t, repo.CreateReservation(ctx, deleted, outbox))
+	require.Equal(t, 0, available())
+
+	stored, err := repo.GetTicketByID(ctx, cancelled.ID.String())
+	require.NoError(t, err)
+	require.True(t, stored.SeatHeld)
+	require.NoError(t, repo.CancelTicket(ctx, *stored, time.Now()))
+	assert.Equal(t, 1, available())
+
+	// Repetir la cancelación con el ticket leído antes no devuelve otro asiento
+	assert.ErrorIs(t, repo.CancelTicket(ctx, *stored, time.Now()), ErrTicketStatusConflict)
+	stored, err = repo.GetTicketByID(ctx, cancelled.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusCancelled, stored.Status)
+	assert.False(t, stored.SeatHeld)
+
+	// Eliminar un ticket cancelado tampoco, porque ya lo devolvió
+	require.NoError(t, repo.DeleteTicket(ctx, *stored))
+	assert.Equal(t, 1, available())
+
+	stored, err = repo.GetTicketByID(ctx, deleted.ID.String())
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTicket(ctx, *stored))
+	assert.Equal(t, 2, available())
+	assert.ErrorIs(t, repo.DeleteTicket(ctx, *stored), ErrTicketStatusConflict)
+	assert.Equal(t, 2, available())
+}
+
+func TestMemoryRepository_CancelWithoutSeatKeepsCapacity(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	event := newOpenEvent(t, repo, 1)
+	ticket, _ := newReservation(event.ID, time.Now().Add(time.Hour))
+	require.NoError(t, repo.SaveTicket(ctx, ticket))
+
+	require.NoError(t, repo.CancelTicket(ctx, ticket, time.Now()))
+
+	stored, err := repo.GetEventByID(ctx, event.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.AvailableCapacity)
+}
+
+func TestMemoryRepository_DeleteEventOnlyWithoutTickets(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	event := newOpenEvent(t, repo, 1)
+
+	ticket, outbox := newReservation(event.ID, time.Now().Add(time.Hour))
+	require.NoError(t, repo.CreateReservation(ctx, ticket, outbox))
+	assert.ErrorIs(t, repo.DeleteEvent(ctx, event.ID.String()), ErrEventHasTickets)
+
+	stored, err := repo.GetTicketByID(ctx, ticket.ID.String())
+	require.NoError(t, err)
+	require.NoError(t, repo.CancelTicket(ctx, *stored, time.Now()))
+	require.NoError(t, repo.DeleteEvent(ctx, event.ID.String()))
+	assert.ErrorIs(t, repo.DeleteEvent(ctx, event.ID.String()), ErrEventNotFound)
+}
+
+func TestMemoryRepository_GetTicketsPaginatesWithCursor(t *testing.T) {
+	repo := NewMemoryRepository()
+	event := newOpenEvent(t, repo, 10)
+	otherEvent := newOpenEvent(t, repo, 10)
+
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		for _, eventID := range []uuid.UUID{event.ID, otherEvent.ID} {
+			ticket, _ := newReservation(eventID, base.Add(time.Hour))
+			ticket.CreatedAt = base.Add(time.Duration(i) * time.Second)
+			require.NoError(t, repo.SaveTicket(context.Background(), ticket))
+		}
+	}
+
+	seen := map[uuid.UUID]bool{}
+	cursor := ""
+	pages := 0
+	for {
+		tickets, next, err := repo.GetTickets(context.Background(), "", event.ID.String(), 2, cursor)
+		require.NoError(t, err)
+		pages++
+		for _, ticket := range tickets {
+			assert.Equal(t, event.ID, ticket.EventID)
+			assert.False(t, seen[ticket.ID], "ticket repetido entre páginas")
+			seen[ticket.ID] = true
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	assert.Len(t, seen, 5)
+	assert.Equal(t, 3, pages)
+
+	_, _, err := repo.GetTickets(context.Background(), "", "", 2, "no-es-un-cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestMemoryRepository_GetEventsPaginatesWithCursor(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	var open []uuid.UUID
+	for i := 0; i < 6; i++ {
+		event := newOpenEvent(t, repo, 1)
+		if i%2 == 0 {
+			open = append(open, event.ID)
+			continue
+		}
+		event.Status = model.EventStatusCancelled
+		require.NoError(t, repo.UpdateEvent(ctx, event, 0))
+	}
+
+	var seen []uuid.UUID
+	cursor := ""
+	for {
+		events, next, err := repo.GetEvents(ctx, model.EventStatusOpen, 2, cursor)
+		require.NoError(t, err)
+		for _, event := range events {
+			assert.Equal(t, model.EventStatusOpen, event.Status)
+			seen = append(seen, event.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	assert.ElementsMatch(t, open, seen)
+
+	_, _, err := repo.GetEvents(ctx, "", 2, "no-es-un-cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestMemoryRepository_CheckInTicketOnlyOnce(t *testing.T) {
+	repo := NewMemoryRepository()
+	event := newOpenEvent(t, repo, 1)
+	ticket, outbox := newReservation(event.ID, time.Now().Add(time.Hour))
+	require.NoError(t, repo.CreateReservation(context.Background(), ticket, outbox))
+	require.NoError(t, repo.TransitionTicketStatus(context.Background(), ticket.ID.String(), model.TicketStatusReserved, model.TicketStatusConfirmed, time.Now()))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	admitted := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := repo.CheckInTicket(context.Background(), ticket.ID.String(), uuid.New(), "3", time.Now()); err == nil {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			} else {
+				assert.ErrorIs(t, err, ErrTicketStatusConflict)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, admitted)
+	stored, err := repo.GetTicketByID(context.Background(), ticket.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusUsed, stored.Status)
+	assert.NotNil(t, stored.CheckedInAt)
+	assert.Equal(t, "3", stored.CheckInGate)
+}
+
+func TestMemoryRepository_OutboxLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepository()
+	event := newOpenEvent(t, repo, 3)
+
+	ticket, poison := newReservation(event.ID, time.Now().Add(time.Hour))
+	require.NoError(t, repo.CreateReservation(ctx, ticket, poison))
+	ticket, processed := newReservation(event.ID, time.Now().Add(time.Hour))
+	require.NoError(t, repo.CreateReservation(ctx, ticket, processed))
+
+	attempts, err := repo.RecordOutboxAttempt(ctx, poison.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	attempts, err = repo.RecordOutboxAttempt(ctx, poison.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+
+	// Un mensaje apartado deja de aparecer como pendiente
+	require.NoError(t, repo.ParkOutbox(ctx, poison.ID.String(), "payload inválido"))
+	now := time.Now()
+	require.NoError(t, repo.MarkOutboxProcessed(ctx, processed.ID.String(), now))
+
+	pending, err := repo.GetPendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	parked, err := repo.GetOutboxMessage(ctx, poison.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.OutboxStatusFailed, parked.Status)
+	assert.Equal(t, "payload inválido", parked.LastError)
+
+	done, err := repo.GetOutboxMessage(ctx, processed.ID.String())
+	require.NoError(t, err)
+	require.NotNil(t, done.ExpiresAt)
+	assert.Equal(t, now.Add(model.OutboxRetention), *done.ExpiresAt)
+
+	// Apartar un mensaje que ya no está pendiente no lo cambia
+	require.NoError(t, repo.ParkOutbox(ctx, processed.ID.String(), "tarde"))
+	done, err = repo.GetOutboxMessage(ctx, processed.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.OutboxStatusProcessed, done.Status)
+}
@@ -0,0 +1,391 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+var (
+	ErrOutboxNotFound         = errors.New("outbox message not found")
+	ErrOutboxAlreadyProcessed = errors.New("outbox message already processed")
+)
+
+// CreateReservation descuenta el asiento del evento, guarda el ticket y escribe
+// el mensaje de outbox en una sola transacción: o se aplican los tres o ninguno.
+// El ticket queda marcado con seat_held para devolver el asiento al cancelarlo.
+func (d *DynamoClient) CreateReservation(ctx context.Context, ticket model.Ticket, outbox model.OutboxMessage) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	ticket.SeatHeld = true
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	_, err := d.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{
+				Update: &types.Update{
+					TableName: aws.String(d.Tables.Events),
+					Key: map[string]types.AttributeValue{
+						"id": &types.AttributeValueMemberS{Value: ticket.EventID.String()},
+					},
+					UpdateExpression:    aws.String("SET available_capacity = available_capacity - :one, updated_at = :now"),
+					ConditionExpression: aws.String("attribute_exists(id) AND #status = :open AND available_capacity >= :one"),
+					ExpressionAttributeNames: map[string]string{
+						"#status": "status",
+					},
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":one":  &types.AttributeValueMemberN{Value: "1"},
+						":open": &types.AttributeValueMemberS{Value: model.EventStatusOpen},
+						":now":  &types.AttributeValueMemberS{Value: now},
+					},
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName:           aws.String(d.Tables.Tickets),
+					Item:                marshalTicket(ticket),
+					ConditionExpression: aws.String("attribute_not_exists(id)"),
+				},
+			},
+			{
+				Put: &types.Put{
+					TableName: aws.String(d.Tables.Outbox),
+					Item:      marshalOutbox(outbox),
+				},
+			},
+		},
+	})
+	if err != nil {
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) && len(canceled.CancellationReasons) > 0 &&
+			aws.ToString(canceled.CancellationReasons[0].Code) == "ConditionalCheckFailed" {
+			return ErrEventUnavailable
+		}
+		return fmt.Errorf("error guardando reserva en DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// GetPendingOutbox devuelve hasta limit mensajes de outbox aún no publicados,
+// los más antiguos primero. Consulta el índice disperso de pendientes, así que
+// no lee los mensajes ya publicados o procesados.
+func (d *DynamoClient) GetPendingOutbox(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(d.Tables.Outbox),
+		IndexName:              aws.String(OutboxPendingIndex),
+		KeyConditionExpression: aws.String("pending = :pending"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending": &types.AttributeValueMemberS{Value: model.OutboxStatusPending},
+		},
+		Limit: aws.Int32(int32(limit)),
+	}
+
+	var messages []model.OutboxMessage
+	for {
+		result, err := d.Client.Query(ctx, queryInput)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range result.Items {
+			msg, err := unmarshalOutbox(item)
+			if err != nil {
+				return nil, err
+			}
+			// El índice es eventualmente consistente: puede devolver un mensaje
+			// que ya se publicó hace un instante
+			if msg.Status != model.OutboxStatusPending {
+				continue
+			}
+			messages = append(messages, *msg)
+			if len(messages) >= limit {
+				return messages, nil
+			}
+		}
+
+		if result.LastEvaluatedKey == nil {
+			return messages, nil
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+func (d *DynamoClient) GetOutboxMessage(ctx context.Context, id string) (*model.OutboxMessage, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	result, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.Tables.Outbox),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Item == nil {
+		return nil, ErrOutboxNotFound
+	}
+
+	return unmarshalOutbox(result.Item)
+}
+
+// MarkOutboxSent marca el mensaje como publicado. Si ya no estaba pendiente
+// (otro relay lo publicó o el consumidor ya lo procesó) no hace nada.
+func (d *DynamoClient) MarkOutboxSent(ctx context.Context, id string, now time.Time) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	_, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.Tables.Outbox),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		UpdateExpression:    aws.String("SET #status = :sent, sent_at = :now ADD attempts :one REMOVE pending"),
+		ConditionExpression: aws.String("#status = :pending"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":sent":    &types.AttributeValueMemberS{Value: model.OutboxStatusSent},
+			":pending": &types.AttributeValueMemberS{Value: model.OutboxStatusPending},
+			":now":     &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+			":one":     &types.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil
+		}
+		return fmt.Errorf("error marcando mensaje de outbox como enviado: %w", err)
+	}
+	return nil
+}
+
+// RecordOutboxAttempt suma un intento fallido de publicación y devuelve cuántos
+// lleva el mensaje
+func (d *DynamoClient) RecordOutboxAttempt(ctx context.Context, id string) (int, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	result, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.Tables.Outbox),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		UpdateExpression:    aws.String("ADD attempts :one"),
+		ConditionExpression: aws.String("attribute_exists(id)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one": &types.AttributeValueMemberN{Value: "1"},
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return 0, ErrOutboxNotFound
+		}
+		return 0, fmt.Errorf("error registrando intento del mensaje de outbox: %w", err)
+	}
+
+	attemptsVal, ok := result.Attributes["attempts"].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("respuesta sin attempts para el mensaje de outbox %s", id)
+	}
+	return strconv.Atoi(attemptsVal.Value)
+}
+
+// ParkOutbox aparta un mensaje pendiente que no se puede publicar: pasa a failed
+// con el motivo y sale del índice de pendientes, de modo que el relay deja de
+// reintentarlo. Si ya no estaba pendiente no hace nada.
+func (d *DynamoClient) ParkOutbox(ctx context.Context, id string, reason string) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	_, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.Tables.Outbox),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		UpdateExpression:    aws.String("SET #status = :failed, last_error = :reason REMOVE pending"),
+		ConditionExpression: aws.String("#status = :pending"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":failed":  &types.AttributeValueMemberS{Value: model.OutboxStatusFailed},
+			":pending": &types.AttributeValueMemberS{Value: model.OutboxStatusPending},
+			":reason":  &types.AttributeValueMemberS{Value: reason},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil
+		}
+		return fmt.Errorf("error apartando mensaje de outbox: %w", err)
+	}
+	return nil
+}
+
+// MarkOutboxProcessed registra que el consumidor procesó el mensaje. Devuelve
+// ErrOutboxAlreadyProcessed si ya lo estaba, lo que permite descartar entregas duplicadas.
+func (d *DynamoClient) MarkOutboxProcessed(ctx context.Context, id string, now time.Time) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	_, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.Tables.Outbox),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id},
+		},
+		// Procesado ya no hace falta: el TTL lo borra pasado OutboxRetention
+		UpdateExpression:    aws.String("SET #status = :processed, processed_at = :now, expires_at = :ttl REMOVE pending"),
+		ConditionExpression: aws.String("attribute_exists(id) AND #status <> :processed"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":processed": &types.AttributeValueMemberS{Value: model.OutboxStatusProcessed},
+			":now":       &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+			":ttl":       &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(model.OutboxRetention).Unix(), 10)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrOutboxAlreadyProcessed
+		}
+		return fmt.Errorf("error marcando mensaje de outbox como procesado: %w", err)
+	}
+	return nil
+}
+
+func marshalOutbox(msg model.OutboxMessage) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"id":           &types.AttributeValueMemberS{Value: msg.ID.String()},
+		"aggregate_id": &types.AttributeValueMemberS{Value: msg.AggregateID},
+		"payload":      &types.AttributeValueMemberS{Value: msg.Payload},
+		"status":       &types.AttributeValueMemberS{Value: msg.Status},
+		"attempts":     &types.AttributeValueMemberN{Value: strconv.Itoa(msg.Attempts)},
+		"created_at":   &types.AttributeValueMemberS{Value: msg.CreatedAt.UTC().Format(time.RFC3339)},
+	}
+
+	if msg.Status == model.OutboxStatusPending {
+		item["pending"] = &types.AttributeValueMemberS{Value: model.OutboxStatusPending}
+	}
+
+	if msg.LastError != "" {
+		item["last_error"] = &types.AttributeValueMemberS{Value: msg.LastError}
+	}
+
+	if msg.ExpiresAt != nil {
+		item["expires_at"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(msg.ExpiresAt.Unix(), 10)}
+	}
+
+	if msg.SentAt != nil {
+		item["sent_at"] = &types.AttributeValueMemberS{Value: msg.SentAt.UTC().Format(time.RFC3339)}
+	}
+
+	if msg.ProcessedAt != nil {
+		item["processed_at"] = &types.AttributeValueMemberS{Value: msg.ProcessedAt.UTC().Format(time.RFC3339)}
+	}
+
+	if len(msg.TraceContext) > 0 {
+		traceContext := make(map[string]types.AttributeValue, len(msg.TraceContext))
+		for key, value := range msg.TraceContext {
+			traceContext[key] = &types.AttributeValueMemberS{Value: value}
+		}
+		item["trace_context"] = &types.AttributeValueMemberM{Value: traceContext}
+	}
+
+	return item
+}
+
+func unmarshalOutbox(item map[string]types.AttributeValue) (*model.OutboxMessage, error) {
+	msg := &model.OutboxMessage{}
+
+	if idVal, ok := item["id"].(*types.AttributeValueMemberS); ok {
+		id, err := uuid.Parse(idVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid outbox ID: %v", err)
+		}
+		msg.ID = id
+	}
+
+	if aggregateVal, ok := item["aggregate_id"].(*types.AttributeValueMemberS); ok {
+		msg.AggregateID = aggregateVal.Value
+	}
+
+	if payloadVal, ok := item["payload"].(*types.AttributeValueMemberS); ok {
+		msg.Payload = payloadVal.Value
+	}
+
+	if statusVal, ok := item["status"].(*types.AttributeValueMemberS); ok {
+		msg.Status = statusVal.Value
+	}
+
+	if attemptsVal, ok := item["attempts"].(*types.AttributeValueMemberN); ok {
+		attempts, err := strconv.Atoi(attemptsVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attempts: %v", err)
+		}
+		msg.Attempts = attempts
+	}
+
+	if createdAtVal, ok := item["created_at"].(*types.AttributeValueMemberS); ok {
+		createdAt, err := time.Parse(time.RFC3339, createdAtVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_at time: %v", err)
+		}
+		msg.CreatedAt = createdAt
+	}
+
+	if sentAtVal, ok := item["sent_at"].(*types.AttributeValueMemberS); ok {
+		sentAt, err := time.Parse(time.RFC3339, sentAtVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sent_at time: %v", err)
+		}
+		msg.SentAt = &sentAt
+	}
+
+	if processedAtVal, ok := item["processed_at"].(*types.AttributeValueMemberS); ok {
+		processedAt, err := time.Parse(time.RFC3339, processedAtVal.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid processed_at time: %v", err)
+		}
+		msg.ProcessedAt = &processedAt
+	}
+
+	if lastErrorVal, ok := item["last_error"].(*types.AttributeValueMemberS); ok {
+		msg.LastError = lastErrorVal.Value
+	}
+
+	if expiresAtVal, ok := item["expires_at"].(*types.AttributeValueMemberN); ok {
+		seconds, err := strconv.ParseInt(expiresAtVal.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_at: %v", err)
+		}
+		expiresAt := time.Unix(seconds, 0)
+		msg.ExpiresAt = &expiresAt
+	}
+
+	if traceVal, ok := item["trace_context"].(*types.AttributeValueMemberM); ok {
+		msg.TraceContext = make(map[string]string, len(traceVal.Value))
+		for key, value := range traceVal.Value {
+			if s, ok := value.(*types.AttributeValueMemberS); ok {
+				msg.TraceContext[key] = s.Value
+			}
+		}
+	}
+
+	return msg, nil
+}
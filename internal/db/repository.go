@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+var ErrTicketNotFound = errors.New("ticket not found")
+
+// TicketRepository abstrae el almacenamiento de tickets. DynamoClient y
+// MemoryRepository la implementan con la misma semántica de escrituras condicionales.
+type TicketRepository interface {
+	SaveTicket(ctx context.Context, ticket model.Ticket) error
+	GetTicketByID(ctx context.Context, ticketID string) (*model.Ticket, error)
+	GetTickets(ctx context.Context, userEmail, eventID string, limit int, cursor string) ([]model.Ticket, string, error)
+	DeleteTicket(ctx context.Context, ticket model.Ticket) error
+	UpdateTicket(ctx context.Context, ticket model.Ticket, expectedStatus string) error
+	TransitionTicketStatus(ctx context.Context, ticketID, from, to string, now time.Time) error
+	CancelTicket(ctx context.Context, ticket model.Ticket, now time.Time) error
+	CheckInTicket(ctx context.Context, ticketID string, scannerID uuid.UUID, gate string, now time.Time) error
+	RecordOfflineCheckIn(ctx context.Context, ticketID string, scannerID uuid.UUID, gate string, scannedAt time.Time) error
+	ExpireReservation(ctx context.Context, ticket model.Ticket, now time.Time) error
+	GetExpiredReservations(ctx context.Context, now time.Time, limit int) ([]model.Ticket, error)
+}
+
+// EventRepository abstrae el almacenamiento de eventos y su inventario de asientos
+type EventRepository interface {
+	SaveEvent(ctx context.Context, event model.Event) error
+	UpdateEvent(ctx context.Context, event model.Event, capacityDelta int) error
+	GetEventByID(ctx context.Context, eventID string) (*model.Event, error)
+	GetEvents(ctx context.Context, status string, limit int, cursor string) ([]model.Event, string, error)
+	DeleteEvent(ctx context.Context, eventID string) error
+}
+
+// OutboxRepository abstrae la creación transaccional de reservas y el outbox de mensajes
+type OutboxRepository interface {
+	CreateReservation(ctx context.Context, ticket model.Ticket, outbox model.OutboxMessage) error
+	GetPendingOutbox(ctx context.Context, limit int) ([]model.OutboxMessage, error)
+	GetOutboxMessage(ctx context.Context, id string) (*model.OutboxMessage, error)
+	MarkOutboxSent(ctx context.Context, id string, now time.Time) error
+	RecordOutboxAttempt(ctx context.Context, id string) (int, error)
+	ParkOutbox(ctx context.Context, id string, reason string) error
+	MarkOutboxProcessed(ctx context.Context, id string, now time.Time) error
+}
+
+// IdempotencyRepository guarda las respuestas de peticiones con Idempotency-Key
+type IdempotencyRepository interface {
+	AcquireIdempotencyKey(ctx context.Context, record model.IdempotencyRecord) error
+	GetIdempotencyRecord(ctx context.Context, key string, now time.Time) (*model.IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, record model.IdempotencyRecord, statusCode int, contentType, body string) error
+	ReleaseIdempotencyKey(ctx context.Context, record model.IdempotencyRecord) error
+}
+
+// Repository agrupa todos los repositorios que usa la aplicación
+type Repository interface {
+	TicketRepository
+	EventRepository
+	OutboxRepository
+	IdempotencyRepository
+
+	// Ping comprueba que el almacenamiento está disponible, para /readyz
+	Ping(ctx context.Context) error
+}
+
+var (
+	_ Repository = (*DynamoClient)(nil)
+	_ Repository = (*MemoryRepository)(nil)
+)
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+var ErrReservationNotPending = errors.New("reservation is not pending or its hold has expired")
+
+// ExpireReservation cancela una reserva vencida y, en la misma transacción,
+// devuelve su asiento al evento. Si mientras tanto fue confirmada o cancelada
+// devuelve ErrReservationNotPending y el evento no se toca; si la transacción
+// falla por otro motivo la reserva sigue vencida y el siguiente barrido la reintenta.
+func (d *DynamoClient) ExpireReservation(ctx context.Context, ticket model.Ticket, now time.Time) error {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+
+	condition := "#status = :reserved AND expires_at <= :now AND (attribute_not_exists(seat_held) OR seat_held <> :held)"
+	if ticket.SeatHeld {
+		condition = "#status = :reserved AND expires_at <= :now AND seat_held = :held"
+	}
+	items := []types.TransactWriteItem{
+		{
+			Update: &types.Update{
+				TableName: aws.String(d.Tables.Tickets),
+				Key: map[string]types.AttributeValue{
+					"id": &types.AttributeValueMemberS{Value: ticket.ID.String()},
+				},
+				UpdateExpression:    aws.String("SET #status = :cancelled, seat_held = :released, updated_at = :now"),
+				ConditionExpression: aws.String(condition),
+				ExpressionAttributeNames: map[string]string{
+					"#status": "status",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":cancelled": &types.AttributeValueMemberS{Value: model.TicketStatusCancelled},
+					":reserved":  &types.AttributeValueMemberS{Value: model.TicketStatusReserved},
+					":held":      &types.AttributeValueMemberBOOL{Value: true},
+					":released":  &types.AttributeValueMemberBOOL{Value: false},
+					":now":       &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+				},
+			},
+		},
+	}
+	if ticket.SeatHeld {
+		items = append(items, d.releaseSeatItem(ticket.EventID.String(), now))
+	}
+
+	err := d.writeTicketTransaction(ctx, items, "error expirando reserva")
+	if errors.Is(err, ErrTicketStatusConflict) {
+		return ErrReservationNotPending
+	}
+	return err
+}
+
+// GetExpiredReservations devuelve hasta limit reservas cuyo plazo venció antes
+// de now. Consulta el índice status-expires_at-index, que solo contiene los
+// tickets con expires_at, en lugar de recorrer la tabla.
+func (d *DynamoClient) GetExpiredReservations(ctx context.Context, now time.Time, limit int) ([]model.Ticket, error) {
+	ctx, cancel := d.callContext(ctx)
+	defer cancel()
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(d.Tables.Tickets),
+		IndexName:              aws.String(TicketsStatusExpiryIndex),
+		KeyConditionExpression: aws.String("#status = :reserved AND expires_at <= :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":reserved": &types.AttributeValueMemberS{Value: model.TicketStatusReserved},
+			":now":      &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+		},
+		Limit: aws.Int32(int32(limit)),
+	}
+
+	var tickets []model.Ticket
+	for {
+		result, err := d.Client.Query(ctx, queryInput)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range result.Items {
+			ticket, err := d.unmarshalTicket(item)
+			if err != nil {
+				return nil, err
+			}
+			tickets = append(tickets, *ticket)
+			if len(tickets) >= limit {
+				return tickets, nil
+			}
+		}
+
+		if result.LastEvaluatedKey == nil {
+			return tickets, nil
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhonathanssegura/ticket-reservation/internal/queue"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+)
+
+// defaultRedriveLimit y maxRedriveLimit acotan cuántos mensajes reenvía
+// RedriveAllDeadLetters en una petición
+const (
+	defaultRedriveLimit = 100
+	maxRedriveLimit     = 1000
+)
+
+// AdminAuth exige el token de administración en la cabecera
+// "Authorization: Bearer <token>". Sin token configurado rechaza todas las
+// peticiones, así los endpoints de administración no quedan abiertos por omisión.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Endpoints de administración deshabilitados", "details": "Defina ADMIN_TOKEN"})
+			return
+		}
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token de administración inválido"})
+			return
+		}
+		c.Next()
+	}
+}
+
+type AdminHandler struct {
+	SQS        queue.DeadLetterQueue
+	Reconciler *service.ArtifactReconciler
+}
+
+func NewAdminHandler(sqs queue.DeadLetterQueue, reconciler *service.ArtifactReconciler) *AdminHandler {
+	return &AdminHandler{SQS: sqs, Reconciler: reconciler}
+}
+
+func (h *AdminHandler) ListDeadLetters(c *gin.Context) {
+	limitStr := c.Query("limit")
+	limit := 10 // default limit
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	messages, err := h.SQS.PeekDeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo mensajes fallidos", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messages,
+		"count":    len(messages),
+		"limit":    limit,
+	})
+}
+
+func (h *AdminHandler) GetDeadLetter(c *gin.Context) {
+	messageID := c.Param("id")
+	if messageID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de mensaje requerido"})
+		return
+	}
+
+	message, err := h.SQS.GetDeadLetter(c.Request.Context(), messageID)
+	if err != nil {
+		if errors.Is(err, queue.ErrDeadLetterNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Mensaje no encontrado en la DLQ"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo mensaje fallido", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+func (h *AdminHandler) RedriveDeadLetter(c *gin.Context) {
+	messageID := c.Param("id")
+	if messageID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de mensaje requerido"})
+		return
+	}
+
+	if err := h.SQS.RedriveDeadLetter(c.Request.Context(), messageID); err != nil {
+		if errors.Is(err, queue.ErrDeadLetterNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Mensaje no encontrado en la DLQ"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reenviando mensaje", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mensaje reenviado a la cola principal", "message_id": messageID})
+}
+
+// RedriveAllDeadLetters reenvía hasta ?max= mensajes de la DLQ (100 por
+// defecto, 1000 como máximo); para vaciar una DLQ grande se repite la petición
+func (h *AdminHandler) RedriveAllDeadLetters(c *gin.Context) {
+	limit := defaultRedriveLimit
+	if maxStr := c.Query("max"); maxStr != "" {
+		l, err := strconv.Atoi(maxStr)
+		if err != nil || l <= 0 || l > maxRedriveLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parámetro max inválido", "details": "Debe estar entre 1 y " + strconv.Itoa(maxRedriveLimit)})
+			return
+		}
+		limit = l
+	}
+
+	redriven, err := h.SQS.RedriveAllDeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":    "Error reenviando mensajes",
+			"details":  err.Error(),
+			"redriven": redriven,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mensajes reenviados a la cola principal", "redriven": redriven, "limit": limit})
+}
+
+func (h *AdminHandler) PurgeDeadLetters(c *gin.Context) {
+	if err := h.SQS.PurgeDeadLetters(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error purgando la DLQ", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "DLQ purgada con éxito"})
+}
+
+// ReconcileArtifacts compara los archivos guardados con los tickets. Por defecto
+// solo informa; con repair=true borra los huérfanos y regenera los faltantes.
+func (h *AdminHandler) ReconcileArtifacts(c *gin.Context) {
+	repair := c.Query("repair") == "true"
+
+	report, err := h.Reconciler.Reconcile(c.Request.Context(), repair)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reconciliando archivos", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/jhonathanssegura/ticket-reservation/internal/queue"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDeadLetterQueue guarda la DLQ y la cola principal en memoria
+type fakeDeadLetterQueue struct {
+	deadLetters []queue.DeadLetterMessage
+	redriven    []queue.DeadLetterMessage
+}
+
+func (f *fakeDeadLetterQueue) PeekDeadLetters(ctx context.Context, max int) ([]queue.DeadLetterMessage, error) {
+	if max > len(f.deadLetters) {
+		max = len(f.deadLetters)
+	}
+	return f.deadLetters[:max], nil
+}
+
+func (f *fakeDeadLetterQueue) GetDeadLetter(ctx context.Context, messageID string) (*queue.DeadLetterMessage, error) {
+	for i := range f.deadLetters {
+		if f.deadLetters[i].MessageID == messageID {
+			return &f.deadLetters[i], nil
+		}
+	}
+	return nil, queue.ErrDeadLetterNotFound
+}
+
+func (f *fakeDeadLetterQueue) RedriveDeadLetter(ctx context.Context, messageID string) error {
+	for i, m := range f.deadLetters {
+		if m.MessageID == messageID {
+			f.redriven = append(f.redriven, m)
+			f.deadLetters = append(f.deadLetters[:i], f.deadLetters[i+1:]...)
+			return nil
+		}
+	}
+	return queue.ErrDeadLetterNotFound
+}
+
+func (f *fakeDeadLetterQueue) RedriveAllDeadLetters(ctx context.Context, max int) (int, error) {
+	count := min(max, len(f.deadLetters))
+	f.redriven = append(f.redriven, f.deadLetters[:count]...)
+	f.deadLetters = f.deadLetters[count:]
+	return count, nil
+}
+
+func (f *fakeDeadLetterQueue) PurgeDeadLetters(ctx context.Context) error {
+	f.deadLetters = nil
+	return nil
+}
+
+func newFakeDeadLetterQueue() *fakeDeadLetterQueue {
+	sentAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	return &fakeDeadLetterQueue{deadLetters: []queue.DeadLetterMessage{
+		{MessageID: "dlq-1", OriginalMessageID: "msg-1", Body: `{"reservation_id":"r1"}`, FailureReason: "evento no encontrado", ReceiveCount: 1, SentAt: sentAt},
+		{MessageID: "dlq-2", OriginalMessageID: "msg-2", Body: `{"reservation_id":"r2"}`, FailureReason: "payload inválido", ReceiveCount: 3, SentAt: sentAt},
+		{MessageID: "dlq-3", OriginalMessageID: "msg-3", Body: `{"reservation_id":"r3"}`, ReceiveCount: 1, SentAt: sentAt},
+	}}
+}
+
+func TestListDeadLetters_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	dlq := newFakeDeadLetterQueue()
+	handler := NewAdminHandler(dlq, nil)
+	r.GET("/admin/dlq/messages", handler.ListDeadLetters)
+
+	w := doJSON(r, http.MethodGet, "/admin/dlq/messages?limit=2", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Messages []queue.DeadLetterMessage `json:"messages"`
+		Count    int                       `json:"count"`
+		Limit    int                       `json:"limit"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Count)
+	assert.Equal(t, 2, resp.Limit)
+	assert.Equal(t, dlq.deadLetters[:2], resp.Messages)
+
+	// Un límite inválido usa el valor por defecto
+	w = doJSON(r, http.MethodGet, "/admin/dlq/messages?limit=abc", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 10, resp.Limit)
+	assert.Equal(t, 3, resp.Count)
+}
+
+func TestRedriveDeadLetter_ValidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	dlq := newFakeDeadLetterQueue()
+	handler := NewAdminHandler(dlq, nil)
+	r.POST("/admin/dlq/messages/:id/redrive", handler.RedriveDeadLetter)
+	r.POST("/admin/dlq/redrive", handler.RedriveAllDeadLetters)
+
+	w := doJSON(r, http.MethodPost, "/admin/dlq/messages/dlq-2/redrive", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		MessageID string `json:"message_id"`
+		Redriven  int    `json:"redriven"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "dlq-2", resp.MessageID)
+	require.Len(t, dlq.redriven, 1)
+	assert.Equal(t, "msg-2", dlq.redriven[0].OriginalMessageID)
+	assert.Len(t, dlq.deadLetters, 2)
+
+	// Ya no está en la DLQ
+	w = doJSON(r, http.MethodPost, "/admin/dlq/messages/dlq-2/redrive", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// El reenvío masivo se limita a max mensajes por petición
+	w = doJSON(r, http.MethodPost, "/admin/dlq/redrive?max=1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Redriven)
+	assert.Len(t, dlq.deadLetters, 1)
+
+	w = doJSON(r, http.MethodPost, "/admin/dlq/redrive?max=5000", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(r, http.MethodPost, "/admin/dlq/redrive", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Redriven)
+	assert.Len(t, dlq.redriven, 3)
+	assert.Empty(t, dlq.deadLetters)
+}
+
+func TestAdminAuth_RequiresToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const token = "token-de-administracion"
+	newRouter := func(token string) *gin.Engine {
+		r := gin.Default()
+		admin := r.Group("/admin", AdminAuth(token))
+		admin.DELETE("/dlq/messages", NewAdminHandler(newFakeDeadLetterQueue(), nil).PurgeDeadLetters)
+		return r
+	}
+	purge := func(r *gin.Engine, authorization string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/admin/dlq/messages", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	r := newRouter(token)
+	assert.Equal(t, http.StatusUnauthorized, purge(r, ""))
+	assert.Equal(t, http.StatusUnauthorized, purge(r, "Bearer otro-token"))
+	assert.Equal(t, http.StatusUnauthorized, purge(r, token))
+	assert.Equal(t, http.StatusOK, purge(r, "Bearer "+token))
+
+	// Sin token configurado los endpoints quedan cerrados
+	assert.Equal(t, http.StatusForbidden, purge(newRouter(""), "Bearer "))
+}
+
+func TestReconcileArtifacts_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost:8080/api/blobs", bytes.Repeat([]byte("s"), 32))
+	require.NoError(t, err)
+	artifacts := service.NewTicketArtifactService(store, testQRService())
+
+	reconciler := service.NewArtifactReconciler(repo, artifacts)
+	reconciler.GracePeriod = 0
+	handler := NewAdminHandler(nil, reconciler)
+	r.POST("/admin/artifacts/reconcile", handler.ReconcileArtifacts)
+
+	now := time.Now().Add(-time.Minute)
+	ticket := model.Ticket{ID: uuid.New(), EventID: uuid.New(), TicketCode: "TKT-admin", Status: model.TicketStatusConfirmed, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.SaveTicket(ctx, ticket))
+	orphanKey := "tickets/" + uuid.New().String() + ".pdf"
+	require.NoError(t, store.Put(ctx, orphanKey, strings.NewReader("pdf"), "application/pdf"))
+
+	reconcile := func(query string) service.ArtifactReport {
+		w := doJSON(r, http.MethodPost, "/admin/artifacts/reconcile"+query, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Report service.ArtifactReport `json:"report"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Report
+	}
+
+	report := reconcile("")
+	assert.False(t, report.Repair)
+	assert.Equal(t, []string{orphanKey}, report.Orphans)
+	assert.Len(t, report.Missing, 2)
+	assert.Empty(t, report.Deleted)
+
+	report = reconcile("?repair=true")
+	assert.Equal(t, []string{orphanKey}, report.Deleted)
+	assert.Equal(t, []string{ticket.ID.String()}, report.Regenerated)
+	assert.Empty(t, report.Errors)
+
+	_, err = store.Head(ctx, orphanKey)
+	assert.ErrorIs(t, err, storage.ErrBlobNotFound)
+	_, err = store.Head(ctx, service.TicketFileKey(ticket))
+	assert.NoError(t, err)
+}
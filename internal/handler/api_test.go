@@ -0,0 +1,992 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// End-to-end tests against the in-memory repository
+func newMemoryAPI() (*gin.Engine, *db.MemoryRepository) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	repo := db.NewMemoryRepository()
+
+	handlerTicket := NewTicketHandler(repo, nil)
+	handlerEvent := NewEventHandler(repo)
+	handlerQR := NewQRHandler(repo, nil, testQRService())
+	handlerCheckin := NewCheckinHandler(repo, testQRService(), testBundleSigner())
+
+	r.GET("/events", handlerEvent.ListEvents)
+	r.GET("/events/:id", handlerEvent.GetEvent)
+	r.POST("/events", handlerEvent.CreateEvent)
+	r.PUT("/events/:id", handlerEvent.UpdateEvent)
+	r.DELETE("/events/:id", handlerEvent.DeleteEvent)
+	r.GET("/tickets", handlerTicket.ListTickets)
+	r.GET("/tickets/:id", handlerTicket.GetTicket)
+	r.POST("/tickets", Idempotency(repo, time.Hour), handlerTicket.CreateTicket)
+	r.PUT("/tickets/:id", handlerTicket.UpdateTicket)
+	r.DELETE("/tickets/:id", handlerTicket.DeleteTicket)
+	r.POST("/tickets/:id/confirm", handlerTicket.ConfirmTicket)
+	r.POST("/tickets/:id/cancel", handlerTicket.CancelTicket)
+	r.GET("/tickets/:id/qr", handlerQR.GetTicketQR)
+	r.POST("/qr/validate", handlerQR.ValidateQR)
+	r.POST("/qr/decode", handlerQR.DecodeQR)
+	r.POST("/checkins", handlerCheckin.CheckIn)
+	r.POST("/checkins/sync", handlerCheckin.SyncCheckIns)
+	r.GET("/events/:id/checkin-bundle", handlerCheckin.ExportBundle)
+
+	return r, repo
+}
+
+func testQRService() *service.QRService {
+	signer, err := service.NewQRSigner("test", map[string][]byte{"test": bytes.Repeat([]byte("k"), 32)})
+	if err != nil {
+		panic(err)
+	}
+	return service.NewQRService(signer)
+}
+
+func testBundleSigner() *service.BundleSigner {
+	signer, err := service.NewBundleSigner("test", bytes.Repeat([]byte("b"), 32))
+	if err != nil {
+		panic(err)
+	}
+	return signer
+}
+
+func doJSON(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func createTestEvent(t *testing.T, r *gin.Engine, capacity int, rotatingQR bool) model.Event {
+	t.Helper()
+	start := time.Now().UTC().Add(2 * time.Hour).Truncate(time.Second)
+	w := doJSON(r, http.MethodPost, "/events", fmt.Sprintf(`{
+		"name": "Concierto",
+		"venue": "Estadio",
+		"start_time": %q,
+		"end_time": %q,
+		"total_capacity": %d,
+		"rotating_qr": %t
+	}`, start.Format(time.RFC3339), start.Add(3*time.Hour).Format(time.RFC3339), capacity, rotatingQR))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Event model.Event `json:"event"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	return created.Event
+}
+
+func createTestTicket(t *testing.T, r *gin.Engine) model.Ticket {
+	t.Helper()
+	event := createTestEvent(t, r, 10, false)
+	w := doJSON(r, http.MethodPost, "/tickets", `{"email":"test@example.com","event_id":"`+event.ID.String()+`"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var resp struct {
+		Ticket model.Ticket `json:"ticket"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Ticket
+}
+
+func TestMemoryAPI_TicketLifecycle(t *testing.T) {
+	r, _ := newMemoryAPI()
+	ticket := createTestTicket(t, r)
+
+	w := doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String(), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "test@example.com")
+
+	w = doJSON(r, http.MethodPut, "/tickets/"+ticket.ID.String(), `{"email":"updated@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, http.MethodGet, "/tickets?user_email=updated@example.com", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":1`)
+
+	w = doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/confirm", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Solo el check-in marca el ticket como usado, registrando hora y lector
+	scannerID := uuid.New()
+	w = doJSON(r, http.MethodPost, "/checkins", fmt.Sprintf(`{"qr_content":%q,"scanner_id":%q}`,
+		testQRService().GenerateQRContent(ticket), scannerID))
+	require.Equal(t, http.StatusOK, w.Code)
+	var checkIn struct {
+		Ticket model.Ticket `json:"ticket"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &checkIn))
+	assert.Equal(t, model.TicketStatusUsed, checkIn.Ticket.Status)
+	require.NotNil(t, checkIn.Ticket.CheckedInAt)
+	require.NotNil(t, checkIn.Ticket.CheckedInBy)
+	assert.Equal(t, scannerID, *checkIn.Ticket.CheckedInBy)
+
+	// A used ticket cannot be resurrected
+	w = doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/cancel", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Transición de estado no permitida")
+
+	w = doJSON(r, http.MethodPut, "/tickets/"+ticket.ID.String(), `{"email":"again@example.com"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doJSON(r, http.MethodDelete, "/tickets/"+ticket.ID.String(), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMemoryAPI_EventCapacityUpdate(t *testing.T) {
+	r, _ := newMemoryAPI()
+
+	w := doJSON(r, http.MethodPost, "/events", `{
+		"name": "Concierto",
+		"venue": "Estadio",
+		"start_time": "2026-12-01T20:00:00Z",
+		"end_time": "2026-12-01T23:00:00Z",
+		"total_capacity": 10
+	}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Event model.Event `json:"event"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, 10, created.Event.AvailableCapacity)
+
+	w = doJSON(r, http.MethodPut, "/events/"+created.Event.ID.String(), `{"total_capacity": 4}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var updated struct {
+		Event model.Event `json:"event"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(t, 4, updated.Event.TotalCapacity)
+	assert.Equal(t, 4, updated.Event.AvailableCapacity)
+}
+
+func TestMemoryAPI_EventTicketTemplate(t *testing.T) {
+	r, _ := newMemoryAPI()
+
+	w := doJSON(r, http.MethodPost, "/events", `{
+		"name": "Concierto",
+		"venue": "Estadio",
+		"start_time": "2026-12-01T20:00:00Z",
+		"end_time": "2026-12-01T23:00:00Z",
+		"total_capacity": 10,
+		"ticket_template": {"layout": "compact"}
+	}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Event model.Event `json:"event"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	require.NotNil(t, created.Event.TicketTemplate)
+	assert.Equal(t, model.TicketLayoutCompact, created.Event.TicketTemplate.Layout)
+	assert.Equal(t, model.PaperSizeA4, created.Event.TicketTemplate.PaperSize)
+
+	w = doJSON(r, http.MethodPut, "/events/"+created.Event.ID.String(), `{"ticket_template": {"accent_color": "rojo"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMemoryAPI_GetTicketQR(t *testing.T) {
+	r, _ := newMemoryAPI()
+	ticket := createTestTicket(t, r)
+
+	w := doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/qr", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+
+	w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/qr?format=svg&size=512&level=H", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+
+	w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/qr?format=pdf", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+
+	w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/qr?size=100000", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The test ticket's event has no logo
+	w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/qr?logo=true", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMemoryAPI_CreateTicketIdempotencyKey(t *testing.T) {
+	r, _ := newMemoryAPI()
+	event := createTestEvent(t, r, 10, false)
+	body := `{"email":"test@example.com","event_id":"` + event.ID.String() + `"}`
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/tickets", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("retry-1", body)
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	// A retry replays the stored response instead of creating a second ticket
+	second := post("retry-1", body)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayHeader))
+
+	w := doJSON(r, http.MethodGet, "/tickets?user_email=test@example.com", "")
+	assert.Contains(t, w.Body.String(), `"count":1`)
+
+	w = post("retry-1", `{"email":"other@example.com","event_id":"`+event.ID.String()+`"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = post("retry-2", body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotEqual(t, first.Body.String(), w.Body.String())
+}
+
+func TestMemoryAPI_ValidateSignedQR(t *testing.T) {
+	r, _ := newMemoryAPI()
+	ticket := createConfirmedTicketForEvent(t, r, false)
+
+	content := testQRService().GenerateQRContent(ticket)
+	assert.NotContains(t, content, ticket.Email)
+
+	w := doJSON(r, http.MethodPost, "/qr/validate", `{"qr_content":"`+content+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"valid":true`)
+
+	// Changing a single character of the signature is rejected before the DB lookup
+	i := len(content) - 5
+	replacement := "A"
+	if content[i] == 'A' {
+		replacement = "B"
+	}
+	tampered := content[:i] + replacement + content[i+1:]
+	w = doJSON(r, http.MethodPost, "/qr/validate", `{"qr_content":"`+tampered+`"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Legacy plain-text payloads can be forged by anyone who knows a ticket ID
+	w = doJSON(r, http.MethodPost, "/qr/validate", `{"qr_content":"TICKET:`+ticket.ID.String()+`|EMAIL:test@example.com|CODE:`+ticket.TicketCode+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMemoryAPI_ValidateQRRejectsTicketStatus(t *testing.T) {
+	r, _ := newMemoryAPI()
+	validate := func(ticket model.Ticket) *httptest.ResponseRecorder {
+		return doJSON(r, http.MethodPost, "/qr/validate", `{"qr_content":"`+testQRService().GenerateQRContent(ticket)+`"}`)
+	}
+
+	reserved := createTestTicket(t, r)
+	w := validate(reserved)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"reason":"not_confirmed"`)
+
+	used := createConfirmedTicketForEvent(t, r, false)
+	require.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/checkins", `{"qr_content":"`+testQRService().GenerateQRContent(used)+`","scanner_id":"550e8400-e29b-41d4-a716-446655440010"}`).Code)
+	w = validate(used)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"reason":"used"`)
+
+	cancelled := createConfirmedTicketForEvent(t, r, false)
+	require.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/tickets/"+cancelled.ID.String()+"/cancel", "").Code)
+	w = validate(cancelled)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"reason":"cancelled"`)
+}
+
+func TestMemoryAPI_CheckInOnlyOnce(t *testing.T) {
+	r, _ := newMemoryAPI()
+	ticket := createTestTicket(t, r)
+	content := testQRService().GenerateQRContent(ticket)
+	body := `{"qr_content":"` + content + `","scanner_id":"550e8400-e29b-41d4-a716-446655440010","gate":"3"}`
+
+	// Reserved tickets are not admitted until they are confirmed
+	w := doJSON(r, http.MethodPost, "/checkins", body)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"reserved"`)
+
+	w = doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/confirm", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, http.MethodPost, "/checkins", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"used"`)
+
+	w = doJSON(r, http.MethodPost, "/checkins", body)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Ticket ya utilizado")
+	assert.Contains(t, w.Body.String(), "por la puerta 3")
+
+	w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String(), "")
+	assert.Contains(t, w.Body.String(), `"checked_in_by":"550e8400-e29b-41d4-a716-446655440010"`)
+}
+
+func TestMemoryAPI_BusinessMetrics(t *testing.T) {
+	r, _ := newMemoryAPI()
+	counters := []prometheus.Collector{
+		metrics.ReservationsCreated,
+		metrics.CheckIns.WithLabelValues(metrics.CheckInOnline, scanAccepted),
+		metrics.CheckIns.WithLabelValues(metrics.CheckInOnline, scanDuplicate),
+		metrics.CheckIns.WithLabelValues(metrics.CheckInOnline, scanRejected),
+		metrics.QRValidationFailures.WithLabelValues(metrics.QRFailureBadSignature),
+	}
+	// Los contadores son globales, así que se comparan diferencias
+	deltas := func(before []float64) []float64 {
+		values := make([]float64, len(counters))
+		for i, c := range counters {
+			values[i] = testutil.ToFloat64(c)
+			if before != nil {
+				values[i] -= before[i]
+			}
+		}
+		return values
+	}
+	before := deltas(nil)
+
+	ticket := createConfirmedTicketForEvent(t, r, false)
+	content := testQRService().GenerateQRContent(ticket)
+	body := `{"qr_content":"` + content + `","scanner_id":"550e8400-e29b-41d4-a716-446655440010"}`
+	require.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/checkins", body).Code)
+	require.Equal(t, http.StatusConflict, doJSON(r, http.MethodPost, "/checkins", body).Code)
+
+	forger, err := service.NewQRSigner("test", map[string][]byte{"test": bytes.Repeat([]byte("x"), 32)})
+	require.NoError(t, err)
+	forged := service.NewQRService(forger).GenerateQRContent(ticket)
+	w := doJSON(r, http.MethodPost, "/checkins", `{"qr_content":"`+forged+`","scanner_id":"550e8400-e29b-41d4-a716-446655440010"}`)
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	assert.Equal(t, []float64{1, 1, 1, 1, 1}, deltas(before))
+}
+
+func createConfirmedTicketForEvent(t *testing.T, r *gin.Engine, rotatingQR bool) model.Ticket {
+	t.Helper()
+	event := createTestEvent(t, r, 10, rotatingQR)
+
+	w := doJSON(r, http.MethodPost, "/tickets", `{"email":"test@example.com","event_id":"`+event.ID.String()+`"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp struct {
+		Ticket model.Ticket `json:"ticket"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	w = doJSON(r, http.MethodPost, "/tickets/"+resp.Ticket.ID.String()+"/confirm", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	return resp.Ticket
+}
+
+func TestMemoryAPI_ExportCheckinBundle(t *testing.T) {
+	r, _ := newMemoryAPI()
+	ticket := createConfirmedTicketForEvent(t, r, false)
+
+	w := doJSON(r, http.MethodGet, "/events/"+ticket.EventID.String()+"/checkin-bundle", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Bundle service.CheckinBundle `json:"bundle"`
+		Count  int                   `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Count)
+
+	manifest, err := service.VerifyCheckinBundle(resp.Bundle, testBundleSigner().PublicKey())
+	require.NoError(t, err)
+	assert.Equal(t, ticket.EventID, manifest.EventID)
+	assert.Contains(t, manifest.Entries, service.BundleEntry(testQRService().GenerateQRContent(ticket)))
+
+	w = doJSON(r, http.MethodGet, "/events/550e8400-e29b-41d4-a716-446655440099/checkin-bundle", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMemoryAPI_SyncOfflineCheckIns(t *testing.T) {
+	r, _ := newMemoryAPI()
+	ticket := createConfirmedTicketForEvent(t, r, false)
+	content := testQRService().GenerateQRContent(ticket)
+	scannedAt := func(ago time.Duration) string {
+		return time.Now().UTC().Add(-ago).Format(time.RFC3339)
+	}
+
+	// Gate 2 syncs first with a later scan
+	w := doJSON(r, http.MethodPost, "/checkins/sync", `{
+		"scanner_id": "550e8400-e29b-41d4-a716-446655440022",
+		"scans": [{"qr_content":"`+content+`","scanned_at":"`+scannedAt(5*time.Minute)+`","gate":"2"}]
+	}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"result":"accepted"`)
+
+	// Gate 3 scanned the same ticket earlier, so it wins regardless of sync order
+	w = doJSON(r, http.MethodPost, "/checkins/sync", `{
+		"scanner_id": "550e8400-e29b-41d4-a716-446655440033",
+		"scans": [
+			{"qr_content":"`+content+`","scanned_at":"`+scannedAt(8*time.Minute)+`","gate":"3"},
+			{"qr_content":"`+content+`","scanned_at":"`+scannedAt(time.Minute)+`","gate":"3"},
+			{"qr_content":"TKT1.test.forged.signature","scanned_at":"`+scannedAt(7*time.Minute)+`","gate":"3"}
+		]
+	}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Results []struct {
+			Result      string `json:"result"`
+			CheckInGate string `json:"check_in_gate"`
+		} `json:"results"`
+		Summary map[string]int `json:"summary"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, "accepted", resp.Results[0].Result)
+	assert.Equal(t, "duplicate", resp.Results[1].Result)
+	assert.Equal(t, "3", resp.Results[1].CheckInGate)
+	assert.Equal(t, "rejected", resp.Results[2].Result)
+	assert.Equal(t, 1, resp.Summary["duplicate"])
+
+	// Gate 2 re-syncing is now reported as a duplicate of gate 3
+	w = doJSON(r, http.MethodPost, "/checkins/sync", `{
+		"scanner_id": "550e8400-e29b-41d4-a716-446655440022",
+		"scans": [{"qr_content":"`+content+`","scanned_at":"`+scannedAt(5*time.Minute)+`","gate":"2"}]
+	}`)
+	assert.Contains(t, w.Body.String(), `"result":"duplicate"`)
+	assert.Contains(t, w.Body.String(), `"check_in_gate":"3"`)
+}
+
+func TestMemoryAPI_SyncRejectsScansOutsideWindow(t *testing.T) {
+	r, repo := newMemoryAPI()
+	ticket := createConfirmedTicketForEvent(t, r, true)
+	now := time.Now()
+
+	// The event (and so its check-in bundle) ended half an hour ago
+	event, err := repo.GetEventByID(context.Background(), ticket.EventID.String())
+	require.NoError(t, err)
+	event.StartTime = now.Add(-3 * time.Hour)
+	event.EndTime = now.Add(-30 * time.Minute)
+	require.NoError(t, repo.UpdateEvent(context.Background(), *event, 0))
+
+	scan := func(at time.Time) string {
+		content, _ := testQRService().Signer.SignRotating(ticket.ID, ticket.EventID, at)
+		return fmt.Sprintf(`{"qr_content":%q,"scanned_at":%q}`, content, at.UTC().Format(time.RFC3339))
+	}
+	w := doJSON(r, http.MethodPost, "/checkins/sync", `{
+		"scanner_id": "550e8400-e29b-41d4-a716-446655440022",
+		"scans": [`+scan(now.Add(-24*time.Hour))+`,`+scan(now.Add(time.Hour))+`,`+scan(now.Add(-10*time.Minute))+`]
+	}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Results []offlineScanResult `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 3)
+	for _, result := range resp.Results {
+		assert.Equal(t, scanRejected, result.Result)
+	}
+	// A rotating code captured a day ago cannot be replayed by backdating the scan
+	assert.Contains(t, resp.Results[0].Reason, "demasiado antiguo")
+	assert.Contains(t, resp.Results[1].Reason, "posterior a la sincronización")
+	assert.Contains(t, resp.Results[2].Reason, "vigencia del bundle")
+
+	stored, err := repo.GetTicketByID(context.Background(), ticket.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusConfirmed, stored.Status)
+
+	// A scan made while the event was running is still accepted
+	w = doJSON(r, http.MethodPost, "/checkins/sync", `{
+		"scanner_id": "550e8400-e29b-41d4-a716-446655440022",
+		"scans": [`+scan(now.Add(-time.Hour))+`]
+	}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"result":"accepted"`)
+}
+
+func TestMemoryAPI_RotatingQR(t *testing.T) {
+	r, _ := newMemoryAPI()
+	ticket := createConfirmedTicketForEvent(t, r, true)
+
+	w := doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/qr", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.NotEmpty(t, w.Header().Get("X-QR-Valid-Until"))
+
+	// A static code (e.g. an old screenshot of a signed QR) is not accepted for this event
+	static := testQRService().GenerateQRContent(ticket)
+	w = doJSON(r, http.MethodPost, "/qr/validate", `{"qr_content":"`+static+`"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	rotating, _ := testQRService().Signer.SignRotating(ticket.ID, ticket.EventID, time.Now())
+	w = doJSON(r, http.MethodPost, "/qr/validate", `{"qr_content":"`+rotating+`"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	stale, _ := testQRService().Signer.SignRotating(ticket.ID, ticket.EventID, time.Now().Add(-5*service.QRRotationStep))
+	w = doJSON(r, http.MethodPost, "/qr/validate", `{"qr_content":"`+stale+`"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Código QR vencido")
+
+	w = doJSON(r, http.MethodGet, "/events/"+ticket.EventID.String()+"/checkin-bundle", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func uploadImage(t *testing.T, r *gin.Engine, path string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("image", "screenshot.png")
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMemoryAPI_DecodeQRImage(t *testing.T) {
+	r, _ := newMemoryAPI()
+	ticket := createConfirmedTicketForEvent(t, r, false)
+
+	qrPNG, err := testQRService().GenerateTicketQRPNG(ticket)
+	require.NoError(t, err)
+
+	w := uploadImage(t, r, "/qr/decode", qrPNG)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"valid":true`)
+	assert.Contains(t, w.Body.String(), ticket.ID.String())
+
+	// A QR signed with another key decodes fine but is reported as invalid
+	forger, err := service.NewQRSigner("test", map[string][]byte{"test": bytes.Repeat([]byte("x"), 32)})
+	require.NoError(t, err)
+	forgedPNG, err := service.NewQRService(forger).GenerateTicketQRPNG(ticket)
+	require.NoError(t, err)
+
+	w = uploadImage(t, r, "/qr/decode", forgedPNG)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"valid":false`)
+	assert.Contains(t, w.Body.String(), `"reason":"bad_signature"`)
+	assert.Contains(t, w.Body.String(), "Firma del código QR inválida")
+
+	var blank bytes.Buffer
+	require.NoError(t, png.Encode(&blank, image.NewGray(image.Rect(0, 0, 64, 64))))
+	w = uploadImage(t, r, "/qr/decode", blank.Bytes())
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = uploadImage(t, r, "/qr/decode", []byte("not an image"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	// Un PNG chico que declara 100000x100000 píxeles se rechaza sin decodificarlo
+	bomb := bytes.Clone(blank.Bytes())
+	binary.BigEndian.PutUint32(bomb[16:20], 100000)
+	binary.BigEndian.PutUint32(bomb[20:24], 100000)
+	binary.BigEndian.PutUint32(bomb[29:33], crc32.ChecksumIEEE(bomb[12:29]))
+	w = uploadImage(t, r, "/qr/decode", bomb)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "megapíxeles")
+
+	w = uploadImage(t, r, "/qr/decode", make([]byte, maxQRImageSize+maxQRFormOverhead))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestMemoryAPI_DecodeQRImageCancelledTicket(t *testing.T) {
+	r, _ := newMemoryAPI()
+	ticket := createConfirmedTicketForEvent(t, r, false)
+	qrPNG, err := testQRService().GenerateTicketQRPNG(ticket)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/cancel", "").Code)
+
+	// La imagen se decodifica pero el ticket cancelado no es válido
+	w := uploadImage(t, r, "/qr/decode", qrPNG)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"valid":false`)
+	assert.Contains(t, w.Body.String(), `"reason":"cancelled"`)
+	assert.Contains(t, w.Body.String(), ticket.ID.String())
+}
+
+func TestMemoryAPI_TicketPDFFromLocalStore(t *testing.T) {
+	r, repo := newMemoryAPI()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost:8080/api/blobs", bytes.Repeat([]byte("s"), 32))
+	require.NoError(t, err)
+
+	handlerDocument := NewDocumentHandler(repo, store, testQRService())
+	r.GET("/tickets/:id/pdf", handlerDocument.GetTicketPDF)
+	r.GET("/tickets/:id/pdf-url", handlerDocument.GetTicketPDFURL)
+	r.GET("/api/blobs/*key", NewBlobHandler(store).ServeBlob)
+
+	ticket := createConfirmedTicketForEvent(t, r, false)
+
+	// El PDF no existe todavía: se genera al pedir la URL
+	w := doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/pdf-url", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		URL string `json:"url"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	u, err := url.Parse(resp.URL)
+	require.NoError(t, err)
+	w = doJSON(r, http.MethodGet, u.RequestURI(), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+
+	w = doJSON(r, http.MethodGet, u.Path+"?expires=1&signature=x", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/pdf-url?redirect=true", "")
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/pdf", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+}
+
+func TestMemoryAPI_TicketQRPresignedURL(t *testing.T) {
+	r, repo := newMemoryAPI()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost:8080/api/blobs", bytes.Repeat([]byte("s"), 32))
+	require.NoError(t, err)
+	store.PresignExpiry = 5 * time.Minute
+
+	handlerQR := NewQRHandler(repo, store, testQRService())
+	r.GET("/tickets/:id/qr-url", handlerQR.GetTicketQRURL)
+	r.GET("/api/blobs/*key", NewBlobHandler(store).ServeBlob)
+
+	ticket := createConfirmedTicketForEvent(t, r, false)
+	path := "/tickets/" + ticket.ID.String() + "/qr-url"
+
+	w := doJSON(r, http.MethodGet, path, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	_, _, err = service.NewTicketArtifactService(store, testQRService()).Generate(context.Background(), ticket, nil)
+	require.NoError(t, err)
+
+	presign := func(query string) (*url.URL, time.Time) {
+		t.Helper()
+		w := doJSON(r, http.MethodGet, path+query, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		var resp struct {
+			URL       string    `json:"url"`
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		u, err := url.Parse(resp.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "/api/blobs/"+service.QRKey(ticket), u.Path)
+		assert.Equal(t, strconv.FormatInt(resp.ExpiresAt.Unix(), 10), u.Query().Get("expires"))
+		return u, resp.ExpiresAt
+	}
+
+	// Sin expires_in vale la vigencia configurada, y no se puede pedir más
+	u, expiresAt := presign("")
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), expiresAt, 2*time.Second)
+	assert.Equal(t, "image/png", u.Query().Get("content_type"))
+	assert.Equal(t, "qr-"+ticket.ID.String()+".png", u.Query().Get("filename"))
+	_, expiresAt = presign("?expires_in=3600")
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), expiresAt, 2*time.Second)
+	_, expiresAt = presign("?expires_in=60")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, 2*time.Second)
+
+	w = doJSON(r, http.MethodGet, path+"?expires_in=0", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(r, http.MethodGet, u.RequestURI(), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("\x89PNG")))
+
+	w = doJSON(r, http.MethodGet, path+"?redirect=true", "")
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, u.Path, location.Path)
+}
+
+func TestMemoryAPI_CancelAndDeleteRemoveArtifacts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	repo := db.NewMemoryRepository()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost:8080/api/blobs", bytes.Repeat([]byte("s"), 32))
+	require.NoError(t, err)
+	artifacts := service.NewTicketArtifactService(store, testQRService())
+
+	handlerTicket := NewTicketHandler(repo, artifacts)
+	handlerEvent := NewEventHandler(repo)
+	handlerQR := NewQRHandler(repo, store, testQRService())
+	r.POST("/events", handlerEvent.CreateEvent)
+	r.POST("/tickets", handlerTicket.CreateTicket)
+	r.POST("/tickets/:id/confirm", handlerTicket.ConfirmTicket)
+	r.POST("/tickets/:id/cancel", handlerTicket.CancelTicket)
+	r.DELETE("/tickets/:id", handlerTicket.DeleteTicket)
+	r.GET("/tickets/:id/qr", handlerQR.GetTicketQR)
+	r.GET("/tickets/:id/qr-url", handlerQR.GetTicketQRURL)
+
+	ctx := context.Background()
+	for _, action := range []string{"cancel", "delete"} {
+		ticket := createConfirmedTicketForEvent(t, r, false)
+		_, _, err := artifacts.Generate(ctx, ticket, nil)
+		require.NoError(t, err)
+
+		w := doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/qr-url", "")
+		require.Equal(t, http.StatusOK, w.Code, action)
+
+		if action == "cancel" {
+			w = doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/cancel", "")
+			require.Equal(t, http.StatusOK, w.Code)
+
+			w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/qr", "")
+			assert.Equal(t, http.StatusConflict, w.Code)
+			w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/qr-url", "")
+			assert.Equal(t, http.StatusConflict, w.Code)
+		} else {
+			w = doJSON(r, http.MethodDelete, "/tickets/"+ticket.ID.String(), "")
+			require.Equal(t, http.StatusOK, w.Code)
+		}
+
+		_, err = store.Head(ctx, service.QRKey(ticket))
+		assert.ErrorIs(t, err, storage.ErrBlobNotFound, action)
+		_, err = store.Head(ctx, service.TicketFileKey(ticket))
+		assert.ErrorIs(t, err, storage.ErrBlobNotFound, action)
+	}
+}
+
+func TestMemoryAPI_ListEventsWithCursor(t *testing.T) {
+	r, _ := newMemoryAPI()
+	created := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		created[createTestEvent(t, r, 1, false).ID.String()] = true
+	}
+
+	seen := map[string]bool{}
+	path := "/events?status=open&limit=2"
+	for {
+		w := doJSON(r, http.MethodGet, path, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Events     []model.Event `json:"events"`
+			NextCursor string        `json:"next_cursor"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		for _, event := range resp.Events {
+			seen[event.ID.String()] = true
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		path = "/events?status=open&limit=2&cursor=" + resp.NextCursor
+	}
+	assert.Equal(t, created, seen)
+
+	w := doJSON(r, http.MethodGet, "/events?cursor=no-es-un-cursor", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMemoryAPI_DeleteEventWithTickets(t *testing.T) {
+	r, _ := newMemoryAPI()
+	ticket := createConfirmedTicketForEvent(t, r, false)
+	eventPath := "/events/" + ticket.EventID.String()
+
+	w := doJSON(r, http.MethodDelete, eventPath, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/cancel", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, http.MethodDelete, eventPath, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(r, http.MethodDelete, eventPath, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Un ticket cuyo evento ya no existe se puede eliminar igual
+	w = doJSON(r, http.MethodDelete, "/tickets/"+ticket.ID.String(), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMemoryAPI_CancelAndDeleteReleaseSeat(t *testing.T) {
+	r, repo := newMemoryAPI()
+	ctx := context.Background()
+	held := createConfirmedTicketForEvent(t, r, false)
+	event, err := repo.GetEventByID(ctx, held.EventID.String())
+	require.NoError(t, err)
+
+	reserve := func() model.Ticket {
+		now := time.Now()
+		ticket := model.Ticket{ID: uuid.New(), EventID: event.ID, Email: "test@example.com", TicketCode: "TKT-seat", Status: model.TicketStatusReserved, CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, repo.CreateReservation(ctx, ticket, model.OutboxMessage{ID: uuid.New(), AggregateID: ticket.ID.String(), Status: model.OutboxStatusPending, CreatedAt: now}))
+		return ticket
+	}
+	available := func() int {
+		stored, err := repo.GetEventByID(ctx, event.ID.String())
+		require.NoError(t, err)
+		return stored.AvailableCapacity
+	}
+
+	cancelled, deleted := reserve(), reserve()
+	before := available()
+
+	w := doJSON(r, http.MethodPost, "/tickets/"+cancelled.ID.String()+"/cancel", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, before+1, available())
+
+	// Borrar un ticket ya cancelado no devuelve el asiento otra vez
+	w = doJSON(r, http.MethodDelete, "/tickets/"+cancelled.ID.String(), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, before+1, available())
+
+	w = doJSON(r, http.MethodDelete, "/tickets/"+deleted.ID.String(), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, before+2, available())
+}
+
+func TestMemoryAPI_CreateTicketTakesSeat(t *testing.T) {
+	r, repo := newMemoryAPI()
+	event := createTestEvent(t, r, 1, false)
+	other := createTestEvent(t, r, 1, false)
+
+	w := doJSON(r, http.MethodPost, "/tickets", `{"email":"test@example.com","event_id":"no-es-un-uuid"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Event ID inválido")
+
+	w = doJSON(r, http.MethodPost, "/tickets", `{"email":"test@example.com","event_id":"550e8400-e29b-41d4-a716-446655440003"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(r, http.MethodPost, "/tickets", `{"email":"test@example.com","event_id":"`+event.ID.String()+`"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp struct {
+		Ticket model.Ticket `json:"ticket"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Ticket.SeatHeld)
+	assert.NotNil(t, resp.Ticket.ExpiresAt)
+
+	stored, err := repo.GetEventByID(context.Background(), event.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.AvailableCapacity)
+
+	// Sin capacidad no se emiten más tickets
+	w = doJSON(r, http.MethodPost, "/tickets", `{"email":"test@example.com","event_id":"`+event.ID.String()+`"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// El ticket no puede pasarse a otro evento sin mover el asiento
+	w = doJSON(r, http.MethodPut, "/tickets/"+resp.Ticket.ID.String(), `{"event_id":"`+other.ID.String()+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(r, http.MethodPut, "/tickets/"+resp.Ticket.ID.String(), `{"event_id":"`+event.ID.String()+`","email":"new@example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(r, http.MethodPost, "/tickets/"+resp.Ticket.ID.String()+"/cancel", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	stored, err = repo.GetEventByID(context.Background(), event.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.AvailableCapacity)
+	stored, err = repo.GetEventByID(context.Background(), other.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.AvailableCapacity)
+}
+
+func TestMemoryAPI_IdempotencyKeyReleasedOnPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	repo := db.NewMemoryRepository()
+	calls := 0
+	r.POST("/tickets", Idempotency(repo, time.Hour), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("fallo inesperado")
+		}
+		c.JSON(http.StatusCreated, gin.H{"calls": calls})
+	})
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/tickets", bytes.NewBufferString(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "panic-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, post().Code)
+
+	// La clave quedó libre, así que el reintento se procesa en lugar de dar 409
+	w := post()
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"calls":2}`, w.Body.String())
+}
+
+func TestMemoryAPI_IdempotencyKeyLeaseTakeover(t *testing.T) {
+	r, repo := newMemoryAPI()
+	event := createTestEvent(t, r, 10, false)
+	body := `{"email":"test@example.com","event_id":"` + event.ID.String() + `"}`
+	hash := sha256.Sum256([]byte(body))
+
+	// Una petición que murió sin liberar la clave deja un registro en curso
+	lock := func(key string, lockExpiresAt time.Time) {
+		now := time.Now()
+		require.NoError(t, repo.AcquireIdempotencyKey(context.Background(), model.IdempotencyRecord{
+			Key:           "POST /tickets " + key,
+			RequestHash:   hex.EncodeToString(hash[:]),
+			Status:        model.IdempotencyStatusInProgress,
+			CreatedAt:     now.Add(-2 * IdempotencyLease),
+			ExpiresAt:     now.Add(time.Hour),
+			LockExpiresAt: lockExpiresAt,
+		}))
+	}
+	post := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/tickets", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	lock("alive", time.Now().Add(IdempotencyLease))
+	w := post("alive")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "en curso")
+
+	lock("crashed", time.Now().Add(-time.Minute))
+	first := post("crashed")
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	// El reintento que tomó la clave completó la respuesta y se repite tal cual
+	second := post("crashed")
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayHeader))
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+)
+
+// BlobHandler sirve los archivos del almacenamiento local a través de las URLs
+// prefirmadas que genera LocalStore. Con S3 no hace falta: la URL apunta al bucket.
+type BlobHandler struct {
+	Store *storage.LocalStore
+}
+
+func NewBlobHandler(store *storage.LocalStore) *BlobHandler {
+	return &BlobHandler{Store: store}
+}
+
+// ServeBlob responde GET /api/blobs/*key si la firma de la URL es válida y no venció
+func (h *BlobHandler) ServeBlob(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Clave de archivo requerida"})
+		return
+	}
+
+	if err := h.Store.VerifyPresigned(key, c.Request.URL.Query(), time.Now()); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrExpiredBlobSignature):
+			c.JSON(http.StatusForbidden, gin.H{"error": "URL de descarga vencida"})
+		case errors.Is(err, storage.ErrInvalidBlobKey):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Clave de archivo inválida"})
+		default:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Firma de URL inválida"})
+		}
+		return
+	}
+
+	info, err := h.Store.Head(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Archivo no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo archivo", "details": err.Error()})
+		return
+	}
+
+	body, err := h.Store.Get(c.Request.Context(), key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo archivo", "details": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	contentType := c.Query("content_type")
+	if contentType == "" {
+		contentType = info.ContentType
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	headers := map[string]string{}
+	if filename := c.Query("filename"); filename != "" {
+		headers["Content-Disposition"] = fmt.Sprintf("inline; filename=%s", filename)
+	}
+	c.DataFromReader(http.StatusOK, info.Size, contentType, body, headers)
+}
@@ -0,0 +1,417 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+)
+
+// maxOfflineScans limita cuántos escaneos acepta una sincronización
+const maxOfflineScans = 500
+
+// DefaultMaxOfflineAge es cuánto tiempo atrás puede haberse hecho un escaneo
+// sin conexión para aceptarlo al sincronizar
+const DefaultMaxOfflineAge = 12 * time.Hour
+
+// offlineClockSkew tolera relojes de lectores algo adelantados respecto del servidor
+const offlineClockSkew = 2 * time.Minute
+
+type CheckinHandler struct {
+	DB      db.Repository
+	QR      *service.QRService
+	Bundles *service.BundleSigner
+	// MaxOfflineAge acota scanned_at hacia atrás: sin ese límite un QR rotativo
+	// capturado podría sincronizarse con la hora de su ventana
+	MaxOfflineAge time.Duration
+}
+
+func NewCheckinHandler(db db.Repository, qr *service.QRService, bundles *service.BundleSigner) *CheckinHandler {
+	return &CheckinHandler{DB: db, QR: qr, Bundles: bundles, MaxOfflineAge: DefaultMaxOfflineAge}
+}
+
+// CheckIn valida un QR escaneado en la puerta y marca el ticket como usado.
+// Un mismo QR solo da acceso una vez; los escaneos siguientes reciben 409 con
+// la hora y la puerta del primer ingreso.
+func (h *CheckinHandler) CheckIn(c *gin.Context) {
+	var req struct {
+		QRContent string `json:"qr_content" binding:"required"`
+		ScannerID string `json:"scanner_id" binding:"required"`
+		Gate      string `json:"gate"`
+		EventID   string `json:"event_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de check-in inválidos", "details": err.Error()})
+		return
+	}
+
+	scannerID, err := uuid.Parse(req.ScannerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de scanner_id inválido", "details": "Debe ser un UUID válido"})
+		return
+	}
+
+	// La firma se verifica antes de tocar la base de datos
+	claims, err := h.QR.ValidateQRContent(req.QRContent)
+	if err != nil {
+		countRejectedScan(qrFailureReason(err))
+		if errors.Is(err, service.ErrQRBadSignature) || errors.Is(err, service.ErrQRUnknownKey) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Firma del código QR inválida"})
+			return
+		}
+		if errors.Is(err, service.ErrQRExpired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Código QR vencido, actualice el código en la app"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato QR inválido", "details": err.Error()})
+		return
+	}
+
+	// Un lector configurado para un evento no acepta tickets de otro
+	if req.EventID != "" && req.EventID != claims.EventID.String() {
+		countRejectedScan(metrics.QRFailureWrongEvent)
+		c.JSON(http.StatusConflict, gin.H{"error": "El ticket pertenece a otro evento", "event_id": claims.EventID})
+		return
+	}
+
+	event, err := findEvent(c.Request.Context(), h.DB, claims.EventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return
+	}
+	if err := service.CheckEventMode(claims, event); err != nil {
+		countRejectedScan(metrics.QRFailureStaticQR)
+		c.JSON(http.StatusForbidden, gin.H{"error": "El evento solo admite QR dinámicos"})
+		return
+	}
+
+	ticketID := claims.TicketID.String()
+	now := time.Now()
+	if err := h.DB.CheckInTicket(c.Request.Context(), ticketID, scannerID, req.Gate, now); err != nil {
+		if errors.Is(err, db.ErrTicketStatusConflict) {
+			h.rejectCheckIn(c, ticketID)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error registrando ingreso", "details": err.Error()})
+		return
+	}
+	metrics.CheckIns.WithLabelValues(metrics.CheckInOnline, scanAccepted).Inc()
+
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo ticket", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Ingreso registrado",
+		"ticket":        ticket,
+		"checked_in_at": ticket.CheckedInAt,
+		"checked_in_by": ticket.CheckedInBy,
+	})
+}
+
+// rejectCheckIn explica por qué el ticket no pudo marcarse como usado
+func (h *CheckinHandler) rejectCheckIn(c *gin.Context, ticketID string) {
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo ticket", "details": err.Error()})
+		return
+	}
+
+	if ticket.Status != model.TicketStatusUsed {
+		metrics.CheckIns.WithLabelValues(metrics.CheckInOnline, scanRejected).Inc()
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "El ticket no permite el ingreso",
+			"status": ticket.Status,
+		})
+		return
+	}
+
+	metrics.CheckIns.WithLabelValues(metrics.CheckInOnline, scanDuplicate).Inc()
+	c.JSON(http.StatusConflict, gin.H{
+		"error":         "Ticket ya utilizado",
+		"message":       h.describeCheckIn(c.Request.Context(), ticket),
+		"checked_in_at": ticket.CheckedInAt,
+		"checked_in_by": ticket.CheckedInBy,
+		"check_in_gate": ticket.CheckInGate,
+	})
+}
+
+// countRejectedScan cuenta un escaneo en la puerta rechazado por su código QR
+func countRejectedScan(reason string) {
+	metrics.QRValidationFailures.WithLabelValues(reason).Inc()
+	metrics.CheckIns.WithLabelValues(metrics.CheckInOnline, scanRejected).Inc()
+}
+
+// qrFailureReason clasifica un error de ValidateQRContent para las métricas
+func qrFailureReason(err error) string {
+	switch {
+	case errors.Is(err, service.ErrQRBadSignature), errors.Is(err, service.ErrQRUnknownKey):
+		return metrics.QRFailureBadSignature
+	case errors.Is(err, service.ErrQRExpired):
+		return metrics.QRFailureExpired
+	default:
+		return metrics.QRFailureMalformed
+	}
+}
+
+// describeCheckIn arma el mensaje para el personal de la puerta, con la hora
+// en la zona horaria del evento
+func (h *CheckinHandler) describeCheckIn(ctx context.Context, ticket *model.Ticket) string {
+	if ticket.CheckedInAt == nil {
+		return "El ticket ya fue utilizado"
+	}
+
+	checkedInAt := *ticket.CheckedInAt
+	if event, err := h.DB.GetEventByID(ctx, ticket.EventID.String()); err == nil && event.Timezone != "" {
+		if loc, err := time.LoadLocation(event.Timezone); err == nil {
+			checkedInAt = checkedInAt.In(loc)
+		}
+	}
+
+	message := fmt.Sprintf("Ya ingresó a las %s", checkedInAt.Format("15:04"))
+	if ticket.CheckInGate != "" {
+		message += fmt.Sprintf(" por la puerta %s", ticket.CheckInGate)
+	}
+	return message
+}
+
+// ExportBundle devuelve la lista firmada de tickets admitidos del evento para
+// que los lectores puedan validar sin conexión
+func (h *CheckinHandler) ExportBundle(c *gin.Context) {
+	eventID := c.Param("id")
+	if eventID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de evento requerido"})
+		return
+	}
+
+	event, err := h.DB.GetEventByID(c.Request.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, db.ErrEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Evento no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return
+	}
+
+	// Los QR rotativos cambian cada pocos segundos y no caben en una lista fija
+	if event.RotatingQR {
+		c.JSON(http.StatusConflict, gin.H{"error": "El evento usa QR dinámicos y requiere validación en línea"})
+		return
+	}
+
+	var tickets []model.Ticket
+	cursor := ""
+	for {
+		page, next, err := h.DB.GetTickets(c.Request.Context(), "", event.ID.String(), maxTicketsPageSize, cursor)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo tickets", "details": err.Error()})
+			return
+		}
+		tickets = append(tickets, page...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	bundle, manifest, err := h.Bundles.BuildCheckinBundle(*event, tickets, h.QR, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando bundle de check-in", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bundle":       bundle,
+		"event_id":     event.ID,
+		"count":        manifest.Count,
+		"generated_at": manifest.GeneratedAt,
+		"valid_until":  manifest.ValidUntil,
+	})
+}
+
+// BundleKeys publica la clave con la que los lectores verifican los bundles
+func (h *CheckinHandler) BundleKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"keys": []gin.H{{
+			"key_id":     h.Bundles.KeyID,
+			"algorithm":  "Ed25519",
+			"public_key": base64.StdEncoding.EncodeToString(h.Bundles.PublicKey()),
+		}},
+	})
+}
+
+type offlineScan struct {
+	QRContent string    `json:"qr_content" binding:"required"`
+	ScannedAt time.Time `json:"scanned_at" binding:"required"`
+	Gate      string    `json:"gate"`
+}
+
+type offlineScanResult struct {
+	Index       int        `json:"index"`
+	TicketID    string     `json:"ticket_id,omitempty"`
+	Result      string     `json:"result"`
+	Reason      string     `json:"reason,omitempty"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+	CheckedInBy *uuid.UUID `json:"checked_in_by,omitempty"`
+	CheckInGate string     `json:"check_in_gate,omitempty"`
+}
+
+const (
+	scanAccepted  = "accepted"
+	scanDuplicate = "duplicate"
+	scanRejected  = "rejected"
+	scanError     = "error"
+)
+
+// SyncCheckIns recibe los ingresos que un lector registró sin conexión. Cuando el
+// mismo ticket se escaneó en varias puertas gana el escaneo más antiguo (y a igual
+// hora el scanner_id menor), sin importar el orden en que llegan las sincronizaciones;
+// los demás se informan como duplicados. Los escaneos con error pueden reenviarse.
+// Se rechazan los escaneos con scanned_at posterior a la recepción, anterior a
+// MaxOfflineAge o fuera de la vigencia del bundle (el fin del evento).
+func (h *CheckinHandler) SyncCheckIns(c *gin.Context) {
+	var req struct {
+		ScannerID string        `json:"scanner_id" binding:"required"`
+		EventID   string        `json:"event_id"`
+		Scans     []offlineScan `json:"scans" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de sincronización inválidos", "details": err.Error()})
+		return
+	}
+
+	if len(req.Scans) > maxOfflineScans {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Demasiados escaneos", "details": fmt.Sprintf("Máximo %d por sincronización", maxOfflineScans)})
+		return
+	}
+
+	scannerID, err := uuid.Parse(req.ScannerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato de scanner_id inválido", "details": "Debe ser un UUID válido"})
+		return
+	}
+
+	// Se aplican en orden cronológico para que el resultado sea determinista
+	order := make([]int, len(req.Scans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return req.Scans[order[a]].ScannedAt.Before(req.Scans[order[b]].ScannedAt)
+	})
+
+	receivedAt := time.Now()
+	results := make([]offlineScanResult, len(req.Scans))
+	summary := map[string]int{scanAccepted: 0, scanDuplicate: 0, scanRejected: 0, scanError: 0}
+	for _, i := range order {
+		results[i] = h.applyOfflineScan(c.Request.Context(), req.Scans[i], scannerID, req.EventID, receivedAt)
+		results[i].Index = i
+		summary[results[i].Result]++
+		metrics.CheckIns.WithLabelValues(metrics.CheckInOffline, results[i].Result).Inc()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"summary": summary,
+	})
+}
+
+func (h *CheckinHandler) applyOfflineScan(ctx context.Context, scan offlineScan, scannerID uuid.UUID, eventID string, receivedAt time.Time) offlineScanResult {
+	if scan.ScannedAt.After(receivedAt.Add(offlineClockSkew)) {
+		return offlineScanResult{Result: scanRejected, Reason: "La hora del escaneo es posterior a la sincronización"}
+	}
+	if scan.ScannedAt.Before(receivedAt.Add(-h.MaxOfflineAge)) {
+		return offlineScanResult{Result: scanRejected, Reason: "El escaneo es demasiado antiguo para sincronizarse"}
+	}
+
+	// Los QR rotativos se comprueban contra la hora del escaneo, no la de la
+	// sincronización; los límites de arriba impiden elegirla libremente
+	claims, err := h.QR.ValidateQRContentAt(scan.QRContent, scan.ScannedAt)
+	if err != nil {
+		metrics.QRValidationFailures.WithLabelValues(qrFailureReason(err)).Inc()
+		return offlineScanResult{Result: scanRejected, Reason: "Código QR inválido"}
+	}
+
+	result := offlineScanResult{TicketID: claims.TicketID.String()}
+	if eventID != "" && eventID != claims.EventID.String() {
+		result.Result = scanRejected
+		result.Reason = "El ticket pertenece a otro evento"
+		metrics.QRValidationFailures.WithLabelValues(metrics.QRFailureWrongEvent).Inc()
+		return result
+	}
+
+	event, err := findEvent(ctx, h.DB, claims.EventID)
+	if err != nil {
+		result.Result = scanError
+		result.Reason = err.Error()
+		return result
+	}
+	if err := service.CheckEventMode(claims, event); err != nil {
+		result.Result = scanRejected
+		result.Reason = "El evento solo admite QR dinámicos"
+		metrics.QRValidationFailures.WithLabelValues(metrics.QRFailureStaticQR).Inc()
+		return result
+	}
+	// El bundle que usó el lector vence al terminar el evento
+	if event != nil && !event.EndTime.IsZero() && scan.ScannedAt.After(event.EndTime) {
+		result.Result = scanRejected
+		result.Reason = "El escaneo es posterior a la vigencia del bundle"
+		return result
+	}
+
+	err = h.DB.RecordOfflineCheckIn(ctx, result.TicketID, scannerID, scan.Gate, scan.ScannedAt)
+	if err == nil {
+		result.Result = scanAccepted
+		return result
+	}
+	if !errors.Is(err, db.ErrTicketStatusConflict) {
+		result.Result = scanError
+		result.Reason = err.Error()
+		return result
+	}
+
+	ticket, err := h.DB.GetTicketByID(ctx, result.TicketID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			result.Result = scanRejected
+			result.Reason = "Ticket no encontrado"
+			return result
+		}
+		result.Result = scanError
+		result.Reason = err.Error()
+		return result
+	}
+
+	if ticket.Status != model.TicketStatusUsed {
+		result.Result = scanRejected
+		result.Reason = fmt.Sprintf("El ticket está en estado '%s'", ticket.Status)
+		return result
+	}
+
+	result.Result = scanDuplicate
+	result.Reason = h.describeCheckIn(ctx, ticket)
+	result.CheckedInAt = ticket.CheckedInAt
+	result.CheckedInBy = ticket.CheckedInBy
+	result.CheckInGate = ticket.CheckInGate
+	return result
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for CheckinHandler
+func TestCheckIn_MissingQRContent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &CheckinHandler{}
+	r.POST("/checkins", handler.CheckIn)
+
+	body := `{"scanner_id":"550e8400-e29b-41d4-a716-446655440010"}`
+	req := httptest.NewRequest(http.MethodPost, "/checkins", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Datos de check-in inválidos")
+}
+
+func TestCheckIn_InvalidScannerID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &CheckinHandler{}
+	r.POST("/checkins", handler.CheckIn)
+
+	body := `{"qr_content":"TKT1.k1.abc.def","scanner_id":"gate-3"}`
+	req := httptest.NewRequest(http.MethodPost, "/checkins", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Formato de scanner_id inválido")
+}
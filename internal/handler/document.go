@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+)
+
+// DocumentHandler sirve los documentos descargables de un ticket
+type DocumentHandler struct {
+	DB        db.Repository
+	Artifacts *service.TicketArtifactService
+}
+
+func NewDocumentHandler(db db.Repository, blobs storage.BlobStore, qr *service.QRService) *DocumentHandler {
+	return &DocumentHandler{
+		DB:        db,
+		Artifacts: service.NewTicketArtifactService(blobs, qr),
+	}
+}
+
+// GetTicketPDF devuelve el ticket imprimible en PDF
+func (h *DocumentHandler) GetTicketPDF(c *gin.Context) {
+	ticketID := c.Param("id")
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de ticket requerido"})
+		return
+	}
+
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
+		return
+	}
+
+	if rejectCancelledTicket(c, ticket) {
+		return
+	}
+
+	event, err := findEvent(c.Request.Context(), h.DB, ticket.EventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return
+	}
+
+	pdfData, err := h.Artifacts.TicketPDF(c.Request.Context(), *ticket, event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo PDF del ticket", "details": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=ticket-%s.pdf", ticket.TicketCode))
+	c.Data(http.StatusOK, "application/pdf", pdfData)
+}
+
+// GetTicketPDFURL devuelve una URL prefirmada del PDF del ticket, o redirige a
+// ella con redirect=true. Si el PDF todavía no está guardado lo genera antes.
+func (h *DocumentHandler) GetTicketPDFURL(c *gin.Context) {
+	ticketID := c.Param("id")
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de ticket requerido"})
+		return
+	}
+
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
+		return
+	}
+
+	if rejectCancelledTicket(c, ticket) {
+		return
+	}
+
+	key := service.TicketFileKey(*ticket)
+	if _, err := h.Artifacts.Blobs.Head(c.Request.Context(), key); err != nil {
+		if !errors.Is(err, storage.ErrBlobNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error consultando PDF del ticket", "details": err.Error()})
+			return
+		}
+		event, err := findEvent(c.Request.Context(), h.DB, ticket.EventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+			return
+		}
+		if _, err := h.Artifacts.TicketPDF(c.Request.Context(), *ticket, event); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando PDF del ticket", "details": err.Error()})
+			return
+		}
+	}
+
+	respondPresignedURL(c, h.Artifacts.Blobs, key, storage.PresignOptions{
+		ContentType: "application/pdf",
+		Filename:    fmt.Sprintf("ticket-%s.pdf", ticket.TicketCode),
+	}, c.Query("redirect") == "true")
+}
@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+// maxEventsPageSize limita cuántos eventos se devuelven por página
+const maxEventsPageSize = 100
+
+type EventHandler struct {
+	DB db.EventRepository
+}
+
+func NewEventHandler(db db.EventRepository) *EventHandler {
+	return &EventHandler{DB: db}
+}
+
+func (h *EventHandler) ListEvents(c *gin.Context) {
+	status := c.Query("status")
+	limitStr := c.Query("limit")
+	limit := 10 // default limit
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	if limit > maxEventsPageSize {
+		limit = maxEventsPageSize
+	}
+
+	if status != "" && !model.IsValidEventStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estado de evento inválido", "details": status})
+		return
+	}
+
+	events, nextCursor, err := h.DB.GetEvents(c.Request.Context(), status, limit, c.Query("cursor"))
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cursor de paginación inválido"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo eventos", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":      events,
+		"count":       len(events),
+		"limit":       limit,
+		"next_cursor": nextCursor,
+	})
+}
+
+func (h *EventHandler) GetEvent(c *gin.Context) {
+	eventID := c.Param("id")
+	if eventID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de evento requerido"})
+		return
+	}
+
+	event, err := h.DB.GetEventByID(c.Request.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, db.ErrEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Evento no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event": event})
+}
+
+func (h *EventHandler) CreateEvent(c *gin.Context) {
+	var req model.CreateEventRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de evento inválidos", "details": err.Error()})
+		return
+	}
+
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if req.Status == "" {
+		req.Status = model.EventStatusOpen
+	}
+	if req.HoldMinutes == 0 {
+		req.HoldMinutes = model.DefaultHoldMinutes
+	}
+	if msg := validateEventFields(req.StartTime, req.EndTime, req.Timezone, req.Status); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if req.TicketTemplate != nil {
+		if msg := req.TicketTemplate.Validate(); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	now := time.Now()
+	event := model.Event{
+		ID:                uuid.New(),
+		Name:              req.Name,
+		Venue:             req.Venue,
+		StartTime:         req.StartTime,
+		EndTime:           req.EndTime,
+		Timezone:          req.Timezone,
+		TotalCapacity:     req.TotalCapacity,
+		AvailableCapacity: req.TotalCapacity,
+		HoldMinutes:       req.HoldMinutes,
+		RotatingQR:        req.RotatingQR,
+		LogoKey:           req.LogoKey,
+		TicketTemplate:    req.TicketTemplate,
+		Status:            req.Status,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	if err := h.DB.SaveEvent(c.Request.Context(), event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando evento", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Evento creado con éxito",
+		"event":   event,
+	})
+}
+
+func (h *EventHandler) UpdateEvent(c *gin.Context) {
+	eventID := c.Param("id")
+	if eventID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de evento requerido"})
+		return
+	}
+
+	var req model.UpdateEventRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de actualización inválidos", "details": err.Error()})
+		return
+	}
+
+	event, err := h.DB.GetEventByID(c.Request.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, db.ErrEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Evento no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return
+	}
+
+	if req.Name != "" {
+		event.Name = req.Name
+	}
+	if req.Venue != "" {
+		event.Venue = req.Venue
+	}
+	if req.StartTime != nil {
+		event.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		event.EndTime = *req.EndTime
+	}
+	if req.Timezone != "" {
+		event.Timezone = req.Timezone
+	}
+	if req.Status != "" {
+		event.Status = req.Status
+	}
+	if req.HoldMinutes != nil {
+		if *req.HoldMinutes <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El tiempo de retención debe ser mayor que cero"})
+			return
+		}
+		event.HoldMinutes = *req.HoldMinutes
+	}
+	if req.RotatingQR != nil {
+		event.RotatingQR = *req.RotatingQR
+	}
+	if req.LogoKey != nil {
+		event.LogoKey = *req.LogoKey
+	}
+	if req.TicketTemplate != nil {
+		if msg := req.TicketTemplate.Validate(); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		event.TicketTemplate = req.TicketTemplate
+	}
+	if msg := validateEventFields(event.StartTime, event.EndTime, event.Timezone, event.Status); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	capacityDelta := 0
+	if req.TotalCapacity != nil {
+		if *req.TotalCapacity <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "La capacidad total debe ser mayor que cero"})
+			return
+		}
+		capacityDelta = *req.TotalCapacity - event.TotalCapacity
+	}
+	event.UpdatedAt = time.Now()
+
+	if err := h.DB.UpdateEvent(c.Request.Context(), *event, capacityDelta); err != nil {
+		if errors.Is(err, db.ErrEventCapacityConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "La nueva capacidad es menor que los tickets ya emitidos"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando evento", "details": err.Error()})
+		return
+	}
+
+	updated, err := h.DB.GetEventByID(c.Request.Context(), eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Evento actualizado con éxito",
+		"event":   updated,
+	})
+}
+
+func (h *EventHandler) DeleteEvent(c *gin.Context) {
+	eventID := c.Param("id")
+	if eventID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de evento requerido"})
+		return
+	}
+
+	// La comprobación de que no hay tickets emitidos la hace el repositorio en
+	// la misma escritura que el borrado
+	if err := h.DB.DeleteEvent(c.Request.Context(), eventID); err != nil {
+		if errors.Is(err, db.ErrEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Evento no encontrado"})
+			return
+		}
+		if errors.Is(err, db.ErrEventHasTickets) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "El evento tiene tickets emitidos",
+				"details": "Cancele el evento en lugar de eliminarlo",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando evento", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Evento eliminado con éxito"})
+}
+
+// validateEventFields devuelve un mensaje de error si los campos del evento no son coherentes
+func validateEventFields(start, end time.Time, timezone, status string) string {
+	if !end.After(start) {
+		return "La fecha de fin debe ser posterior a la fecha de inicio"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return "Zona horaria inválida"
+	}
+	if !model.IsValidEventStatus(status) {
+		return "Estado de evento inválido"
+	}
+	return ""
+}
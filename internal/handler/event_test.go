@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for EventHandler
+func TestListEvents_InvalidStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &EventHandler{}
+	r.GET("/events", handler.ListEvents)
+
+	req := httptest.NewRequest(http.MethodGet, "/events?status=unknown", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Estado de evento inválido")
+}
+
+func TestCreateEvent_InvalidJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &EventHandler{}
+	r.POST("/events", handler.CreateEvent)
+
+	req := httptest.NewRequest(http.MethodPost, "/events", bytes.NewBufferString(`invalid json`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Datos de evento inválidos")
+}
+
+func TestCreateEvent_MissingCapacity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &EventHandler{}
+	r.POST("/events", handler.CreateEvent)
+
+	body := `{
+		"name": "Concierto",
+		"venue": "Estadio",
+		"start_time": "2026-12-01T20:00:00Z",
+		"end_time": "2026-12-01T23:00:00Z"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/events", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Datos de evento inválidos")
+}
+
+func TestCreateEvent_EndBeforeStart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &EventHandler{}
+	r.POST("/events", handler.CreateEvent)
+
+	body := `{
+		"name": "Concierto",
+		"venue": "Estadio",
+		"start_time": "2026-12-01T20:00:00Z",
+		"end_time": "2026-12-01T18:00:00Z",
+		"total_capacity": 100
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/events", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "La fecha de fin debe ser posterior")
+}
+
+func TestCreateEvent_InvalidTimezone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &EventHandler{}
+	r.POST("/events", handler.CreateEvent)
+
+	body := `{
+		"name": "Concierto",
+		"venue": "Estadio",
+		"start_time": "2026-12-01T20:00:00Z",
+		"end_time": "2026-12-01T23:00:00Z",
+		"timezone": "Mars/Olympus",
+		"total_capacity": 100
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/events", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Zona horaria inválida")
+}
+
+func TestCreateEvent_ValidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &EventHandler{}
+	r.POST("/events", handler.CreateEvent)
+
+	body := `{
+		"name": "Concierto",
+		"venue": "Estadio",
+		"start_time": "2026-12-01T20:00:00Z",
+		"end_time": "2026-12-01T23:00:00Z",
+		"timezone": "America/Bogota",
+		"total_capacity": 100
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/events", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// This will fail because we don't have the dependencies set up,
+	// but it should pass validation
+	assert.NotEqual(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateEvent_InvalidJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &EventHandler{}
+	r.PUT("/events/:id", handler.UpdateEvent)
+
+	req := httptest.NewRequest(http.MethodPut, "/events/550e8400-e29b-41d4-a716-446655440001", bytes.NewBufferString(`invalid json`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Datos de actualización inválidos")
+}
+
+func TestGetEvent_ValidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &EventHandler{}
+	r.GET("/events/:id", handler.GetEvent)
+
+	req := httptest.NewRequest(http.MethodGet, "/events/550e8400-e29b-41d4-a716-446655440001", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// This will fail because we don't have the dependencies set up,
+	// but it should not be a validation error
+	assert.NotEqual(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeleteEvent_ValidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &EventHandler{}
+	r.DELETE("/events/:id", handler.DeleteEvent)
+
+	req := httptest.NewRequest(http.MethodDelete, "/events/550e8400-e29b-41d4-a716-446655440001", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// This will fail because we don't have the dependencies set up,
+	// but it should not be a validation error
+	assert.NotEqual(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateEvent_InvalidHoldMinutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &EventHandler{}
+	r.PUT("/events/:id", handler.UpdateEvent)
+
+	body := `{"hold_minutes": "quince"}`
+	req := httptest.NewRequest(http.MethodPut, "/events/550e8400-e29b-41d4-a716-446655440001", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Datos de actualización inválidos")
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+)
+
+type HealthHandler struct {
+	Checker *service.HealthChecker
+}
+
+func NewHealthHandler(checker *service.HealthChecker) *HealthHandler {
+	return &HealthHandler{Checker: checker}
+}
+
+// Liveness responde 200 mientras el proceso pueda atender peticiones; no
+// consulta dependencias para que una caída de AWS no reinicie el pod
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": service.HealthStatusOK})
+}
+
+// Readiness responde 200 si todas las dependencias están disponibles y 503 si
+// alguna falla, con el detalle de cada una
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.Checker.Check(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != service.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &HealthHandler{}
+	r.GET("/healthz", handler.Liveness)
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, tc := range []struct {
+		name     string
+		sqsErr   error
+		expected int
+	}{
+		{"ready", nil, http.StatusOK},
+		{"dependency down", errors.New("connection refused"), http.StatusServiceUnavailable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := service.NewHealthChecker()
+			checker.Add("memory", func(ctx context.Context) error { return nil })
+			checker.Add("sqs", func(ctx context.Context) error { return tc.sqsErr })
+
+			r := gin.Default()
+			r.GET("/readyz", NewHealthHandler(checker).Readiness)
+
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expected, w.Code)
+
+			var report service.HealthReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Len(t, report.Dependencies, 2)
+			assert.Equal(t, service.HealthStatusOK, report.Dependencies["memory"].Status)
+		})
+	}
+}
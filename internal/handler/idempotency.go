@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+const (
+	IdempotencyKeyHeader    = "Idempotency-Key"
+	IdempotentReplayHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+	// IdempotencyLease es cuánto puede tardar la petición que tiene una clave en
+	// curso. Si el proceso muere sin liberarla, un reintento la toma pasado ese plazo.
+	IdempotencyLease = time.Minute
+)
+
+// Idempotency hace que los reintentos de una petición con la misma
+// Idempotency-Key devuelvan la primera respuesta en lugar de repetir el efecto.
+// La misma clave con un cuerpo distinto se rechaza con 422. Las peticiones sin
+// cabecera pasan sin cambios.
+func Idempotency(repo db.IdempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key demasiado larga"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Error leyendo el cuerpo de la petición", "details": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// La clave se limita a la ruta para que un mismo valor no choque entre endpoints
+		scopedKey := c.Request.Method + " " + c.FullPath() + " " + key
+		hash := sha256.Sum256(body)
+		now := time.Now()
+
+		record := model.IdempotencyRecord{
+			Key:           scopedKey,
+			RequestHash:   hex.EncodeToString(hash[:]),
+			Status:        model.IdempotencyStatusInProgress,
+			CreatedAt:     now,
+			ExpiresAt:     now.Add(ttl),
+			LockExpiresAt: now.Add(IdempotencyLease),
+		}
+
+		if err := repo.AcquireIdempotencyKey(c.Request.Context(), record); err != nil {
+			if !errors.Is(err, db.ErrIdempotencyKeyExists) {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error registrando Idempotency-Key", "details": err.Error()})
+				return
+			}
+			replayIdempotentResponse(c, repo, record)
+			return
+		}
+
+		// Si el cliente se desconectó la clave igual debe quedar liberada o completada
+		ctx := context.WithoutCancel(c.Request.Context())
+		release := func() {
+			if err := repo.ReleaseIdempotencyKey(ctx, record); err != nil {
+				log.Printf("Error liberando Idempotency-Key %s: %v", key, err)
+			}
+		}
+
+		// Un panic del handler no debe dejar la clave en curso: se libera y se
+		// relanza para que lo atienda el middleware de recuperación
+		defer func() {
+			if r := recover(); r != nil {
+				release()
+				panic(r)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Los errores del servidor no se guardan: el cliente debe poder reintentar
+		if recorder.Status() >= http.StatusInternalServerError {
+			release()
+			return
+		}
+
+		if err := repo.CompleteIdempotencyKey(ctx, record, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.String()); err != nil {
+			log.Printf("Error guardando respuesta para Idempotency-Key %s: %v", key, err)
+		}
+	}
+}
+
+func replayIdempotentResponse(c *gin.Context, repo db.IdempotencyRepository, record model.IdempotencyRecord) {
+	existing, err := repo.GetIdempotencyRecord(c.Request.Context(), record.Key, record.CreatedAt)
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyNotFound) {
+			// La clave venció entre el registro y la lectura
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Idempotency-Key en uso, reintente la petición"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo Idempotency-Key", "details": err.Error()})
+		return
+	}
+
+	if existing.RequestHash != record.RequestHash {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key ya usada con un cuerpo distinto"})
+		return
+	}
+
+	if existing.Status != model.IdempotencyStatusCompleted {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Ya hay una petición en curso con esta Idempotency-Key"})
+		return
+	}
+
+	c.Header(IdempotentReplayHeader, "true")
+	c.Data(existing.StatusCode, existing.ContentType, []byte(existing.ResponseBody))
+	c.Abort()
+}
+
+// responseRecorder copia lo que escribe el handler para poder guardarlo
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+)
+
+// respondPresignedURL entrega una URL prefirmada de key en lugar de pasar el
+// archivo por la API. Con redirect responde 302 a la URL; si no, la devuelve en
+// JSON junto con su vencimiento. expires_in (segundos) permite pedir una
+// vigencia menor que la configurada.
+func respondPresignedURL(c *gin.Context, blobs storage.BlobStore, key string, opts storage.PresignOptions, redirect bool) {
+	if expiresIn := c.Query("expires_in"); expiresIn != "" {
+		seconds, err := strconv.Atoi(expiresIn)
+		if err != nil || seconds <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in debe ser un número de segundos mayor que cero"})
+			return
+		}
+		opts.Expiry = time.Duration(seconds) * time.Second
+	}
+
+	if _, err := blobs.Head(c.Request.Context(), key); err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Archivo no encontrado", "key": key})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error consultando archivo", "details": err.Error()})
+		return
+	}
+
+	url, expiresAt, err := blobs.Presign(c.Request.Context(), key, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando URL de descarga", "details": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	if redirect {
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        url,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	})
+}
@@ -3,31 +3,42 @@ package handler
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
+	"image"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
 	"github.com/jhonathanssegura/ticket-reservation/internal/service"
 	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type QRHandler struct {
-	DB *db.DynamoClient
-	S3 *storage.S3Client
-	QR *service.QRService
+	DB    db.Repository
+	Blobs storage.BlobStore
+	QR    *service.QRService
 }
 
-func NewQRHandler(db *db.DynamoClient, s3 *storage.S3Client) *QRHandler {
+func NewQRHandler(db db.Repository, blobs storage.BlobStore, qr *service.QRService) *QRHandler {
 	return &QRHandler{
-		DB: db,
-		S3: s3,
-		QR: service.NewQRService(),
+		DB:    db,
+		Blobs: blobs,
+		QR:    qr,
 	}
 }
 
+// GetTicketQR dibuja el QR del ticket. Acepta format=png|svg|pdf, size (en px),
+// level (L, M, Q o H) y logo=true para poner el logo del evento en el centro.
 func (h *QRHandler) GetTicketQR(c *gin.Context) {
 	ticketID := c.Param("id")
 	if ticketID == "" {
@@ -35,21 +46,103 @@ func (h *QRHandler) GetTicketQR(c *gin.Context) {
 		return
 	}
 
-	ticket, err := h.DB.GetTicketByID(ticketID)
+	opts, err := parseQROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Opciones de QR inválidas", "details": err.Error()})
+		return
+	}
+
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
 		return
 	}
 
-	qrData, err := h.QR.GenerateTicketQRPNG(ticket.ID, ticket.Email, ticket.TicketCode)
+	if rejectCancelledTicket(c, ticket) {
+		return
+	}
+
+	event, err := findEvent(c.Request.Context(), h.DB, ticket.EventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return
+	}
+
+	if c.Query("logo") == "true" {
+		if event == nil || event.LogoKey == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El evento no tiene logo configurado"})
+			return
+		}
+		logo, err := h.loadLogo(c.Request.Context(), event.LogoKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo logo del evento", "details": err.Error()})
+			return
+		}
+		opts.Logo = logo
+	}
+
+	// Los eventos con QR rotativo reciben el código de la ventana actual, que el
+	// cliente debe volver a pedir cuando venza
+	content := ""
+	if event != nil && event.RotatingQR {
+		var validUntil time.Time
+		content, validUntil = h.QR.Signer.SignRotating(ticket.ID, ticket.EventID, time.Now())
+		c.Header("Cache-Control", "no-store")
+		c.Header("X-QR-Valid-Until", validUntil.UTC().Format(time.RFC3339))
+	} else {
+		content = h.QR.GenerateQRContent(*ticket)
+	}
+
+	_, span := tracing.Start(c.Request.Context(), "qr.render", trace.WithAttributes(
+		attribute.String("ticket.id", ticket.ID.String()),
+		attribute.String("qr.format", opts.Format),
+	))
+	qrData, err := service.RenderQR(content, opts)
+	tracing.End(span, err)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando código QR", "details": err.Error()})
 		return
 	}
 
-	c.Header("Content-Type", "image/png")
-	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=qr-%s.png", ticket.ID))
-	c.Data(http.StatusOK, "image/png", qrData)
+	c.Header("Content-Type", opts.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=qr-%s.%s", ticket.ID, opts.Format))
+	c.Data(http.StatusOK, opts.ContentType(), qrData)
+}
+
+// parseQROptions lee las opciones de dibujo del QR de la query
+func parseQROptions(c *gin.Context) (service.QROptions, error) {
+	opts := service.DefaultQROptions()
+	opts.Format = strings.ToLower(c.DefaultQuery("format", service.QRFormatPNG))
+
+	if sizeStr := c.Query("size"); sizeStr != "" {
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil {
+			return opts, fmt.Errorf("tamaño inválido: %s", sizeStr)
+		}
+		opts.Size = size
+	}
+
+	level, err := service.ParseQRLevel(c.Query("level"))
+	if err != nil {
+		return opts, err
+	}
+	opts.Level = level
+
+	return opts, opts.Validate()
+}
+
+func (h *QRHandler) loadLogo(ctx context.Context, key string) (image.Image, error) {
+	reader, err := h.Blobs.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	logo, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, fmt.Errorf("el logo %s no es una imagen PNG o JPEG válida: %v", key, err)
+	}
+	return logo, nil
 }
 
 func (h *QRHandler) GetTicketQRFromS3(c *gin.Context) {
@@ -59,30 +152,55 @@ func (h *QRHandler) GetTicketQRFromS3(c *gin.Context) {
 		return
 	}
 
-	_, err := h.DB.GetTicketByID(ticketID)
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
 		return
 	}
 
-	qrS3Key := fmt.Sprintf("qrcodes/%s.png", ticketID)
+	if rejectCancelledTicket(c, ticket) {
+		return
+	}
 
-	qrReader, err := h.S3.DownloadTicketFile(context.Background(), qrS3Key)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Código QR no encontrado en S3"})
+	if !h.allowsStaticQR(c, ticket) {
 		return
 	}
-	defer qrReader.Close()
 
-	qrData, err := io.ReadAll(qrReader)
+	// Se redirige a una URL prefirmada para no pasar el archivo por la API
+	respondPresignedURL(c, h.Blobs, service.QRKey(*ticket), qrPresignOptions(ticket), true)
+}
+
+// GetTicketQRURL devuelve una URL prefirmada del QR guardado, o redirige a
+// ella con redirect=true
+func (h *QRHandler) GetTicketQRURL(c *gin.Context) {
+	ticketID := c.Param("id")
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de ticket requerido"})
+		return
+	}
+
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error leyendo archivo QR"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
 		return
 	}
 
-	c.Header("Content-Type", "image/png")
-	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=qr-%s.png", ticketID))
-	c.Data(http.StatusOK, "image/png", qrData)
+	if rejectCancelledTicket(c, ticket) {
+		return
+	}
+
+	if !h.allowsStaticQR(c, ticket) {
+		return
+	}
+
+	respondPresignedURL(c, h.Blobs, service.QRKey(*ticket), qrPresignOptions(ticket), c.Query("redirect") == "true")
+}
+
+func qrPresignOptions(ticket *model.Ticket) storage.PresignOptions {
+	return storage.PresignOptions{
+		ContentType: "image/png",
+		Filename:    fmt.Sprintf("qr-%s.png", ticket.ID),
+	}
 }
 
 func (h *QRHandler) ValidateQR(c *gin.Context) {
@@ -95,52 +213,162 @@ func (h *QRHandler) ValidateQR(c *gin.Context) {
 		return
 	}
 
-	isValid, err := h.QR.ValidateQRContent(req.QRContent)
+	result := h.checkQR(c.Request.Context(), req.QRContent)
+	if result.status != http.StatusOK {
+		body := gin.H{"error": result.reason}
+		if result.failure != "" {
+			body["reason"] = result.failure
+		}
+		if result.details != "" {
+			body["details"] = result.details
+		}
+		c.JSON(result.status, body)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":   true,
+		"ticket":  result.ticket,
+		"message": "Código QR válido",
+	})
+}
+
+// DecodeQR recibe una foto o captura (PNG o JPEG) en el campo "image", extrae
+// el código QR y le aplica la misma validación que ValidateQR. El resultado de
+// la validación va en "valid", "reason" (el motivo del rechazo, como en las
+// métricas) y "message" para que soporte pueda revisar el caso.
+func (h *QRHandler) DecodeQR(c *gin.Context) {
+	// El límite se aplica al cuerpo antes de parsear el multipart, así una
+	// subida enorme se corta sin llegar a escribirse en disco
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxQRImageSize+maxQRFormOverhead)
+	file, err := c.FormFile("image")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Formato QR inválido", "details": err.Error()})
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Imagen demasiado grande", "details": fmt.Sprintf("Máximo %d MB", maxQRImageSize>>20)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Imagen requerida", "details": "Envíe la imagen en el campo 'image'"})
 		return
 	}
 
-	if !isValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Código QR inválido"})
+	if file.Size > maxQRImageSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Imagen demasiado grande", "details": fmt.Sprintf("Máximo %d MB", maxQRImageSize>>20)})
 		return
 	}
 
-	parts := strings.Split(req.QRContent, "|")
-	ticketInfo := make(map[string]string)
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error leyendo imagen", "details": err.Error()})
+		return
+	}
+	defer f.Close()
 
-	for _, part := range parts {
-		if strings.Contains(part, ":") {
-			keyValue := strings.SplitN(part, ":", 2)
-			if len(keyValue) == 2 {
-				ticketInfo[keyValue[0]] = keyValue[1]
-			}
+	content, err := service.DecodeQRImage(f)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnsupportedImage):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Formato de imagen no soportado", "details": "Use PNG o JPEG"})
+		case errors.Is(err, service.ErrImageTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Imagen demasiado grande", "details": fmt.Sprintf("Máximo %d megapíxeles", service.MaxQRImagePixels/1_000_000)})
+		case errors.Is(err, service.ErrQRNotFound):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No se encontró un código QR en la imagen"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Error leyendo imagen", "details": err.Error()})
 		}
+		return
 	}
 
-	ticketID, exists := ticketInfo["TICKET"]
-	if !exists {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de ticket no encontrado en QR"})
+	result := h.checkQR(c.Request.Context(), content)
+	if result.status == http.StatusInternalServerError {
+		c.JSON(result.status, gin.H{"error": result.reason, "details": result.details})
 		return
 	}
 
-	ticket, err := h.DB.GetTicketByID(ticketID)
+	response := gin.H{
+		"qr_content": content,
+		"valid":      result.status == http.StatusOK,
+	}
+	if result.ticket != nil {
+		response["ticket"] = result.ticket
+	}
+	if result.status != http.StatusOK {
+		response["reason"] = result.failure
+		response["message"] = result.reason
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// maxQRImageSize limita el tamaño de las imágenes que acepta DecodeQR
+const maxQRImageSize = 10 << 20
+
+// maxQRFormOverhead es el margen para las cabeceras y separadores del multipart
+const maxQRFormOverhead = 64 << 10
+
+// qrCheck es el resultado de validar el contenido de un QR: status es el código
+// HTTP con el que responde ValidateQR, reason el motivo si no es válido y
+// failure la etiqueta de ese motivo en las métricas
+type qrCheck struct {
+	status  int
+	reason  string
+	details string
+	failure string
+	ticket  *model.Ticket
+}
+
+// checkQR valida el contenido de un QR y cuenta los rechazos por motivo
+func (h *QRHandler) checkQR(ctx context.Context, content string) qrCheck {
+	result := h.validateQR(ctx, content)
+	if result.failure != "" {
+		metrics.QRValidationFailures.WithLabelValues(result.failure).Inc()
+	}
+	return result
+}
+
+// validateQR verifica la firma del QR, busca el ticket y comprueba que corresponda
+// al evento y a su modo de QR y que esté confirmado: un ticket reservado,
+// cancelado o ya usado no es válido para entrar
+func (h *QRHandler) validateQR(ctx context.Context, content string) qrCheck {
+	// La firma se verifica antes de tocar la base de datos
+	claims, err := h.QR.ValidateQRContent(content)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado en base de datos"})
-		return
+		if errors.Is(err, service.ErrQRBadSignature) || errors.Is(err, service.ErrQRUnknownKey) {
+			return qrCheck{status: http.StatusForbidden, reason: "Firma del código QR inválida", failure: metrics.QRFailureBadSignature}
+		}
+		if errors.Is(err, service.ErrQRExpired) {
+			return qrCheck{status: http.StatusForbidden, reason: "Código QR vencido, actualice el código en la app", failure: metrics.QRFailureExpired}
+		}
+		return qrCheck{status: http.StatusBadRequest, reason: "Formato QR inválido", details: err.Error(), failure: metrics.QRFailureMalformed}
 	}
 
-	expectedQRContent := h.QR.GenerateQRContent(ticket.ID, ticket.Email, ticket.TicketCode, "")
-	if req.QRContent != expectedQRContent {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Código QR no coincide con ticket"})
-		return
+	ticket, err := h.DB.GetTicketByID(ctx, claims.TicketID.String())
+	if err != nil {
+		return qrCheck{status: http.StatusNotFound, reason: "Ticket no encontrado en base de datos", failure: metrics.QRFailureTicketNotFound}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"valid":   true,
-		"ticket":  ticket,
-		"message": "Código QR válido",
-	})
+	if ticket.EventID != claims.EventID {
+		return qrCheck{status: http.StatusBadRequest, reason: "Código QR no coincide con ticket", failure: metrics.QRFailureTicketMismatch, ticket: ticket}
+	}
+
+	switch ticket.Status {
+	case model.TicketStatusConfirmed:
+	case model.TicketStatusCancelled:
+		return qrCheck{status: http.StatusConflict, reason: "Ticket cancelado", failure: metrics.QRFailureCancelled, ticket: ticket}
+	case model.TicketStatusUsed:
+		return qrCheck{status: http.StatusConflict, reason: "Ticket ya utilizado", failure: metrics.QRFailureUsed, ticket: ticket}
+	default:
+		return qrCheck{status: http.StatusConflict, reason: "Ticket no confirmado", failure: metrics.QRFailureNotConfirmed, ticket: ticket}
+	}
+
+	event, err := findEvent(ctx, h.DB, ticket.EventID)
+	if err != nil {
+		return qrCheck{status: http.StatusInternalServerError, reason: "Error obteniendo evento", details: err.Error()}
+	}
+	if err := service.CheckEventMode(claims, event); err != nil {
+		return qrCheck{status: http.StatusForbidden, reason: "El evento solo admite QR dinámicos", failure: metrics.QRFailureStaticQR, ticket: ticket}
+	}
+
+	return qrCheck{status: http.StatusOK, ticket: ticket}
 }
 
 func (h *QRHandler) GenerateQRForTicket(c *gin.Context) {
@@ -150,27 +378,77 @@ func (h *QRHandler) GenerateQRForTicket(c *gin.Context) {
 		return
 	}
 
-	ticket, err := h.DB.GetTicketByID(ticketID)
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
 		return
 	}
 
-	qrData, err := h.QR.GenerateTicketQRPNG(ticket.ID, ticket.Email, ticket.TicketCode)
+	if rejectCancelledTicket(c, ticket) {
+		return
+	}
+
+	if !h.allowsStaticQR(c, ticket) {
+		return
+	}
+
+	_, span := tracing.Start(c.Request.Context(), "qr.render", trace.WithAttributes(attribute.String("ticket.id", ticket.ID.String())))
+	qrData, err := h.QR.GenerateTicketQRPNG(*ticket)
+	tracing.End(span, err)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando código QR", "details": err.Error()})
 		return
 	}
 
-	qrS3Key := fmt.Sprintf("qrcodes/%s.png", ticket.ID)
-	if err := h.S3.UploadTicketFile(context.Background(), qrS3Key, bytes.NewReader(qrData)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error subiendo código QR a S3", "details": err.Error()})
+	qrKey := service.QRKey(*ticket)
+	if err := h.Blobs.Put(c.Request.Context(), qrKey, bytes.NewReader(qrData), "image/png"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error guardando código QR", "details": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "Código QR generado y subido exitosamente",
-		"qr_code":   qrS3Key,
+		"qr_code":   qrKey,
 		"ticket_id": ticket.ID,
 	})
 }
+
+// rejectCancelledTicket responde 409 si el ticket está cancelado: sus archivos se
+// borran al cancelarlo y no se deben volver a generar ni entregar
+func rejectCancelledTicket(c *gin.Context, ticket *model.Ticket) bool {
+	if ticket.Status != model.TicketStatusCancelled {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "Ticket cancelado"})
+	return true
+}
+
+// allowsStaticQR responde 409 si el evento del ticket usa QR rotativos, para
+// los que no se guarda un QR estático
+func (h *QRHandler) allowsStaticQR(c *gin.Context, ticket *model.Ticket) bool {
+	event, err := findEvent(c.Request.Context(), h.DB, ticket.EventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return false
+	}
+	if event != nil && event.RotatingQR {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "El evento usa QR dinámicos",
+			"details": fmt.Sprintf("Use GET /api/tickets/%s/qr para obtener el código vigente", ticket.ID),
+		})
+		return false
+	}
+	return true
+}
+
+// findEvent devuelve el evento, o nil si no existe
+func findEvent(ctx context.Context, events db.EventRepository, eventID uuid.UUID) (*model.Event, error) {
+	event, err := events.GetEventByID(ctx, eventID.String())
+	if err != nil {
+		if errors.Is(err, db.ErrEventNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return event, nil
+}
@@ -124,6 +124,21 @@ func TestValidateQR_ValidRequest(t *testing.T) {
 	assert.NotEqual(t, http.StatusBadRequest, w.Code)
 }
 
+func TestDecodeQR_MissingImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &QRHandler{}
+	r.POST("/qr/decode", handler.DecodeQR)
+
+	req := httptest.NewRequest(http.MethodPost, "/qr/decode", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Imagen requerida")
+}
+
 func TestGenerateQRForTicket_MissingID(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
@@ -1,9 +1,10 @@
 package handler
 
 import (
-	"bytes"
-	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -11,25 +12,29 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
 	"github.com/jhonathanssegura/ticket-reservation/internal/model"
 	"github.com/jhonathanssegura/ticket-reservation/internal/queue"
 	"github.com/jhonathanssegura/ticket-reservation/internal/service"
 	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
 )
 
 type ReservationHandler struct {
-	SQS *queue.SQSClient
-	S3  *storage.S3Client
-	DB  *db.DynamoClient
-	QR  *service.QRService
+	SQS       *queue.SQSClient
+	Blobs     storage.BlobStore
+	DB        db.Repository
+	QR        *service.QRService
+	Artifacts *service.TicketArtifactService
 }
 
-func NewReservationHandler(sqs *queue.SQSClient, s3 *storage.S3Client, db *db.DynamoClient) *ReservationHandler {
+func NewReservationHandler(sqs *queue.SQSClient, blobs storage.BlobStore, db db.Repository, qr *service.QRService) *ReservationHandler {
 	return &ReservationHandler{
-		SQS: sqs,
-		S3:  s3,
-		DB:  db,
-		QR:  service.NewQRService(),
+		SQS:       sqs,
+		Blobs:     blobs,
+		DB:        db,
+		QR:        qr,
+		Artifacts: service.NewTicketArtifactService(blobs, qr),
 	}
 }
 
@@ -40,6 +45,7 @@ func (h *ReservationHandler) ReserveTicket(c *gin.Context) {
 		Email     string `json:"email"`
 		UserEmail string `json:"user_email"`
 		Name      string `json:"name"`
+		Seat      string `json:"seat"`
 	}
 
 	if err := c.BindJSON(&req); err != nil {
@@ -52,6 +58,7 @@ func (h *ReservationHandler) ReserveTicket(c *gin.Context) {
 				"email":      "Email válido (opcional si se proporciona user_email)",
 				"user_email": "Email válido (opcional si se proporciona email)",
 				"name":       "Nombre del usuario (opcional, se usa 'Usuario Anónimo' por defecto)",
+				"seat":       "Asiento asignado (opcional, admisión general por defecto)",
 			},
 		})
 		return
@@ -113,9 +120,36 @@ func (h *ReservationHandler) ReserveTicket(c *gin.Context) {
 		userName = "Usuario Anónimo"
 	}
 
+	event, err := h.DB.GetEventByID(c.Request.Context(), eventID.String())
+	if err != nil {
+		if errors.Is(err, db.ErrEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Evento no encontrado",
+				"details": fmt.Sprintf("No existe un evento con id '%s'", eventID),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return
+	}
+
+	if event.Status != model.EventStatusOpen {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "El evento no admite reservas",
+			"details": fmt.Sprintf("El evento está en estado '%s'", event.Status),
+		})
+		return
+	}
+
+	if event.AvailableCapacity <= 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Evento agotado", "event_id": event.ID})
+		return
+	}
+
 	// Generate UUID for ticket
 	ticketID := uuid.New()
 	now := time.Now()
+	expiresAt := now.Add(event.HoldDuration())
 
 	ticket := model.Ticket{
 		ID:         ticketID,
@@ -124,57 +158,27 @@ func (h *ReservationHandler) ReserveTicket(c *gin.Context) {
 		Email:      userEmail,
 		Name:       userName,
 		TicketCode: fmt.Sprintf("TKT-%s", ticketID.String()[:8]),
+		Seat:       req.Seat,
 		Status:     model.TicketStatusReserved,
 		Price:      0.0, // This should be calculated
 		ReservedAt: now,
+		ExpiresAt:  &expiresAt,
 		CreatedAt:  now,
 		UpdatedAt:  now,
 	}
 
-	// Generate QR code for the ticket
-	qrData, err := h.QR.GenerateTicketQRPNG(ticket.ID, ticket.Email, ticket.TicketCode)
+	outbox, err := newReservationOutbox(c, ticket, now)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error generando código QR",
-			"details": err.Error(),
-		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error preparando mensaje de reserva", "details": err.Error()})
 		return
 	}
 
-	qrS3Key := fmt.Sprintf("qrcodes/%s.png", ticket.ID)
-	if err := h.S3.UploadTicketFile(context.Background(), qrS3Key, bytes.NewReader(qrData)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error subiendo código QR a S3",
-			"details": err.Error(),
-		})
-		return
-	}
-
-	ticketContent := fmt.Sprintf(`TICKET INFORMATION
-		==================
-		Ticket ID: %s
-		Event ID: %s
-		User: %s (%s)
-		Ticket Code: %s
-		Status: %s
-		Price: $%.2f
-		Reserved At: %s
-		QR Code: %s
-		`,
-		ticket.ID, ticket.EventID, ticket.Name, ticket.Email,
-		ticket.TicketCode, ticket.Status, ticket.Price,
-		ticket.ReservedAt.Format("2006-01-02 15:04:05"), qrS3Key)
-
-	ticketS3Key := fmt.Sprintf("tickets/%s.txt", ticket.ID)
-	if err := h.S3.UploadTicketFile(context.Background(), ticketS3Key, bytes.NewReader([]byte(ticketContent))); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error subiendo archivo de ticket a S3",
-			"details": err.Error(),
-		})
-		return
-	}
-
-	if err := h.DB.SaveTicket(ticket); err != nil {
+	// El asiento, el ticket y el mensaje de outbox se guardan en la misma transacción
+	if err := h.DB.CreateReservation(c.Request.Context(), ticket, outbox); err != nil {
+		if errors.Is(err, db.ErrEventUnavailable) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Evento agotado", "event_id": event.ID})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":     "Error guardando ticket en base de datos",
 			"details":   err.Error(),
@@ -182,6 +186,15 @@ func (h *ReservationHandler) ReserveTicket(c *gin.Context) {
 		})
 		return
 	}
+	metrics.ReservationsCreated.Inc()
+
+	// Los archivos se suben después de guardar la reserva para que una reserva
+	// fallida no deje archivos huérfanos. Si fallan, el worker los genera al
+	// confirmarla y la reconciliación repone los que falten.
+	qrS3Key, ticketS3Key, err := h.Artifacts.Generate(c.Request.Context(), ticket, event)
+	if err != nil {
+		log.Printf("Error generando archivos de la reserva %s: %v", ticket.ID, err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":     "Ticket reservado con éxito",
@@ -190,6 +203,7 @@ func (h *ReservationHandler) ReserveTicket(c *gin.Context) {
 		"qr_code":     qrS3Key,
 		"ticket_info": map[string]interface{}{
 			"event_id":    ticket.EventID,
+			"event_name":  event.Name,
 			"user_id":     ticket.UserID,
 			"email":       ticket.Email,
 			"name":        ticket.Name,
@@ -197,6 +211,81 @@ func (h *ReservationHandler) ReserveTicket(c *gin.Context) {
 			"status":      ticket.Status,
 			"price":       ticket.Price,
 			"reserved_at": ticket.ReservedAt.Format("2006-01-02 15:04:05"),
+			"expires_at":  expiresAt.Format(time.RFC3339),
 		},
 	})
 }
+
+// newReservationOutbox prepara el mensaje de outbox que CreateReservation guarda
+// junto al ticket y que el worker usa para generar sus archivos y avisar al titular
+func newReservationOutbox(c *gin.Context, ticket model.Ticket, now time.Time) (model.OutboxMessage, error) {
+	outboxID := uuid.New()
+	payload, err := json.Marshal(queue.TicketReservationMessage{
+		MessageID:     outboxID.String(),
+		ReservationID: ticket.ID.String(),
+		UserID:        ticket.UserID.String(),
+		EventID:       ticket.EventID.String(),
+		NumTickets:    1,
+	})
+	if err != nil {
+		return model.OutboxMessage{}, err
+	}
+
+	return model.OutboxMessage{
+		ID:          outboxID,
+		AggregateID: ticket.ID.String(),
+		Payload:     string(payload),
+		Status:      model.OutboxStatusPending,
+		CreatedAt:   now,
+		// El worker continúa la traza de esta petición al publicar y procesar el mensaje
+		TraceContext: tracing.Inject(c.Request.Context()),
+	}, nil
+}
+
+func (h *ReservationHandler) ConfirmReservation(c *gin.Context) {
+	ticketID := c.Param("id")
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de reserva requerido"})
+		return
+	}
+
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reserva no encontrada"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo reserva", "details": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if ticket.Status == model.TicketStatusReserved && ticket.ExpiresAt != nil && !now.Before(*ticket.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{
+			"error":      "La reserva ha expirado",
+			"expires_at": ticket.ExpiresAt.Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := h.DB.TransitionTicketStatus(c.Request.Context(), ticketID, ticket.Status, model.TicketStatusConfirmed, now); err != nil {
+		if errors.Is(err, db.ErrTicketStatusConflict) || errors.Is(err, db.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "La reserva no se puede confirmar",
+				"details": fmt.Sprintf("El ticket está en estado '%s'", ticket.Status),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error confirmando reserva", "details": err.Error()})
+		return
+	}
+
+	ticket.Status = model.TicketStatusConfirmed
+	ticket.ExpiresAt = nil
+	ticket.UpdatedAt = now
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reserva confirmada con éxito",
+		"ticket":  ticket,
+	})
+}
@@ -2,12 +2,19 @@ package handler
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Tests for ReservationHandler
@@ -115,3 +122,62 @@ func TestReserveTicket_ValidRequest(t *testing.T) {
 	assert.NotContains(t, w.Body.String(), "Email requerido")
 	assert.NotContains(t, w.Body.String(), "Formato de email inválido")
 }
+
+// newReservationAPI monta ConfirmReservation sobre la API en memoria
+func newReservationAPI() (*gin.Engine, *db.MemoryRepository) {
+	r, repo := newMemoryAPI()
+	handler := NewReservationHandler(nil, nil, repo, testQRService())
+	r.POST("/reservations/:id/confirm", handler.ConfirmReservation)
+	return r, repo
+}
+
+// saveReservation guarda una reserva con su mensaje de outbox, como
+// ReserveTicket, con el vencimiento indicado
+func saveReservation(t *testing.T, r *gin.Engine, repo *db.MemoryRepository, expiresAt time.Time) model.Ticket {
+	t.Helper()
+	event := createTestEvent(t, r, 10, false)
+	now := time.Now()
+	ticket := model.Ticket{ID: uuid.New(), EventID: event.ID, UserID: uuid.New(), TicketCode: "TCK-TEST", Email: "test@example.com",
+		Status: model.TicketStatusReserved, ReservedAt: now, ExpiresAt: &expiresAt, CreatedAt: now, UpdatedAt: now}
+	outbox := model.OutboxMessage{ID: uuid.New(), AggregateID: ticket.ID.String(), Status: model.OutboxStatusPending, CreatedAt: now}
+	require.NoError(t, repo.CreateReservation(context.Background(), ticket, outbox))
+	return ticket
+}
+
+func TestConfirmReservation_WithinHold(t *testing.T) {
+	r, repo := newReservationAPI()
+	ticket := saveReservation(t, r, repo, time.Now().Add(10*time.Minute))
+
+	w := doJSON(r, http.MethodPost, "/reservations/"+ticket.ID.String()+"/confirm", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Ticket model.Ticket `json:"ticket"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, model.TicketStatusConfirmed, resp.Ticket.Status)
+	assert.Nil(t, resp.Ticket.ExpiresAt)
+
+	stored, err := repo.GetTicketByID(context.Background(), ticket.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusConfirmed, stored.Status)
+
+	// Una reserva ya confirmada no se vuelve a confirmar
+	w = doJSON(r, http.MethodPost, "/reservations/"+ticket.ID.String()+"/confirm", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doJSON(r, http.MethodPost, "/reservations/550e8400-e29b-41d4-a716-446655440101/confirm", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestConfirmReservation_ExpiredHold(t *testing.T) {
+	r, repo := newReservationAPI()
+	ticket := saveReservation(t, r, repo, time.Now().Add(-time.Minute))
+
+	w := doJSON(r, http.MethodPost, "/reservations/"+ticket.ID.String()+"/confirm", "")
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Contains(t, w.Body.String(), "La reserva ha expirado")
+
+	stored, err := repo.GetTicketByID(context.Background(), ticket.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusReserved, stored.Status)
+}
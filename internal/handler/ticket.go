@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,15 +12,22 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
 	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
 )
 
+// maxTicketsPageSize limita cuántos tickets se devuelven por página
+const maxTicketsPageSize = 100
+
 type TicketHandler struct {
-	DB *db.DynamoClient
+	DB db.Repository
+	// Artifacts borra el QR y el PDF de los tickets eliminados o cancelados; puede ser nil
+	Artifacts *service.TicketArtifactService
 }
 
-func NewTicketHandler(db *db.DynamoClient) *TicketHandler {
-	return &TicketHandler{DB: db}
+func NewTicketHandler(db db.Repository, artifacts *service.TicketArtifactService) *TicketHandler {
+	return &TicketHandler{DB: db, Artifacts: artifacts}
 }
 
 func (h *TicketHandler) ListTickets(c *gin.Context) {
@@ -31,17 +40,25 @@ func (h *TicketHandler) ListTickets(c *gin.Context) {
 			limit = l
 		}
 	}
+	if limit > maxTicketsPageSize {
+		limit = maxTicketsPageSize
+	}
 
-	tickets, err := h.DB.GetTickets(userEmail, eventID, limit)
+	tickets, nextCursor, err := h.DB.GetTickets(c.Request.Context(), userEmail, eventID, limit, c.Query("cursor"))
 	if err != nil {
+		if errors.Is(err, db.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cursor de paginación inválido"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo tickets", "details": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"tickets": tickets,
-		"count":   len(tickets),
-		"limit":   limit,
+		"tickets":     tickets,
+		"count":       len(tickets),
+		"limit":       limit,
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -52,7 +69,7 @@ func (h *TicketHandler) GetTicket(c *gin.Context) {
 		return
 	}
 
-	ticket, err := h.DB.GetTicketByID(ticketID)
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
@@ -76,12 +93,29 @@ func (h *TicketHandler) CreateTicket(c *gin.Context) {
 		return
 	}
 
+	eventID, err := uuid.Parse(ticketData.EventID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event ID inválido", "details": err.Error()})
+		return
+	}
+
+	event, err := h.DB.GetEventByID(c.Request.Context(), eventID.String())
+	if err != nil {
+		if errors.Is(err, db.ErrEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Evento no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return
+	}
+
 	ticketID := uuid.New()
 	now := time.Now()
+	expiresAt := now.Add(event.HoldDuration())
 
 	ticket := &model.Ticket{
 		ID:         ticketID,
-		EventID:    uuid.MustParse(ticketData.EventID),
+		EventID:    eventID,
 		UserID:     uuid.New(),
 		Email:      ticketData.Email,
 		Name:       "User Name",
@@ -89,14 +123,32 @@ func (h *TicketHandler) CreateTicket(c *gin.Context) {
 		Status:     model.TicketStatusReserved,
 		Price:      0.0,
 		ReservedAt: now,
+		ExpiresAt:  &expiresAt,
 		CreatedAt:  now,
 		UpdatedAt:  now,
 	}
 
-	if err := h.DB.SaveTicket(*ticket); err != nil {
+	outbox, err := newReservationOutbox(c, *ticket, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error preparando mensaje de reserva", "details": err.Error()})
+		return
+	}
+
+	// Igual que una reserva: el asiento solo se descuenta si el evento está
+	// abierto y le queda capacidad, en la misma transacción que guarda el ticket
+	if err := h.DB.CreateReservation(c.Request.Context(), *ticket, outbox); err != nil {
+		if errors.Is(err, db.ErrEventUnavailable) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "El evento no admite reservas",
+				"details": "El evento no está abierto o no tiene capacidad disponible",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando ticket", "details": err.Error()})
 		return
 	}
+	ticket.SeatHeld = true
+	metrics.ReservationsCreated.Inc()
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Ticket creado con éxito",
@@ -111,7 +163,27 @@ func (h *TicketHandler) UpdateTicket(c *gin.Context) {
 		return
 	}
 
-	existingTicket, err := h.DB.GetTicketByID(ticketID)
+	var updateData struct {
+		Email   string `json:"email"`
+		EventID string `json:"event_id"`
+	}
+
+	if err := c.BindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de actualización inválidos", "details": err.Error()})
+		return
+	}
+
+	var eventID uuid.UUID
+	if updateData.EventID != "" {
+		parsed, err := uuid.Parse(updateData.EventID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Event ID inválido", "details": err.Error()})
+			return
+		}
+		eventID = parsed
+	}
+
+	existingTicket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
@@ -121,29 +193,33 @@ func (h *TicketHandler) UpdateTicket(c *gin.Context) {
 		return
 	}
 
-	var updateData struct {
-		Email   string `json:"email"`
-		EventID string `json:"event_id"`
+	if len(model.AllowedTicketTransitions(existingTicket.Status)) == 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "El ticket no se puede modificar",
+			"details": fmt.Sprintf("El ticket está en estado final '%s'", existingTicket.Status),
+		})
+		return
 	}
 
-	if err := c.BindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de actualización inválidos", "details": err.Error()})
+	// Cambiar de evento movería el asiento; para eso se cancela y se reserva de nuevo
+	if updateData.EventID != "" && eventID != existingTicket.EventID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "El evento del ticket no se puede cambiar",
+			"details": "Cancele el ticket y reserve uno nuevo en el otro evento",
+		})
 		return
 	}
 
 	if updateData.Email != "" {
 		existingTicket.Email = updateData.Email
 	}
-	if updateData.EventID != "" {
-		eventID, err := uuid.Parse(updateData.EventID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Event ID inválido", "details": err.Error()})
+	existingTicket.UpdatedAt = time.Now()
+
+	if err := h.DB.UpdateTicket(c.Request.Context(), *existingTicket, existingTicket.Status); err != nil {
+		if errors.Is(err, db.ErrTicketStatusConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "El ticket cambió de estado durante la actualización"})
 			return
 		}
-		existingTicket.EventID = eventID
-	}
-
-	if err := h.DB.SaveTicket(*existingTicket); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando ticket", "details": err.Error()})
 		return
 	}
@@ -154,6 +230,80 @@ func (h *TicketHandler) UpdateTicket(c *gin.Context) {
 	})
 }
 
+func (h *TicketHandler) ConfirmTicket(c *gin.Context) {
+	h.transitionTicket(c, model.TicketStatusConfirmed)
+}
+
+func (h *TicketHandler) CancelTicket(c *gin.Context) {
+	h.transitionTicket(c, model.TicketStatusCancelled)
+}
+
+// transitionTicket aplica el cambio de estado validándolo contra la tabla de
+// transiciones y contra el estado actual en DynamoDB.
+func (h *TicketHandler) transitionTicket(c *gin.Context, to string) {
+	ticketID := c.Param("id")
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de ticket requerido"})
+		return
+	}
+
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo ticket", "details": err.Error()})
+		return
+	}
+
+	if !model.CanTransitionTicket(ticket.Status, to) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Transición de estado no permitida",
+			"details": fmt.Sprintf("No se puede pasar de '%s' a '%s'", ticket.Status, to),
+			"allowed": model.AllowedTicketTransitions(ticket.Status),
+		})
+		return
+	}
+
+	now := time.Now()
+	if to == model.TicketStatusCancelled {
+		// El cambio de estado y la devolución del asiento van en la misma escritura
+		err = h.DB.CancelTicket(c.Request.Context(), *ticket, now)
+	} else {
+		err = h.DB.TransitionTicketStatus(c.Request.Context(), ticketID, ticket.Status, to, now)
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrTicketStatusConflict) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "El ticket cambió de estado o su reserva expiró",
+				"details": fmt.Sprintf("Se esperaba el estado '%s'", ticket.Status),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error cambiando estado del ticket", "details": err.Error()})
+		return
+	}
+
+	if to == model.TicketStatusCancelled {
+		h.removeArtifacts(c, *ticket)
+		ticket.SeatHeld = false
+	}
+
+	previous := ticket.Status
+	ticket.Status = to
+	ticket.UpdatedAt = now
+	if to == model.TicketStatusConfirmed {
+		ticket.ExpiresAt = nil
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Estado del ticket actualizado",
+		"previous_status": previous,
+		"ticket":          ticket,
+	})
+}
+
 func (h *TicketHandler) DeleteTicket(c *gin.Context) {
 	ticketID := c.Param("id")
 	if ticketID == "" {
@@ -161,7 +311,7 @@ func (h *TicketHandler) DeleteTicket(c *gin.Context) {
 		return
 	}
 
-	_, err := h.DB.GetTicketByID(ticketID)
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
@@ -171,14 +321,30 @@ func (h *TicketHandler) DeleteTicket(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.DeleteTicket(ticketID); err != nil {
+	if err := h.DB.DeleteTicket(c.Request.Context(), *ticket); err != nil {
+		if errors.Is(err, db.ErrTicketStatusConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "El ticket cambió de estado durante la eliminación"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando ticket", "details": err.Error()})
 		return
 	}
+	h.removeArtifacts(c, *ticket)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Ticket eliminado con éxito"})
 }
 
+// removeArtifacts borra los archivos del ticket. Si falla solo se registra: el
+// ticket ya cambió en la base y la reconciliación limpia lo que quede.
+func (h *TicketHandler) removeArtifacts(c *gin.Context, ticket model.Ticket) {
+	if h.Artifacts == nil {
+		return
+	}
+	if err := h.Artifacts.Remove(c.Request.Context(), ticket); err != nil {
+		log.Printf("Error borrando archivos del ticket %s: %v", ticket.ID, err)
+	}
+}
+
 func generateTicketID() string {
 	return "TICKET-" + strconv.FormatInt(time.Now().UnixNano(), 10)
 }
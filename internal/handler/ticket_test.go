@@ -2,12 +2,16 @@ package handler
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Tests for TicketHandler
@@ -206,3 +210,58 @@ func TestDeleteTicket_ValidID(t *testing.T) {
 	// but it should not be a validation error
 	assert.NotEqual(t, http.StatusBadRequest, w.Code)
 }
+
+// transitionResponse es la respuesta de los endpoints de cambio de estado
+type transitionResponse struct {
+	PreviousStatus string       `json:"previous_status"`
+	Ticket         model.Ticket `json:"ticket"`
+}
+
+func TestCancelTicket_ValidID(t *testing.T) {
+	r, repo := newMemoryAPI()
+	ticket := createConfirmedTicketForEvent(t, r, false)
+
+	w := doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/cancel", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp transitionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, model.TicketStatusConfirmed, resp.PreviousStatus)
+	assert.Equal(t, model.TicketStatusCancelled, resp.Ticket.Status)
+
+	stored, err := repo.GetTicketByID(context.Background(), ticket.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusCancelled, stored.Status)
+
+	// Un ticket cancelado no puede volver a cancelarse ni confirmarse
+	w = doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/cancel", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/confirm", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Transición de estado no permitida")
+}
+
+func TestConfirmTicket_ValidID(t *testing.T) {
+	r, repo := newMemoryAPI()
+	ticket := createTestTicket(t, r)
+	require.Equal(t, model.TicketStatusReserved, ticket.Status)
+
+	w := doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/confirm", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp transitionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, model.TicketStatusReserved, resp.PreviousStatus)
+	assert.Equal(t, model.TicketStatusConfirmed, resp.Ticket.Status)
+	assert.Nil(t, resp.Ticket.ExpiresAt)
+
+	stored, err := repo.GetTicketByID(context.Background(), ticket.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusConfirmed, stored.Status)
+
+	// Confirmar dos veces no es una transición válida
+	w = doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/confirm", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"allowed"`)
+
+	w = doJSON(r, http.MethodPost, "/tickets/550e8400-e29b-41d4-a716-446655440003/confirm", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+)
+
+// WalletHandler genera los pases de Apple Wallet y Google Wallet de un ticket.
+// Passes o Google pueden ser nil si la wallet correspondiente no está configurada.
+type WalletHandler struct {
+	DB     db.Repository
+	QR     *service.QRService
+	Passes *service.PassSigner
+	Google *service.GoogleWalletIssuer
+}
+
+func NewWalletHandler(db db.Repository, qr *service.QRService, passes *service.PassSigner, google *service.GoogleWalletIssuer) *WalletHandler {
+	return &WalletHandler{
+		DB:     db,
+		QR:     qr,
+		Passes: passes,
+		Google: google,
+	}
+}
+
+// GetApplePass devuelve el .pkpass firmado del ticket
+func (h *WalletHandler) GetApplePass(c *gin.Context) {
+	ticketID := c.Param("id")
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de ticket requerido"})
+		return
+	}
+
+	if h.Passes == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Apple Wallet no está configurado"})
+		return
+	}
+
+	ticket, event, ok := h.walletTicket(c, ticketID)
+	if !ok {
+		return
+	}
+
+	pass, err := h.Passes.BuildPass(*ticket, event, h.QR.GenerateQRContent(*ticket))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando pase de Apple Wallet", "details": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=ticket-%s.pkpass", ticket.TicketCode))
+	c.Data(http.StatusOK, "application/vnd.apple.pkpass", pass)
+}
+
+// GetGoogleWalletJWT devuelve el JWT y el enlace para guardar el ticket en Google Wallet
+func (h *WalletHandler) GetGoogleWalletJWT(c *gin.Context) {
+	ticketID := c.Param("id")
+	if ticketID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de ticket requerido"})
+		return
+	}
+
+	if h.Google == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Google Wallet no está configurado"})
+		return
+	}
+
+	ticket, event, ok := h.walletTicket(c, ticketID)
+	if !ok {
+		return
+	}
+
+	token, err := h.Google.SaveJWT(*ticket, event, h.QR.GenerateQRContent(*ticket), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generando pase de Google Wallet", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jwt":      token,
+		"save_url": service.GoogleWalletSaveURL + token,
+	})
+}
+
+// walletTicket carga el ticket y su evento. Los pases llevan un QR estático, así
+// que no se emiten para tickets cancelados ni para eventos con QR rotativo.
+func (h *WalletHandler) walletTicket(c *gin.Context, ticketID string) (*model.Ticket, *model.Event, bool) {
+	ticket, err := h.DB.GetTicketByID(c.Request.Context(), ticketID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
+		return nil, nil, false
+	}
+
+	if rejectCancelledTicket(c, ticket) {
+		return nil, nil, false
+	}
+
+	event, err := findEvent(c.Request.Context(), h.DB, ticket.EventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo evento", "details": err.Error()})
+		return nil, nil, false
+	}
+	if event != nil && event.RotatingQR {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "El evento usa QR dinámicos",
+			"details": fmt.Sprintf("Use GET /api/tickets/%s/qr para obtener el código vigente", ticket.ID),
+		})
+		return nil, nil, false
+	}
+
+	return ticket, event, true
+}
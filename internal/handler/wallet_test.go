@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for WalletHandler
+func TestGetApplePass_NotConfigured(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &WalletHandler{}
+	r.GET("/tickets/:id/pkpass", handler.GetApplePass)
+
+	req := httptest.NewRequest(http.MethodGet, "/tickets/550e8400-e29b-41d4-a716-446655440003/pkpass", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "Apple Wallet no está configurado")
+}
+
+func TestGetGoogleWalletJWT_NotConfigured(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	handler := &WalletHandler{}
+	r.GET("/tickets/:id/google-wallet", handler.GetGoogleWalletJWT)
+
+	req := httptest.NewRequest(http.MethodGet, "/tickets/550e8400-e29b-41d4-a716-446655440003/google-wallet", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "Google Wallet no está configurado")
+}
+
+// testGoogleWalletIssuer crea un emisor con una cuenta de servicio temporal
+func testGoogleWalletIssuer(t *testing.T) *service.GoogleWalletIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	account, err := json.Marshal(map[string]string{
+		"client_email": "wallet@example.iam.gserviceaccount.com",
+		"private_key":  string(keyPEM),
+	})
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "service-account.json")
+	require.NoError(t, os.WriteFile(keyFile, account, 0o600))
+
+	issuer, err := service.LoadGoogleWalletIssuer(keyFile, "3388000000000000000", []string{"https://tickets.example.com"})
+	require.NoError(t, err)
+	return issuer
+}
+
+func TestGetGoogleWalletJWT_Configured(t *testing.T) {
+	r, repo := newMemoryAPI()
+	issuer := testGoogleWalletIssuer(t)
+	handler := NewWalletHandler(repo, testQRService(), nil, issuer)
+	r.GET("/tickets/:id/google-wallet", handler.GetGoogleWalletJWT)
+
+	ticket := createConfirmedTicketForEvent(t, r, false)
+
+	w := doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/google-wallet", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		JWT     string `json:"jwt"`
+		SaveURL string `json:"save_url"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, service.GoogleWalletSaveURL+resp.JWT, resp.SaveURL)
+
+	parts := strings.Split(resp.JWT, ".")
+	require.Len(t, parts, 3)
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(issuer.PublicKey(), crypto.SHA256, digest[:], signature))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims struct {
+		Payload struct {
+			EventTicketObjects []struct {
+				ID      string `json:"id"`
+				Barcode struct {
+					Value string `json:"value"`
+				} `json:"barcode"`
+			} `json:"eventTicketObjects"`
+		} `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	require.Len(t, claims.Payload.EventTicketObjects, 1)
+	object := claims.Payload.EventTicketObjects[0]
+	assert.Equal(t, issuer.ObjectID(ticket), object.ID)
+
+	// El código de barras del pase es el QR firmado del ticket
+	qrClaims, err := testQRService().ValidateQRContent(object.Barcode.Value)
+	require.NoError(t, err)
+	assert.Equal(t, ticket.ID, qrClaims.TicketID)
+
+	// Un ticket cancelado no tiene pase
+	require.Equal(t, http.StatusOK, doJSON(r, http.MethodPost, "/tickets/"+ticket.ID.String()+"/cancel", "").Code)
+	w = doJSON(r, http.MethodGet, "/tickets/"+ticket.ID.String()+"/google-wallet", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
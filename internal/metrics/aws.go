@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+const awsMiddlewareID = "TicketMetrics"
+
+// AddAWSMiddleware agrega a un cliente del SDK la medición de cada operación.
+// Se usa en APIOptions: o.APIOptions = append(o.APIOptions, metrics.AddAWSMiddleware)
+func AddAWSMiddleware(stack *middleware.Stack) error {
+	// Después de los middlewares de inicialización del SDK el contexto ya tiene
+	// el servicio y la operación; la medición incluye los reintentos.
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc(awsMiddlewareID,
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			service := awsmiddleware.GetServiceID(ctx)
+			operation := awsmiddleware.GetOperationName(ctx)
+
+			start := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+
+			AWSCalls.WithLabelValues(service, operation).Inc()
+			AWSCallDuration.WithLabelValues(service, operation).Observe(time.Since(start).Seconds())
+			if err != nil {
+				AWSCallErrors.WithLabelValues(service, operation).Inc()
+			}
+			return out, metadata, err
+		}), middleware.After)
+}
+
+// RemoveAWSMiddleware quita la medición de una operación que no llega a AWS,
+// como las URLs prefirmadas, que se firman en local.
+func RemoveAWSMiddleware(stack *middleware.Stack) error {
+	if _, ok := stack.Initialize.Get(awsMiddlewareID); !ok {
+		return nil
+	}
+	_, err := stack.Initialize.Remove(awsMiddlewareID)
+	return err
+}
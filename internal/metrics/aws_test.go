@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAWSMiddleware(t *testing.T) {
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		if fail {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"Requested resource not found"}`))
+			return
+		}
+		w.Write([]byte(`{"Table":{"TableName":"tickets","TableStatus":"ACTIVE"}}`))
+	}))
+	defer server.Close()
+
+	client := dynamodb.New(dynamodb.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		Credentials:      credentials.NewStaticCredentialsProvider("test", "test", ""),
+		RetryMaxAttempts: 1,
+		APIOptions:       []func(*middleware.Stack) error{AddAWSMiddleware},
+	})
+
+	AWSCalls.Reset()
+	AWSCallErrors.Reset()
+	AWSCallDuration.Reset()
+
+	_, err := client.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{TableName: aws.String("tickets")})
+	require.NoError(t, err)
+	fail = true
+	_, err = client.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{TableName: aws.String("tickets")})
+	require.Error(t, err)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(AWSCalls.WithLabelValues("DynamoDB", "DescribeTable")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(AWSCallErrors.WithLabelValues("DynamoDB", "DescribeTable")))
+	assert.Equal(t, 2, histogramCount(t, AWSCallDuration.WithLabelValues("DynamoDB", "DescribeTable")))
+}
+
+// histogramCount devuelve cuántas observaciones tiene una serie de un histograma
+func histogramCount(t *testing.T, observer prometheus.Observer) int {
+	t.Helper()
+	var m dto.Metric
+	require.NoError(t, observer.(prometheus.Metric).Write(&m))
+	return int(m.GetHistogram().GetSampleCount())
+}
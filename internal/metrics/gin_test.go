@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware_LabelsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/api/tickets/:id", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket no encontrado"})
+	})
+
+	HTTPRequestDuration.Reset()
+	for _, path := range []string{"/api/tickets/a", "/api/tickets/b", "/no-existe"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Los dos IDs comparten serie; la ruta desconocida va a "unmatched"
+	assert.Equal(t, 2, testutil.CollectAndCount(HTTPRequestDuration))
+	assert.Equal(t, 2, histogramCount(t, HTTPRequestDuration.WithLabelValues(http.MethodGet, "/api/tickets/:id", "404")))
+	assert.Equal(t, 1, histogramCount(t, HTTPRequestDuration.WithLabelValues(http.MethodGet, "unmatched", "404")))
+}
@@ -0,0 +1,94 @@
+// Package metrics define las métricas de Prometheus de la API y del worker:
+// peticiones HTTP, llamadas a AWS y eventos de negocio. Se publican en /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ticket_reservation"
+
+// Motivos de QRValidationFailures
+const (
+	QRFailureBadSignature   = "bad_signature"
+	QRFailureExpired        = "expired"
+	QRFailureMalformed      = "malformed"
+	QRFailureTicketNotFound = "ticket_not_found"
+	QRFailureTicketMismatch = "ticket_mismatch"
+	QRFailureWrongEvent     = "wrong_event"
+	QRFailureStaticQR       = "static_qr_not_allowed"
+	QRFailureCancelled      = "cancelled"
+	QRFailureUsed           = "used"
+	QRFailureNotConfirmed   = "not_confirmed"
+)
+
+// Modos de CheckIns
+const (
+	CheckInOnline  = "online"
+	CheckInOffline = "offline"
+)
+
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duración de las peticiones HTTP por ruta y código de estado.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	AWSCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "aws",
+		Name:      "calls_total",
+		Help:      "Llamadas a AWS por servicio y operación.",
+	}, []string{"service", "operation"})
+
+	AWSCallErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "aws",
+		Name:      "call_errors_total",
+		Help:      "Llamadas a AWS que devolvieron error, por servicio y operación.",
+	}, []string{"service", "operation"})
+
+	AWSCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "aws",
+		Name:      "call_duration_seconds",
+		Help:      "Duración de las llamadas a AWS, reintentos incluidos.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 20},
+	}, []string{"service", "operation"})
+
+	ReservationsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reservations_created_total",
+		Help:      "Reservas creadas.",
+	})
+
+	CheckIns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checkins_total",
+		Help:      "Escaneos de check-in por modo (online u offline) y resultado.",
+	}, []string{"mode", "result"})
+
+	QRValidationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "qr_validation_failures_total",
+		Help:      "Códigos QR rechazados por motivo.",
+	}, []string{"reason"})
+
+	Tickets = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tickets",
+		Help:      "Tickets por evento y estado, según el último recuento.",
+	}, []string{"event_id", "status"})
+)
+
+// Handler expone las métricas registradas en formato Prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Event struct {
+	ID                uuid.UUID       `json:"id" db:"id"`
+	Name              string          `json:"name" db:"name"`
+	Venue             string          `json:"venue" db:"venue"`
+	StartTime         time.Time       `json:"start_time" db:"start_time"`
+	EndTime           time.Time       `json:"end_time" db:"end_time"`
+	Timezone          string          `json:"timezone" db:"timezone"`
+	TotalCapacity     int             `json:"total_capacity" db:"total_capacity"`
+	AvailableCapacity int             `json:"available_capacity" db:"available_capacity"`
+	HoldMinutes       int             `json:"hold_minutes" db:"hold_minutes"`
+	RotatingQR        bool            `json:"rotating_qr" db:"rotating_qr"`
+	LogoKey           string          `json:"logo_key,omitempty" db:"logo_key"`
+	TicketTemplate    *TicketTemplate `json:"ticket_template,omitempty" db:"ticket_template"`
+	Status            string          `json:"status" db:"status"`
+	CreatedAt         time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+type CreateEventRequest struct {
+	Name           string          `json:"name" binding:"required"`
+	Venue          string          `json:"venue" binding:"required"`
+	StartTime      time.Time       `json:"start_time" binding:"required"`
+	EndTime        time.Time       `json:"end_time" binding:"required"`
+	Timezone       string          `json:"timezone"`
+	TotalCapacity  int             `json:"total_capacity" binding:"required,gt=0"`
+	HoldMinutes    int             `json:"hold_minutes" binding:"omitempty,gt=0"`
+	RotatingQR     bool            `json:"rotating_qr"`
+	LogoKey        string          `json:"logo_key"`
+	TicketTemplate *TicketTemplate `json:"ticket_template"`
+	Status         string          `json:"status"`
+}
+
+type UpdateEventRequest struct {
+	Name           string          `json:"name"`
+	Venue          string          `json:"venue"`
+	StartTime      *time.Time      `json:"start_time"`
+	EndTime        *time.Time      `json:"end_time"`
+	Timezone       string          `json:"timezone"`
+	TotalCapacity  *int            `json:"total_capacity"`
+	HoldMinutes    *int            `json:"hold_minutes"`
+	RotatingQR     *bool           `json:"rotating_qr"`
+	LogoKey        *string         `json:"logo_key"`
+	TicketTemplate *TicketTemplate `json:"ticket_template"`
+	Status         string          `json:"status"`
+}
+
+// TicketTemplate configura cómo se imprime el PDF de los tickets de un evento
+type TicketTemplate struct {
+	Layout      string `json:"layout"`
+	PaperSize   string `json:"paper_size"`
+	AccentColor string `json:"accent_color"`
+	FooterText  string `json:"footer_text,omitempty"`
+}
+
+const (
+	TicketLayoutStandard = "standard"
+	TicketLayoutCompact  = "compact"
+
+	PaperSizeA4     = "A4"
+	PaperSizeLetter = "Letter"
+)
+
+// DefaultTicketTemplate es la plantilla que se usa si el evento no define otra
+func DefaultTicketTemplate() TicketTemplate {
+	return TicketTemplate{
+		Layout:      TicketLayoutStandard,
+		PaperSize:   PaperSizeA4,
+		AccentColor: "#1F3A93",
+	}
+}
+
+// Validate completa los valores vacíos con los de la plantilla por defecto y
+// devuelve un mensaje si algún valor no es válido
+func (t *TicketTemplate) Validate() string {
+	defaults := DefaultTicketTemplate()
+	if t.Layout == "" {
+		t.Layout = defaults.Layout
+	}
+	if t.PaperSize == "" {
+		t.PaperSize = defaults.PaperSize
+	}
+	if t.AccentColor == "" {
+		t.AccentColor = defaults.AccentColor
+	}
+
+	if t.Layout != TicketLayoutStandard && t.Layout != TicketLayoutCompact {
+		return "Diseño de ticket inválido, use 'standard' o 'compact'"
+	}
+	if t.PaperSize != PaperSizeA4 && t.PaperSize != PaperSizeLetter {
+		return "Tamaño de papel inválido, use 'A4' o 'Letter'"
+	}
+	if _, _, _, ok := t.RGB(); !ok {
+		return "Color de acento inválido, use el formato #RRGGBB"
+	}
+	return ""
+}
+
+// RGB devuelve el color de acento como componentes RGB
+func (t *TicketTemplate) RGB() (r, g, b int, ok bool) {
+	if len(t.AccentColor) != 7 || t.AccentColor[0] != '#' {
+		return 0, 0, 0, false
+	}
+	if _, err := fmt.Sscanf(t.AccentColor[1:], "%02x%02x%02x", &r, &g, &b); err != nil {
+		return 0, 0, 0, false
+	}
+	return r, g, b, true
+}
+
+// DefaultHoldMinutes es el tiempo que una reserva bloquea el asiento si el evento no define otro
+const DefaultHoldMinutes = 15
+
+const (
+	EventStatusOpen      = "open"
+	EventStatusClosed    = "closed"
+	EventStatusCancelled = "cancelled"
+)
+
+// IsValidEventStatus indica si el estado recibido es uno de los estados de evento conocidos
+func IsValidEventStatus(status string) bool {
+	switch status {
+	case EventStatusOpen, EventStatusClosed, EventStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// HoldDuration devuelve cuánto tiempo se mantiene una reserva antes de expirar
+func (e *Event) HoldDuration() time.Duration {
+	if e.HoldMinutes <= 0 {
+		return DefaultHoldMinutes * time.Minute
+	}
+	return time.Duration(e.HoldMinutes) * time.Minute
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// IdempotencyRecord guarda la respuesta de una petición identificada por su
+// Idempotency-Key para devolverla tal cual si el cliente la reintenta.
+type IdempotencyRecord struct {
+	Key          string    `json:"key" db:"id"`
+	RequestHash  string    `json:"request_hash" db:"request_hash"`
+	Status       string    `json:"status" db:"status"`
+	StatusCode   int       `json:"status_code,omitempty" db:"status_code"`
+	ContentType  string    `json:"content_type,omitempty" db:"content_type"`
+	ResponseBody string    `json:"response_body,omitempty" db:"response_body"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	// LockExpiresAt es el fin del plazo de la petición que tiene la clave en
+	// curso; vencido, un reintento puede tomarla. También identifica al dueño.
+	LockExpiresAt time.Time `json:"lock_expires_at,omitempty" db:"lock_expires_at"`
+}
+
+const (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+)
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMessage es un mensaje pendiente de publicar en la cola. Se escribe en la
+// misma transacción que el ticket para que ambos no puedan divergir.
+type OutboxMessage struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	AggregateID string     `json:"aggregate_id" db:"aggregate_id"`
+	Payload     string     `json:"payload" db:"payload"`
+	Status      string     `json:"status" db:"status"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	SentAt      *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty" db:"processed_at"`
+	// ExpiresAt es cuándo se borra un mensaje ya procesado (TTL de DynamoDB)
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	// LastError es el motivo por el que se apartó un mensaje fallido
+	LastError string `json:"last_error,omitempty" db:"last_error"`
+	// TraceContext es el contexto de traza de la petición que creó el mensaje,
+	// para que su publicación y su procesamiento sigan la misma traza
+	TraceContext map[string]string `json:"trace_context,omitempty" db:"trace_context"`
+}
+
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusSent      = "sent"
+	OutboxStatusProcessed = "processed"
+	// OutboxStatusFailed es un mensaje que agotó sus intentos o no se puede
+	// publicar; queda guardado para revisarlo pero el relay ya no lo toma
+	OutboxStatusFailed = "failed"
+)
+
+// OutboxRetention es cuánto se conserva un mensaje después de procesarse
+const OutboxRetention = 7 * 24 * time.Hour
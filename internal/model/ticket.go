@@ -7,17 +7,23 @@ import (
 )
 
 type Ticket struct {
-	ID          uuid.UUID  `json:"id" db:"id"`
-	EventID     uuid.UUID  `json:"event_id" db:"event_id"`
-	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
-	Email       string     `json:"email" db:"email"`
-	Name        string     `json:"name" db:"name"`
-	TicketCode  string     `json:"ticket_code" db:"ticket_code"`
-	Status      string     `json:"status" db:"status"`
+	ID         uuid.UUID `json:"id" db:"id"`
+	EventID    uuid.UUID `json:"event_id" db:"event_id"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id"`
+	Email      string    `json:"email" db:"email"`
+	Name       string    `json:"name" db:"name"`
+	TicketCode string    `json:"ticket_code" db:"ticket_code"`
+	Seat       string    `json:"seat,omitempty" db:"seat"`
+	Status     string    `json:"status" db:"status"`
+	// SeatHeld indica que el ticket descontó un asiento del evento y debe
+	// devolverlo si se cancela o se elimina
+	SeatHeld    bool       `json:"seat_held" db:"seat_held"`
 	Price       float64    `json:"price" db:"price"`
 	ReservedAt  time.Time  `json:"reserved_at" db:"reserved_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
 	CheckedInAt *time.Time `json:"checked_in_at,omitempty" db:"checked_in_at"`
 	CheckedInBy *uuid.UUID `json:"checked_in_by,omitempty" db:"checked_in_by"`
+	CheckInGate string     `json:"check_in_gate,omitempty" db:"check_in_gate"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
 }
@@ -35,3 +41,33 @@ const (
 	TicketStatusCancelled = "cancelled"
 	TicketStatusUsed      = "used"
 )
+
+// ticketTransitions define los cambios de estado permitidos para un ticket.
+// cancelled y used son estados finales.
+var ticketTransitions = map[string][]string{
+	TicketStatusReserved:  {TicketStatusConfirmed, TicketStatusCancelled},
+	TicketStatusConfirmed: {TicketStatusUsed, TicketStatusCancelled},
+	TicketStatusCancelled: {},
+	TicketStatusUsed:      {},
+}
+
+// IsValidTicketStatus indica si el estado recibido es uno de los estados de ticket conocidos
+func IsValidTicketStatus(status string) bool {
+	_, ok := ticketTransitions[status]
+	return ok
+}
+
+// CanTransitionTicket indica si un ticket puede pasar del estado from al estado to
+func CanTransitionTicket(from, to string) bool {
+	for _, allowed := range ticketTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedTicketTransitions devuelve los estados a los que puede pasar un ticket desde from
+func AllowedTicketTransitions(from string) []string {
+	return append([]string{}, ticketTransitions[from]...)
+}
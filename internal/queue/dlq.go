@@ -0,0 +1,274 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead-letter message not found")
+
+const (
+	failureReasonAttribute     = "failure_reason"
+	originalMessageIDAttribute = "original_message_id"
+	// maxDeadLetterScans limita cuántas lecturas se hacen al buscar en la DLQ,
+	// ya que SQS no permite obtener un mensaje por su ID.
+	maxDeadLetterScans = 20
+	// deadLetterWaitSeconds es la espera de long polling al leer la DLQ: una
+	// lectura vacía significa que no quedan mensajes visibles y no que SQS
+	// consultó solo una parte de sus servidores
+	deadLetterWaitSeconds = 5
+)
+
+// DeadLetterMessage es un mensaje de la cola de mensajes fallidos
+type DeadLetterMessage struct {
+	MessageID         string    `json:"message_id"`
+	OriginalMessageID string    `json:"original_message_id,omitempty"`
+	Body              string    `json:"body"`
+	FailureReason     string    `json:"failure_reason,omitempty"`
+	ReceiveCount      int       `json:"receive_count"`
+	SentAt            time.Time `json:"sent_at"`
+	receiptHandle     string
+}
+
+// DeadLetterQueue son las operaciones de administración de la DLQ que expone la API
+type DeadLetterQueue interface {
+	PeekDeadLetters(ctx context.Context, max int) ([]DeadLetterMessage, error)
+	GetDeadLetter(ctx context.Context, messageID string) (*DeadLetterMessage, error)
+	RedriveDeadLetter(ctx context.Context, messageID string) error
+	RedriveAllDeadLetters(ctx context.Context, max int) (int, error)
+	PurgeDeadLetters(ctx context.Context) error
+}
+
+// SendToDeadLetter mueve el cuerpo de un mensaje a la DLQ junto con el motivo del fallo
+func (s *SQSClient) SendToDeadLetter(ctx context.Context, delivery ReservationDelivery, reason string) error {
+	ctx, cancel := s.callContext(ctx, 0)
+	defer cancel()
+
+	if s.DeadLetterURL == "" {
+		return errors.New("dead-letter queue not configured")
+	}
+
+	_, err := s.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(s.DeadLetterURL),
+		MessageBody: aws.String(delivery.Body),
+		MessageAttributes: withTraceContext(ctx, map[string]types.MessageAttributeValue{
+			failureReasonAttribute: {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(reason),
+			},
+			originalMessageIDAttribute: {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(delivery.MessageID),
+			},
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("error sending message to dead-letter queue: %w", err)
+	}
+	return nil
+}
+
+// PeekDeadLetters lista hasta max mensajes de la DLQ sin consumirlos
+func (s *SQSClient) PeekDeadLetters(ctx context.Context, max int) ([]DeadLetterMessage, error) {
+	seen := make(map[string]bool)
+	var messages []DeadLetterMessage
+
+	for i := 0; i < maxDeadLetterScans && len(messages) < max; i++ {
+		batch, err := s.receiveDeadLetters(ctx, 10, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		added := 0
+		for _, m := range batch {
+			if seen[m.MessageID] || len(messages) >= max {
+				continue
+			}
+			seen[m.MessageID] = true
+			messages = append(messages, m)
+			added++
+		}
+		if added == 0 {
+			break
+		}
+	}
+	return messages, nil
+}
+
+// GetDeadLetter busca un mensaje de la DLQ por su ID sin consumirlo
+func (s *SQSClient) GetDeadLetter(ctx context.Context, messageID string) (*DeadLetterMessage, error) {
+	for i := 0; i < maxDeadLetterScans; i++ {
+		batch, err := s.receiveDeadLetters(ctx, 10, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, m := range batch {
+			if m.MessageID == messageID {
+				return &m, nil
+			}
+		}
+	}
+	return nil, ErrDeadLetterNotFound
+}
+
+// RedriveDeadLetter devuelve a la cola principal el mensaje de la DLQ con el ID indicado
+func (s *SQSClient) RedriveDeadLetter(ctx context.Context, messageID string) error {
+	for i := 0; i < maxDeadLetterScans; i++ {
+		batch, err := s.receiveDeadLetters(ctx, 10, 30)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var found *DeadLetterMessage
+		for j := range batch {
+			if batch[j].MessageID == messageID {
+				found = &batch[j]
+				continue
+			}
+			s.releaseDeadLetter(ctx, batch[j])
+		}
+		if found != nil {
+			return s.redrive(ctx, *found)
+		}
+	}
+	return ErrDeadLetterNotFound
+}
+
+// RedriveAllDeadLetters devuelve a la cola principal hasta max mensajes de la
+// DLQ. Se detiene en la primera lectura vacía, así un mensaje que vuelve a
+// fallar mientras tanto no mantiene el bucle andando indefinidamente.
+func (s *SQSClient) RedriveAllDeadLetters(ctx context.Context, max int) (int, error) {
+	redriven := 0
+	for redriven < max {
+		batch, err := s.receiveDeadLetters(ctx, int32(min(10, max-redriven)), 30)
+		if err != nil {
+			return redriven, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, m := range batch {
+			if err := s.redrive(ctx, m); err != nil {
+				return redriven, err
+			}
+			redriven++
+		}
+	}
+	return redriven, nil
+}
+
+// PurgeDeadLetters elimina todos los mensajes de la DLQ
+func (s *SQSClient) PurgeDeadLetters(ctx context.Context) error {
+	ctx, cancel := s.callContext(ctx, 0)
+	defer cancel()
+
+	if s.DeadLetterURL == "" {
+		return errors.New("dead-letter queue not configured")
+	}
+
+	_, err := s.Client.PurgeQueue(ctx, &sqs.PurgeQueueInput{
+		QueueUrl: aws.String(s.DeadLetterURL),
+	})
+	if err != nil {
+		return fmt.Errorf("error purging dead-letter queue: %w", err)
+	}
+	return nil
+}
+
+func (s *SQSClient) redrive(ctx context.Context, m DeadLetterMessage) error {
+	ctx, cancel := s.callContext(ctx, 0)
+	defer cancel()
+
+	_, err := s.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:          aws.String(s.QueueURL),
+		MessageBody:       aws.String(m.Body),
+		MessageAttributes: withTraceContext(ctx, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("error redriving dead-letter message %s: %w", m.MessageID, err)
+	}
+
+	_, err = s.Client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(s.DeadLetterURL),
+		ReceiptHandle: aws.String(m.receiptHandle),
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting redriven dead-letter message %s: %w", m.MessageID, err)
+	}
+	return nil
+}
+
+// releaseDeadLetter vuelve a hacer visible un mensaje que se leyó pero no se redirigió
+func (s *SQSClient) releaseDeadLetter(ctx context.Context, m DeadLetterMessage) {
+	ctx, cancel := s.callContext(ctx, 0)
+	defer cancel()
+
+	_, _ = s.Client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(s.DeadLetterURL),
+		ReceiptHandle:     aws.String(m.receiptHandle),
+		VisibilityTimeout: 0,
+	})
+}
+
+func (s *SQSClient) receiveDeadLetters(ctx context.Context, maxMessages, visibilityTimeout int32) ([]DeadLetterMessage, error) {
+	ctx, cancel := s.callContext(ctx, deadLetterWaitSeconds*time.Second)
+	defer cancel()
+
+	if s.DeadLetterURL == "" {
+		return nil, errors.New("dead-letter queue not configured")
+	}
+
+	resp, err := s.Client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:              aws.String(s.DeadLetterURL),
+		MaxNumberOfMessages:   maxMessages,
+		WaitTimeSeconds:       deadLetterWaitSeconds,
+		VisibilityTimeout:     visibilityTimeout,
+		MessageAttributeNames: []string{"All"},
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameApproximateReceiveCount,
+			types.MessageSystemAttributeNameSentTimestamp,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error receiving dead-letter messages: %w", err)
+	}
+
+	messages := make([]DeadLetterMessage, 0, len(resp.Messages))
+	for _, m := range resp.Messages {
+		dl := DeadLetterMessage{
+			MessageID:     aws.ToString(m.MessageId),
+			Body:          aws.ToString(m.Body),
+			receiptHandle: aws.ToString(m.ReceiptHandle),
+		}
+		if attr, ok := m.MessageAttributes[failureReasonAttribute]; ok {
+			dl.FailureReason = aws.ToString(attr.StringValue)
+		}
+		if attr, ok := m.MessageAttributes[originalMessageIDAttribute]; ok {
+			dl.OriginalMessageID = aws.ToString(attr.StringValue)
+		}
+		if count, err := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)]); err == nil {
+			dl.ReceiveCount = count
+		}
+		if millis, err := strconv.ParseInt(m.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64); err == nil {
+			dl.SentAt = time.UnixMilli(millis)
+		}
+		messages = append(messages, dl)
+	}
+	return messages, nil
+}
@@ -4,32 +4,77 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
 type TicketReservationMessage struct {
+	MessageID     string `json:"message_id,omitempty"`
 	ReservationID string `json:"reservation_id"`
 	UserID        string `json:"user_id"`
 	EventID       string `json:"event_id"`
 	NumTickets    int    `json:"num_tickets"`
 }
 
+// ReservationDelivery es un mensaje recibido junto con el receipt handle
+// necesario para borrarlo de la cola una vez procesado. Si el cuerpo no se pudo
+// decodificar, DecodeErr lo indica y Body conserva el contenido original.
+type ReservationDelivery struct {
+	MessageID     string
+	ReceiptHandle string
+	ReceiveCount  int
+	Body          string
+	Message       TicketReservationMessage
+	DecodeErr     error
+	// TraceContext son los atributos de propagación de la traza (traceparent, ...)
+	TraceContext map[string]string
+}
+
+// ReservationQueue son las operaciones con que el worker consume la cola de reservas
+type ReservationQueue interface {
+	ReceiveReservationMessages(ctx context.Context, maxMessages int32) ([]ReservationDelivery, error)
+	DeleteReservationMessage(ctx context.Context, receiptHandle string) error
+	ExtendVisibility(ctx context.Context, receiptHandle string, seconds int32) error
+	SendToDeadLetter(ctx context.Context, delivery ReservationDelivery, reason string) error
+}
+
+// receiveWaitSeconds es la espera de long polling al recibir de la cola principal
+const receiveWaitSeconds = 10
+
 type SQSClient struct {
-	Client   *sqs.Client
-	QueueURL string
+	Client            *sqs.Client
+	QueueURL          string
+	DeadLetterURL     string
+	VisibilityTimeout int32
+	// Timeout limita cada llamada a SQS; la recepción suma la espera de long polling
+	Timeout time.Duration
+}
+
+// callContext deriva del contexto del llamador el contexto de una llamada a SQS
+func (s *SQSClient) callContext(ctx context.Context, wait time.Duration) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.Timeout+wait)
 }
 
 func (s *SQSClient) SendReservationMessage(ctx context.Context, msg TicketReservationMessage) error {
+	ctx, cancel := s.callContext(ctx, 0)
+	defer cancel()
+
 	body, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("error marshaling SQS message: %w", err)
 	}
 
 	_, err = s.Client.SendMessage(ctx, &sqs.SendMessageInput{
-		QueueUrl:    aws.String(s.QueueURL),
-		MessageBody: aws.String(string(body)),
+		QueueUrl:          aws.String(s.QueueURL),
+		MessageBody:       aws.String(string(body)),
+		MessageAttributes: withTraceContext(ctx, nil),
 	})
 	if err != nil {
 		return fmt.Errorf("error sending SQS message: %w", err)
@@ -37,22 +82,95 @@ func (s *SQSClient) SendReservationMessage(ctx context.Context, msg TicketReserv
 	return nil
 }
 
-func (s *SQSClient) ReceiveReservationMessages(ctx context.Context, maxMessages int32) ([]TicketReservationMessage, error) {
+func (s *SQSClient) ReceiveReservationMessages(ctx context.Context, maxMessages int32) ([]ReservationDelivery, error) {
+	ctx, cancel := s.callContext(ctx, receiveWaitSeconds*time.Second)
+	defer cancel()
+
 	resp, err := s.Client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
-		QueueUrl:            aws.String(s.QueueURL),
-		MaxNumberOfMessages: maxMessages,
-		WaitTimeSeconds:     10,
+		QueueUrl:              aws.String(s.QueueURL),
+		MaxNumberOfMessages:   maxMessages,
+		WaitTimeSeconds:       receiveWaitSeconds,
+		VisibilityTimeout:     s.VisibilityTimeout,
+		MessageAttributeNames: traceAttributeNames(),
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameApproximateReceiveCount,
+		},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error receiving SQS messages: %w", err)
 	}
 
-	var messages []TicketReservationMessage
+	var deliveries []ReservationDelivery
 	for _, m := range resp.Messages {
-		var msg TicketReservationMessage
-		if err := json.Unmarshal([]byte(*m.Body), &msg); err == nil {
-			messages = append(messages, msg)
+		delivery := ReservationDelivery{
+			MessageID:     aws.ToString(m.MessageId),
+			ReceiptHandle: aws.ToString(m.ReceiptHandle),
+			Body:          aws.ToString(m.Body),
+		}
+		if count, err := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)]); err == nil {
+			delivery.ReceiveCount = count
+		}
+		if len(m.MessageAttributes) > 0 {
+			delivery.TraceContext = make(map[string]string, len(m.MessageAttributes))
+			for name, attr := range m.MessageAttributes {
+				delivery.TraceContext[name] = aws.ToString(attr.StringValue)
+			}
+		}
+		if err := json.Unmarshal([]byte(delivery.Body), &delivery.Message); err != nil {
+			delivery.DecodeErr = fmt.Errorf("invalid message body: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// Ping lee los atributos de la cola principal y, si está configurada, de la DLQ
+func (s *SQSClient) Ping(ctx context.Context) error {
+	ctx, cancel := s.callContext(ctx, 0)
+	defer cancel()
+
+	for _, queueURL := range []string{s.QueueURL, s.DeadLetterURL} {
+		if queueURL == "" {
+			continue
+		}
+		_, err := s.Client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+			QueueUrl:       aws.String(queueURL),
+			AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameQueueArn},
+		})
+		if err != nil {
+			return fmt.Errorf("error consultando cola %s: %w", queueURL, err)
 		}
 	}
-	return messages, nil
+	return nil
+}
+
+// ExtendVisibility amplía el tiempo durante el cual el mensaje permanece oculto
+// para otros consumidores mientras se procesa.
+func (s *SQSClient) ExtendVisibility(ctx context.Context, receiptHandle string, seconds int32) error {
+	ctx, cancel := s.callContext(ctx, 0)
+	defer cancel()
+
+	_, err := s.Client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(s.QueueURL),
+		ReceiptHandle:     aws.String(receiptHandle),
+		VisibilityTimeout: seconds,
+	})
+	if err != nil {
+		return fmt.Errorf("error extending SQS message visibility: %w", err)
+	}
+	return nil
+}
+
+func (s *SQSClient) DeleteReservationMessage(ctx context.Context, receiptHandle string) error {
+	ctx, cancel := s.callContext(ctx, 0)
+	defer cancel()
+
+	_, err := s.Client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(s.QueueURL),
+		ReceiptHandle: aws.String(receiptHandle),
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting SQS message: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
+	"go.opentelemetry.io/otel"
+)
+
+// attributeCarrier guarda el contexto de traza (traceparent, tracestate y
+// baggage) en los atributos de un mensaje de SQS
+type attributeCarrier map[string]types.MessageAttributeValue
+
+func (c attributeCarrier) Get(key string) string {
+	if attr, ok := c[key]; ok {
+		return aws.ToString(attr.StringValue)
+	}
+	return ""
+}
+
+func (c attributeCarrier) Set(key, value string) {
+	c[key] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+}
+
+func (c attributeCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// withTraceContext agrega a attributes el contexto de traza de ctx. Devuelve nil
+// si no hay nada que enviar, porque SQS rechaza un mapa de atributos vacío.
+func withTraceContext(ctx context.Context, attributes map[string]types.MessageAttributeValue) map[string]types.MessageAttributeValue {
+	if attributes == nil {
+		attributes = map[string]types.MessageAttributeValue{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, attributeCarrier(attributes))
+	if len(attributes) == 0 {
+		return nil
+	}
+	return attributes
+}
+
+// traceAttributeNames son los atributos que hay que pedir al recibir para
+// reconstruir el contexto de traza
+func traceAttributeNames() []string {
+	return otel.GetTextMapPropagator().Fields()
+}
+
+// ContextWithTrace devuelve ctx con el contexto de traza que traía el mensaje,
+// para que su procesamiento continúe la traza de quien lo publicó
+func (d ReservationDelivery) ContextWithTrace(ctx context.Context) context.Context {
+	return tracing.Extract(ctx, d.TraceContext)
+}
@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceContextRoundTrip(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	// Los atributos propios del mensaje se conservan
+	attributes := withTraceContext(ctx, map[string]types.MessageAttributeValue{
+		failureReasonAttribute: {DataType: aws.String("String"), StringValue: aws.String("timeout")},
+	})
+	assert.Len(t, attributes, 2)
+	assert.Equal(t, "timeout", aws.ToString(attributes[failureReasonAttribute].StringValue))
+	assert.Equal(t, []string{"traceparent", "tracestate"}, traceAttributeNames())
+
+	delivery := ReservationDelivery{TraceContext: map[string]string{
+		"traceparent": aws.ToString(attributes["traceparent"].StringValue),
+	}}
+	received := trace.SpanContextFromContext(delivery.ContextWithTrace(context.Background()))
+	assert.Equal(t, traceID, received.TraceID())
+	assert.Equal(t, spanID, received.SpanID())
+	assert.True(t, received.IsRemote())
+
+	// Sin traza activa no se envían atributos: SQS rechaza un mapa vacío
+	assert.Nil(t, withTraceContext(context.Background(), nil))
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TicketArtifactService genera y guarda los archivos asociados a un ticket
+type TicketArtifactService struct {
+	Blobs storage.BlobStore
+	QR    *QRService
+}
+
+// NewTicketArtifactService crea una nueva instancia del servicio de artefactos
+func NewTicketArtifactService(blobs storage.BlobStore, qr *QRService) *TicketArtifactService {
+	return &TicketArtifactService{Blobs: blobs, QR: qr}
+}
+
+// QRKey devuelve la clave del código QR de un ticket
+func QRKey(ticket model.Ticket) string {
+	return fmt.Sprintf("qrcodes/%s.png", ticket.ID)
+}
+
+// TicketFileKey devuelve la clave del PDF imprimible de un ticket
+func TicketFileKey(ticket model.Ticket) string {
+	return fmt.Sprintf("tickets/%s.pdf", ticket.ID)
+}
+
+// Generate genera el código QR y el PDF del ticket y los guarda en el almacenamiento.
+// Las claves son deterministas, así que volver a generarlos sobrescribe la versión anterior.
+// Si el evento usa QR rotativos no se guarda un QR estático: qrKey queda vacío y
+// el código se obtiene en el momento desde GET /api/tickets/:id/qr.
+func (s *TicketArtifactService) Generate(ctx context.Context, ticket model.Ticket, event *model.Event) (qrKey, ticketKey string, err error) {
+	var qrData []byte
+	if event == nil || !event.RotatingQR {
+		qrData, err = s.renderQR(ctx, ticket)
+		if err != nil {
+			return "", "", err
+		}
+
+		qrKey = QRKey(ticket)
+		if err := s.Blobs.Put(ctx, qrKey, bytes.NewReader(qrData), "image/png"); err != nil {
+			return "", "", fmt.Errorf("error guardando código QR: %w", err)
+		}
+	}
+
+	pdfData, err := s.renderPDF(ctx, ticket, event, qrData)
+	if err != nil {
+		return "", "", err
+	}
+
+	ticketKey = TicketFileKey(ticket)
+	if err := s.Blobs.Put(ctx, ticketKey, bytes.NewReader(pdfData), "application/pdf"); err != nil {
+		return "", "", fmt.Errorf("error guardando PDF del ticket: %w", err)
+	}
+
+	return qrKey, ticketKey, nil
+}
+
+// TicketPDF devuelve el PDF guardado. Si todavía no existe (tickets emitidos
+// antes de los PDF o subidas fallidas) lo genera y lo guarda en ese momento.
+func (s *TicketArtifactService) TicketPDF(ctx context.Context, ticket model.Ticket, event *model.Event) ([]byte, error) {
+	if body, err := s.Blobs.Get(ctx, TicketFileKey(ticket)); err == nil {
+		defer body.Close()
+		return io.ReadAll(body)
+	} else if !errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, err
+	}
+
+	var qrData []byte
+	if event == nil || !event.RotatingQR {
+		data, err := s.renderQR(ctx, ticket)
+		if err != nil {
+			return nil, err
+		}
+		qrData = data
+	}
+
+	pdfData, err := s.renderPDF(ctx, ticket, event, qrData)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Blobs.Put(ctx, TicketFileKey(ticket), bytes.NewReader(pdfData), "application/pdf"); err != nil {
+		return nil, fmt.Errorf("error guardando PDF del ticket: %w", err)
+	}
+	return pdfData, nil
+}
+
+// renderQR genera el PNG del QR del ticket en su propio span
+func (s *TicketArtifactService) renderQR(ctx context.Context, ticket model.Ticket) (data []byte, err error) {
+	_, span := tracing.Start(ctx, "qr.render", trace.WithAttributes(attribute.String("ticket.id", ticket.ID.String())))
+	defer func() { tracing.End(span, err) }()
+
+	data, err = s.QR.GenerateTicketQRPNG(ticket)
+	if err != nil {
+		return nil, fmt.Errorf("error generando código QR: %w", err)
+	}
+	return data, nil
+}
+
+// renderPDF genera el PDF del ticket en su propio span
+func (s *TicketArtifactService) renderPDF(ctx context.Context, ticket model.Ticket, event *model.Event, qrData []byte) (data []byte, err error) {
+	_, span := tracing.Start(ctx, "ticket.pdf.render", trace.WithAttributes(attribute.String("ticket.id", ticket.ID.String())))
+	defer func() { tracing.End(span, err) }()
+
+	return RenderTicketPDF(ticket, event, qrData)
+}
+
+// Remove borra el QR y el PDF del ticket. Se usa al eliminar o cancelar un
+// ticket para que sus archivos dejen de poder descargarse.
+func (s *TicketArtifactService) Remove(ctx context.Context, ticket model.Ticket) error {
+	var errs []error
+	for _, key := range []string{QRKey(ticket), TicketFileKey(ticket)} {
+		if err := s.Blobs.Delete(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+// checkinBundleVersion identifica el formato del manifiesto del bundle
+const checkinBundleVersion = 1
+
+var ErrBundleBadSignature = errors.New("invalid check-in bundle signature")
+
+// CheckinBundle es la lista de tickets admitidos para un evento que los lectores
+// descargan para validar sin conexión. Payload es el manifiesto JSON en base64 y
+// Signature su firma Ed25519: el lector verifica la firma sobre los bytes
+// decodificados antes de interpretarlos.
+type CheckinBundle struct {
+	KeyID     string `json:"key_id"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// CheckinManifest es el contenido firmado del bundle. Entries lleva el hash
+// (SHA-256 truncado a 16 bytes, base64url) del contenido de cada QR admitido, no
+// los IDs: sin la clave de firma de los QR no se puede fabricar uno que coincida.
+type CheckinManifest struct {
+	Version     int       `json:"version"`
+	EventID     uuid.UUID `json:"event_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	ValidUntil  time.Time `json:"valid_until"`
+	Count       int       `json:"count"`
+	Entries     []string  `json:"entries"`
+}
+
+// BundleSigner firma los bundles de check-in con Ed25519. A diferencia de los QR
+// se usa una clave asimétrica porque los lectores solo deben poder verificar.
+type BundleSigner struct {
+	KeyID      string
+	privateKey ed25519.PrivateKey
+}
+
+// NewBundleSigner crea un firmador a partir de una semilla Ed25519 de 32 bytes
+func NewBundleSigner(keyID string, seed []byte) (*BundleSigner, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("ID de clave de bundle requerido")
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("la clave de bundle debe tener %d bytes", ed25519.SeedSize)
+	}
+	return &BundleSigner{KeyID: keyID, privateKey: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// LoadBundleSignerFromConfig construye el firmador con la semilla de cfg. Si no
+// hay ninguna genera una clave temporal.
+func LoadBundleSignerFromConfig(cfg config.CheckinConfig) (*BundleSigner, error) {
+	if cfg.BundleKey == "" {
+		log.Println("⚠️  CHECKIN_BUNDLE_KEY no definida: se usa una clave temporal para firmar los bundles de check-in")
+		seed := make([]byte, ed25519.SeedSize)
+		if _, err := rand.Read(seed); err != nil {
+			return nil, fmt.Errorf("error generando clave de bundle: %v", err)
+		}
+		return NewBundleSigner("dev", seed)
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(cfg.BundleKey)
+	if err != nil {
+		return nil, fmt.Errorf("CHECKIN_BUNDLE_KEY no es base64 válido: %v", err)
+	}
+	return NewBundleSigner(cfg.BundleKeyID, seed)
+}
+
+// PublicKey devuelve la clave pública que los lectores usan para verificar
+func (s *BundleSigner) PublicKey() ed25519.PublicKey {
+	return s.privateKey.Public().(ed25519.PublicKey)
+}
+
+// BuildCheckinBundle arma y firma el bundle con los tickets confirmados del evento.
+// Cada ticket aporta una entrada por cada clave de QR configurada, de modo que los
+// QR emitidos antes de una rotación siguen validando sin conexión.
+func (s *BundleSigner) BuildCheckinBundle(event model.Event, tickets []model.Ticket, qr *QRService, now time.Time) (*CheckinBundle, *CheckinManifest, error) {
+	manifest := &CheckinManifest{
+		Version:     checkinBundleVersion,
+		EventID:     event.ID,
+		GeneratedAt: now.UTC(),
+		ValidUntil:  event.EndTime.UTC(),
+		Entries:     []string{},
+	}
+
+	for _, ticket := range tickets {
+		if ticket.EventID != event.ID || ticket.Status != model.TicketStatusConfirmed {
+			continue
+		}
+		for _, content := range qr.Signer.SignAll(ticket.ID, ticket.EventID) {
+			manifest.Entries = append(manifest.Entries, BundleEntry(content))
+		}
+		manifest.Count++
+	}
+
+	payload, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error serializando bundle: %v", err)
+	}
+
+	return &CheckinBundle{
+		KeyID:     s.KeyID,
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(s.privateKey, payload)),
+	}, manifest, nil
+}
+
+// VerifyCheckinBundle comprueba la firma del bundle y devuelve su manifiesto.
+// Es lo mismo que hace un lector antes de usar la lista.
+func VerifyCheckinBundle(bundle CheckinBundle, publicKey ed25519.PublicKey) (*CheckinManifest, error) {
+	payload, err := base64.StdEncoding.DecodeString(bundle.Payload)
+	if err != nil {
+		return nil, ErrBundleBadSignature
+	}
+	signature, err := base64.StdEncoding.DecodeString(bundle.Signature)
+	if err != nil || !ed25519.Verify(publicKey, payload, signature) {
+		return nil, ErrBundleBadSignature
+	}
+
+	var manifest CheckinManifest
+	if err := json.Unmarshal(payload, &manifest); err != nil {
+		return nil, fmt.Errorf("error leyendo bundle: %v", err)
+	}
+	return &manifest, nil
+}
+
+// BundleEntry calcula la entrada del bundle para el contenido de un QR
+func BundleEntry(qrContent string) string {
+	sum := sha256.Sum256([]byte(qrContent))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+
+	// DefaultHealthTimeout es el tiempo máximo de cada comprobación de dependencia
+	DefaultHealthTimeout = 2 * time.Second
+	// DefaultHealthCacheTTL es cuánto se reutiliza el último resultado, para que
+	// los sondeos frecuentes del orquestador no golpeen a AWS en cada petición
+	DefaultHealthCacheTTL = 5 * time.Second
+)
+
+// HealthCheckFunc comprueba una dependencia; devuelve nil si está disponible
+type HealthCheckFunc func(ctx context.Context) error
+
+// DependencyHealth es el resultado de comprobar una dependencia
+type DependencyHealth struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HealthReport es el estado de todas las dependencias. Status es "ok" solo si
+// todas lo están.
+type HealthReport struct {
+	Status       string                      `json:"status"`
+	CheckedAt    time.Time                   `json:"checked_at"`
+	Dependencies map[string]DependencyHealth `json:"dependencies"`
+}
+
+// HealthChecker ejecuta en paralelo las comprobaciones registradas, cada una con
+// Timeout, y guarda el resultado durante CacheTTL
+type HealthChecker struct {
+	Timeout  time.Duration
+	CacheTTL time.Duration
+
+	names  []string
+	checks map[string]HealthCheckFunc
+
+	mu     sync.Mutex
+	cached *HealthReport
+}
+
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{
+		Timeout:  DefaultHealthTimeout,
+		CacheTTL: DefaultHealthCacheTTL,
+		checks:   make(map[string]HealthCheckFunc),
+	}
+}
+
+// Add registra la comprobación de una dependencia con el nombre que aparece en el reporte
+func (h *HealthChecker) Add(name string, check HealthCheckFunc) {
+	if _, ok := h.checks[name]; !ok {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+}
+
+// Check devuelve el último reporte si sigue vigente o comprueba todas las
+// dependencias. Las peticiones concurrentes esperan al mismo resultado.
+func (h *HealthChecker) Check(ctx context.Context) HealthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && time.Since(h.cached.CheckedAt) < h.CacheTTL {
+		return *h.cached
+	}
+
+	report := h.run(ctx)
+	h.cached = &report
+	return report
+}
+
+func (h *HealthChecker) run(ctx context.Context) HealthReport {
+	// La comprobación no depende de la petición que la disparó: su resultado se
+	// comparte con las siguientes
+	ctx = context.WithoutCancel(ctx)
+
+	results := make([]DependencyHealth, len(h.names))
+	var wg sync.WaitGroup
+	for i, name := range h.names {
+		wg.Add(1)
+		go func(i int, check HealthCheckFunc) {
+			defer wg.Done()
+			results[i] = h.checkDependency(ctx, check)
+		}(i, h.checks[name])
+	}
+	wg.Wait()
+
+	report := HealthReport{
+		Status:       HealthStatusOK,
+		CheckedAt:    time.Now(),
+		Dependencies: make(map[string]DependencyHealth, len(h.names)),
+	}
+	for i, name := range h.names {
+		report.Dependencies[name] = results[i]
+		if results[i].Status != HealthStatusOK {
+			report.Status = HealthStatusUnavailable
+		}
+	}
+	return report
+}
+
+func (h *HealthChecker) checkDependency(ctx context.Context, check HealthCheckFunc) DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := DependencyHealth{
+		Status:    HealthStatusOK,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		result.Status = HealthStatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
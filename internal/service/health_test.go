@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthChecker_ReportsEachDependency(t *testing.T) {
+	checker := NewHealthChecker()
+	checker.Add("dynamodb", func(ctx context.Context) error { return nil })
+	checker.Add("s3", func(ctx context.Context) error { return errors.New("NoSuchBucket") })
+
+	report := checker.Check(context.Background())
+	assert.Equal(t, HealthStatusUnavailable, report.Status)
+	assert.Equal(t, HealthStatusOK, report.Dependencies["dynamodb"].Status)
+	assert.Equal(t, HealthStatusUnavailable, report.Dependencies["s3"].Status)
+	assert.Equal(t, "NoSuchBucket", report.Dependencies["s3"].Error)
+}
+
+func TestHealthChecker_TimesOutSlowDependency(t *testing.T) {
+	checker := NewHealthChecker()
+	checker.Timeout = 20 * time.Millisecond
+	checker.Add("sqs", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := checker.Check(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, HealthStatusUnavailable, report.Dependencies["sqs"].Status)
+	assert.Contains(t, report.Dependencies["sqs"].Error, "deadline exceeded")
+}
+
+func TestHealthChecker_CachesResult(t *testing.T) {
+	var calls atomic.Int32
+	checker := NewHealthChecker()
+	checker.Add("dynamodb", func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	first := checker.Check(context.Background())
+	second := checker.Check(context.Background())
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, first.CheckedAt, second.CheckedAt)
+
+	checker.CacheTTL = 0
+	checker.Check(context.Background())
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestHealthChecker_IgnoresCallerCancellation(t *testing.T) {
+	checker := NewHealthChecker()
+	checker.Add("dynamodb", func(ctx context.Context) error { return ctx.Err() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// El resultado se comparte, así que un cliente que se desconecta no lo contamina
+	report := checker.Check(ctx)
+	assert.Equal(t, HealthStatusOK, report.Status)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+)
+
+// HoldSweeper cancela periódicamente las reservas vencidas y devuelve sus asientos al evento
+type HoldSweeper struct {
+	DB        db.Repository
+	Interval  time.Duration
+	BatchSize int
+	// Artifacts borra el QR y el PDF de las reservas vencidas; puede ser nil
+	Artifacts *TicketArtifactService
+}
+
+// NewHoldSweeper crea un sweeper con el intervalo indicado
+func NewHoldSweeper(db db.Repository, interval time.Duration) *HoldSweeper {
+	return &HoldSweeper{
+		DB:        db,
+		Interval:  interval,
+		BatchSize: 100,
+	}
+}
+
+// Run ejecuta el barrido hasta que se cancele el contexto
+func (s *HoldSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Sweep(ctx, time.Now()); err != nil {
+			log.Printf("Error barriendo reservas vencidas: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep cancela las reservas vencidas a la fecha now y devuelve cuántas liberó
+func (s *HoldSweeper) Sweep(ctx context.Context, now time.Time) (int, error) {
+	expired, err := s.DB.GetExpiredReservations(ctx, now, s.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, ticket := range expired {
+		// La cancelación y la devolución del asiento se aplican juntas; si fallan,
+		// la reserva sigue vencida y el siguiente barrido la reintenta
+		if err := s.DB.ExpireReservation(ctx, ticket, now); err != nil {
+			if !errors.Is(err, db.ErrReservationNotPending) {
+				log.Printf("Error expirando reserva %s: %v", ticket.ID, err)
+			}
+			continue
+		}
+		released++
+
+		if s.Artifacts != nil {
+			if err := s.Artifacts.Remove(ctx, ticket); err != nil {
+				log.Printf("Error borrando archivos de la reserva %s: %v", ticket.ID, err)
+			}
+		}
+	}
+
+	if released > 0 {
+		log.Printf("Reservas vencidas liberadas: %d", released)
+	}
+	return released, nil
+}
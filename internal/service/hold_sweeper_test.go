@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHoldSweeper_ReleasesSeatsOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+	now := time.Now()
+	event := model.Event{ID: uuid.New(), Name: "Concierto", TotalCapacity: 3, AvailableCapacity: 3, Status: model.EventStatusOpen, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.SaveEvent(ctx, event))
+
+	reserve := func(expiresAt time.Time) model.Ticket {
+		ticket := model.Ticket{ID: uuid.New(), EventID: event.ID, Status: model.TicketStatusReserved, ExpiresAt: &expiresAt, CreatedAt: now, UpdatedAt: now}
+		outbox := model.OutboxMessage{ID: uuid.New(), AggregateID: ticket.ID.String(), Status: model.OutboxStatusPending, CreatedAt: now}
+		require.NoError(t, repo.CreateReservation(ctx, ticket, outbox))
+		return ticket
+	}
+	available := func() int {
+		stored, err := repo.GetEventByID(ctx, event.ID.String())
+		require.NoError(t, err)
+		return stored.AvailableCapacity
+	}
+
+	expired := reserve(now.Add(-time.Minute))
+	reserve(now.Add(-time.Second))
+	live := reserve(now.Add(time.Hour))
+	require.Equal(t, 0, available())
+
+	sweeper := NewHoldSweeper(repo, time.Minute)
+	released, err := sweeper.Sweep(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, released)
+	assert.Equal(t, 2, available())
+
+	released, err = sweeper.Sweep(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, released)
+	assert.Equal(t, 2, available())
+
+	stored, err := repo.GetTicketByID(ctx, expired.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusCancelled, stored.Status)
+	stored, err = repo.GetTicketByID(ctx, live.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusReserved, stored.Status)
+}
+
+func TestHoldSweeper_CancelsWhenEventIsGone(t *testing.T) {
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+	now := time.Now()
+
+	// Una reserva que quedó sin evento no tiene asiento que devolver, pero se
+	// cancela igual en lugar de reintentarse en cada barrido
+	expiresAt := now.Add(-time.Minute)
+	ticket := model.Ticket{ID: uuid.New(), EventID: uuid.New(), Status: model.TicketStatusReserved, SeatHeld: true, ExpiresAt: &expiresAt, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.SaveTicket(ctx, ticket))
+
+	sweeper := NewHoldSweeper(repo, time.Minute)
+	released, err := sweeper.Sweep(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, released)
+
+	stored, err := repo.GetTicketByID(ctx, ticket.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusCancelled, stored.Status)
+	assert.False(t, stored.SeatHeld)
+
+	released, err = sweeper.Sweep(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, released)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+// Notifier envía avisos al titular de un ticket
+type Notifier interface {
+	// NotifyReservationReceived avisa que la reserva está apartada y debe
+	// confirmarse antes de que venza
+	NotifyReservationReceived(ctx context.Context, ticket model.Ticket) error
+	NotifyTicketConfirmed(ctx context.Context, ticket model.Ticket) error
+}
+
+// LogNotifier es un Notifier que solo registra el aviso en el log.
+// Sirve para desarrollo local mientras no haya un proveedor de correo configurado.
+type LogNotifier struct{}
+
+// NewLogNotifier crea una nueva instancia de LogNotifier
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// NotifyReservationReceived registra el aviso de la reserva apartada
+func (n *LogNotifier) NotifyReservationReceived(ctx context.Context, ticket model.Ticket) error {
+	expiresAt := "sin vencimiento"
+	if ticket.ExpiresAt != nil {
+		expiresAt = ticket.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	log.Printf("📧 Reserva %s recibida para %s <%s>, confirmar antes de: %s", ticket.TicketCode, ticket.Name, ticket.Email, expiresAt)
+	return nil
+}
+
+// NotifyTicketConfirmed registra el aviso de confirmación del ticket
+func (n *LogNotifier) NotifyTicketConfirmed(ctx context.Context, ticket model.Ticket) error {
+	log.Printf("📧 Ticket %s confirmado para %s <%s>", ticket.TicketCode, ticket.Name, ticket.Email)
+	return nil
+}
@@ -1,25 +1,33 @@
 package service
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
+	"image"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
 	"github.com/skip2/go-qrcode"
 )
 
+// ErrQRRotationRequired indica que se presentó un QR estático para un evento que
+// solo admite QR rotativos
+var ErrQRRotationRequired = errors.New("event requires rotating QR codes")
+
 // QRService maneja la generación de códigos QR
-type QRService struct{}
+type QRService struct {
+	Signer *QRSigner
+}
 
 // NewQRService crea una nueva instancia del servicio QR
-func NewQRService() *QRService {
-	return &QRService{}
+func NewQRService(signer *QRSigner) *QRService {
+	return &QRService{Signer: signer}
 }
 
 // GenerateTicketQR genera un código QR para un ticket
-func (s *QRService) GenerateTicketQR(ticketID uuid.UUID, userEmail, ticketCode string) ([]byte, error) {
-	// Crear el contenido del QR con información del ticket
-	qrContent := fmt.Sprintf("TICKET:%s|EMAIL:%s|CODE:%s", ticketID.String(), userEmail, ticketCode)
+func (s *QRService) GenerateTicketQR(ticket model.Ticket) ([]byte, error) {
+	// Crear el contenido firmado del QR
+	qrContent := s.GenerateQRContent(ticket)
 
 	// Generar el código QR
 	qrCode, err := qrcode.Encode(qrContent, qrcode.Medium, 256)
@@ -31,9 +39,9 @@ func (s *QRService) GenerateTicketQR(ticketID uuid.UUID, userEmail, ticketCode s
 }
 
 // GenerateTicketQRPNG genera un código QR en formato PNG
-func (s *QRService) GenerateTicketQRPNG(ticketID uuid.UUID, userEmail, ticketCode string) ([]byte, error) {
-	// Crear el contenido del QR con información del ticket
-	qrContent := fmt.Sprintf("TICKET:%s|EMAIL:%s|CODE:%s", ticketID.String(), userEmail, ticketCode)
+func (s *QRService) GenerateTicketQRPNG(ticket model.Ticket) ([]byte, error) {
+	// Crear el contenido firmado del QR
+	qrContent := s.GenerateQRContent(ticket)
 
 	// Generar el código QR como PNG
 	qrCode, err := qrcode.New(qrContent, qrcode.Medium)
@@ -50,49 +58,46 @@ func (s *QRService) GenerateTicketQRPNG(ticketID uuid.UUID, userEmail, ticketCod
 	return pngData, nil
 }
 
-// GenerateTicketQRWithLogo genera un código QR con logo (versión avanzada)
-func (s *QRService) GenerateTicketQRWithLogo(ticketID uuid.UUID, userEmail, ticketCode string) ([]byte, error) {
-	// Crear el contenido del QR con información del ticket
-	qrContent := fmt.Sprintf("TICKET:%s|EMAIL:%s|CODE:%s", ticketID.String(), userEmail, ticketCode)
-
-	// Generar el código QR con configuración personalizada
-	qrCode, err := qrcode.New(qrContent, qrcode.High)
-	if err != nil {
-		return nil, fmt.Errorf("error creando código QR: %v", err)
-	}
+// GenerateTicketQRWithLogo genera un código QR PNG con el logo del evento en el
+// centro, usando el nivel de corrección más alto para que siga siendo legible
+func (s *QRService) GenerateTicketQRWithLogo(ticket model.Ticket, logo image.Image) ([]byte, error) {
+	opts := DefaultQROptions()
+	opts.Size = 300
+	opts.Logo = logo
+	return RenderQR(s.GenerateQRContent(ticket), opts)
+}
 
-	// Configurar el código QR
-	qrCode.DisableBorder = false
+// GenerateQRContent genera el contenido firmado que se codificará en el QR.
+// Solo lleva los IDs del ticket y del evento, nunca el email del titular.
+func (s *QRService) GenerateQRContent(ticket model.Ticket) string {
+	return s.Signer.Sign(ticket.ID, ticket.EventID)
+}
 
-	// Convertir a PNG con tamaño personalizado
-	pngData, err := qrCode.PNG(300)
-	if err != nil {
-		return nil, fmt.Errorf("error convirtiendo QR a PNG: %v", err)
+// ValidateQRContent verifica la firma de un código QR y devuelve su contenido.
+// No consulta la base de datos: un QR falsificado se descarta aquí.
+func (s *QRService) ValidateQRContent(content string) (*QRClaims, error) {
+	if len(content) < 10 {
+		return nil, fmt.Errorf("contenido QR demasiado corto")
 	}
 
-	return pngData, nil
-}
-
-// GenerateQRContent genera el contenido que se codificará en el QR
-func (s *QRService) GenerateQRContent(ticketID uuid.UUID, userEmail, ticketCode, eventName string) string {
-	return fmt.Sprintf("TICKET:%s|EMAIL:%s|CODE:%s|EVENT:%s",
-		ticketID.String(), userEmail, ticketCode, eventName)
+	return s.Signer.Verify(content)
 }
 
-// ValidateQRContent valida el contenido de un código QR
-func (s *QRService) ValidateQRContent(content string) (bool, error) {
-	// Verificar que el contenido tenga el formato esperado
+// ValidateQRContentAt verifica un QR escaneado en scannedAt, por ejemplo un
+// escaneo sin conexión que se sincroniza más tarde
+func (s *QRService) ValidateQRContentAt(content string, scannedAt time.Time) (*QRClaims, error) {
 	if len(content) < 10 {
-		return false, fmt.Errorf("contenido QR demasiado corto")
+		return nil, fmt.Errorf("contenido QR demasiado corto")
 	}
 
-	// Verificar que contenga los campos requeridos
-	requiredFields := []string{"TICKET:", "EMAIL:", "CODE:"}
-	for _, field := range requiredFields {
-		if !bytes.Contains([]byte(content), []byte(field)) {
-			return false, fmt.Errorf("campo requerido no encontrado: %s", field)
-		}
-	}
+	return s.Signer.VerifyAt(content, scannedAt)
+}
 
-	return true, nil
+// CheckEventMode rechaza los QR estáticos de eventos que usan QR rotativos.
+// Un evento nil (no encontrado) no impone restricciones.
+func CheckEventMode(claims *QRClaims, event *model.Event) error {
+	if event != nil && event.RotatingQR && !claims.Rotating {
+		return ErrQRRotationRequired
+	}
+	return nil
 }
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	ErrQRNotFound       = errors.New("no QR code found in image")
+	ErrImageTooLarge    = errors.New("image dimensions too large")
+)
+
+// MaxQRImagePixels limita las dimensiones de las imágenes que se decodifican.
+// Un PNG pequeño puede declarar millones de píxeles y ocupar gigas al
+// descomprimirse; 20 megapíxeles alcanzan para cualquier foto de un teléfono.
+const MaxQRImagePixels = 20_000_000
+
+// DecodeQRImage lee una imagen PNG o JPEG y devuelve el texto del código QR que contiene
+func DecodeQRImage(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("error leyendo imagen: %w", err)
+	}
+
+	// Las dimensiones se leen de la cabecera antes de reservar memoria para los píxeles
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return "", ErrUnsupportedImage
+		}
+		return "", fmt.Errorf("error leyendo imagen: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > MaxQRImagePixels/config.Height {
+		return "", ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return "", ErrUnsupportedImage
+		}
+		return "", fmt.Errorf("error leyendo imagen: %w", err)
+	}
+
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", fmt.Errorf("error procesando imagen: %w", err)
+	}
+
+	// TRY_HARDER ayuda con fotos de pantallas, que suelen venir giradas o con reflejos
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+	result, err := qrcode.NewQRCodeReader().Decode(bitmap, hints)
+	if err != nil {
+		// El detector a veces no encuentra los patrones de posición en una
+		// captura limpia del código, sin bordes ni perspectiva: en ese caso se
+		// lee la imagen como un código sin fondo
+		result, err = qrcode.NewQRCodeReader().Decode(bitmap, map[gozxing.DecodeHintType]interface{}{
+			gozxing.DecodeHintType_PURE_BARCODE: true,
+		})
+		if err != nil {
+			return "", ErrQRNotFound
+		}
+	}
+
+	return result.GetText(), nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+	QRFormatPDF = "pdf"
+
+	DefaultQRSize = 256
+	MinQRSize     = 64
+	MaxQRSize     = 2048
+
+	// qrLogoRatio es la fracción del ancho del QR que ocupa el logo. Con el nivel
+	// High se puede perder hasta ~30% de los módulos, así que un logo de un 20%
+	// de lado (4% del área más el margen) se lee sin problemas.
+	qrLogoRatio = 0.2
+)
+
+var ErrInvalidQROptions = errors.New("invalid QR rendering options")
+
+// QROptions controla cómo se dibuja un código QR
+type QROptions struct {
+	Format string
+	Size   int
+	Level  qrcode.RecoveryLevel
+	Logo   image.Image
+}
+
+// DefaultQROptions devuelve las opciones con las que se generan los QR por defecto
+func DefaultQROptions() QROptions {
+	return QROptions{Format: QRFormatPNG, Size: DefaultQRSize, Level: qrcode.Medium}
+}
+
+// ParseQRLevel interpreta el nivel de corrección de errores (L, M, Q, H o su nombre)
+func ParseQRLevel(level string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToLower(level) {
+	case "", "m", "medium":
+		return qrcode.Medium, nil
+	case "l", "low":
+		return qrcode.Low, nil
+	case "q", "quartile":
+		return qrcode.High, nil
+	case "h", "high":
+		return qrcode.Highest, nil
+	}
+	return 0, fmt.Errorf("%w: nivel de corrección '%s'", ErrInvalidQROptions, level)
+}
+
+// Validate comprueba las opciones y aplica los valores por defecto
+func (o *QROptions) Validate() error {
+	if o.Format == "" {
+		o.Format = QRFormatPNG
+	}
+	switch o.Format {
+	case QRFormatPNG, QRFormatSVG, QRFormatPDF:
+	default:
+		return fmt.Errorf("%w: formato '%s'", ErrInvalidQROptions, o.Format)
+	}
+
+	if o.Size == 0 {
+		o.Size = DefaultQRSize
+	}
+	if o.Size < MinQRSize || o.Size > MaxQRSize {
+		return fmt.Errorf("%w: el tamaño debe estar entre %d y %d", ErrInvalidQROptions, MinQRSize, MaxQRSize)
+	}
+
+	// El logo tapa parte de los módulos: se fuerza el nivel de corrección más alto
+	if o.Logo != nil {
+		o.Level = qrcode.Highest
+	}
+	return nil
+}
+
+// ContentType devuelve el tipo MIME del formato elegido
+func (o QROptions) ContentType() string {
+	switch o.Format {
+	case QRFormatSVG:
+		return "image/svg+xml"
+	case QRFormatPDF:
+		return "application/pdf"
+	}
+	return "image/png"
+}
+
+// RenderQR dibuja el contenido como QR con las opciones indicadas
+func RenderQR(content string, opts QROptions) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	qr, err := qrcode.New(content, opts.Level)
+	if err != nil {
+		return nil, fmt.Errorf("error creando código QR: %v", err)
+	}
+
+	switch opts.Format {
+	case QRFormatSVG:
+		return renderQRSVG(qr, opts)
+	case QRFormatPDF:
+		pngData, err := renderQRPNG(qr, opts)
+		if err != nil {
+			return nil, err
+		}
+		return renderQRPDF(pngData, opts.Size)
+	}
+	return renderQRPNG(qr, opts)
+}
+
+func renderQRPNG(qr *qrcode.QRCode, opts QROptions) ([]byte, error) {
+	img := qr.Image(opts.Size)
+	if opts.Logo != nil {
+		img = overlayLogo(img, opts.Logo)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error convirtiendo QR a PNG: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// overlayLogo pega el logo escalado en el centro del QR sobre un recuadro blanco
+func overlayLogo(qr image.Image, logo image.Image) image.Image {
+	bounds := qr.Bounds()
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, bounds, qr, bounds.Min, draw.Src)
+
+	side := int(float64(bounds.Dx()) * qrLogoRatio)
+	logoBounds := logo.Bounds()
+	width, height := side, side
+	if logoBounds.Dx() > logoBounds.Dy() {
+		height = side * logoBounds.Dy() / logoBounds.Dx()
+	} else if logoBounds.Dy() > logoBounds.Dx() {
+		width = side * logoBounds.Dx() / logoBounds.Dy()
+	}
+
+	center := image.Pt(bounds.Min.X+bounds.Dx()/2, bounds.Min.Y+bounds.Dy()/2)
+	padding := side / 10
+	background := image.Rect(center.X-width/2-padding, center.Y-height/2-padding, center.X+width/2+padding, center.Y+height/2+padding)
+	draw.Draw(canvas, background, image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	target := image.Rect(center.X-width/2, center.Y-height/2, center.X-width/2+width, center.Y-height/2+height)
+	xdraw.CatmullRom.Scale(canvas, target, logo, logoBounds, draw.Over, nil)
+	return canvas
+}
+
+// renderQRSVG dibuja cada módulo oscuro como un rectángulo. Los proveedores de
+// impresión prefieren SVG porque escala sin perder nitidez.
+func renderQRSVG(qr *qrcode.QRCode, opts QROptions) ([]byte, error) {
+	bitmap := qr.Bitmap()
+	modules := len(bitmap)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		opts.Size, opts.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", modules, modules)
+
+	buf.WriteString(`<path fill="#000000" d="`)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/>` + "\n")
+
+	if opts.Logo != nil {
+		var logo bytes.Buffer
+		if err := png.Encode(&logo, opts.Logo); err != nil {
+			return nil, fmt.Errorf("error convirtiendo logo a PNG: %v", err)
+		}
+		side := float64(modules) * qrLogoRatio
+		offset := (float64(modules) - side) / 2
+		padding := side / 10
+		fmt.Fprintf(&buf, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="#ffffff"/>`+"\n",
+			offset-padding, offset-padding, side+2*padding, side+2*padding)
+		fmt.Fprintf(&buf, `<image x="%.2f" y="%.2f" width="%.2f" height="%.2f" preserveAspectRatio="xMidYMid meet" xlink:href="data:image/png;base64,%s"/>`+"\n",
+			offset, offset, side, side, base64.StdEncoding.EncodeToString(logo.Bytes()))
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
+
+// renderQRPDF devuelve un PDF de una página del tamaño del QR
+func renderQRPDF(pngData []byte, size int) ([]byte, error) {
+	// 1px = 0.75pt a 96 dpi
+	side := float64(size) * 0.75
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "pt",
+		Size:    fpdf.SizeType{Wd: side, Ht: side},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(pngData))
+	pdf.ImageOptions("qr", 0, 0, side, side, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("error generando PDF del QR: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderQR_Formats(t *testing.T) {
+	content := "TKT1.k1.payload.signature"
+
+	pngData, err := RenderQR(content, QROptions{Format: QRFormatPNG, Size: 128})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pngData, []byte("\x89PNG")))
+	img, _, err := image.Decode(bytes.NewReader(pngData))
+	require.NoError(t, err)
+	assert.Equal(t, 128, img.Bounds().Dx())
+
+	svgData, err := RenderQR(content, QROptions{Format: QRFormatSVG, Size: 512})
+	require.NoError(t, err)
+	assert.Contains(t, string(svgData), `<svg`)
+	assert.Contains(t, string(svgData), `width="512"`)
+
+	pdfData, err := RenderQR(content, QROptions{Format: QRFormatPDF})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdfData, []byte("%PDF")))
+}
+
+func TestRenderQR_RejectsInvalidOptions(t *testing.T) {
+	_, err := RenderQR("content", QROptions{Format: "gif"})
+	assert.ErrorIs(t, err, ErrInvalidQROptions)
+
+	_, err = RenderQR("content", QROptions{Size: MaxQRSize + 1})
+	assert.ErrorIs(t, err, ErrInvalidQROptions)
+
+	_, err = ParseQRLevel("x")
+	assert.ErrorIs(t, err, ErrInvalidQROptions)
+}
+
+func TestRenderQR_LogoStaysReadable(t *testing.T) {
+	logo := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 20; y++ {
+			logo.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+
+	content := "TKT1.k1.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA.signature"
+	pngData, err := RenderQR(content, QROptions{Format: QRFormatPNG, Size: 400, Logo: logo})
+	require.NoError(t, err)
+
+	decoded, err := DecodeQRImage(bytes.NewReader(pngData))
+	require.NoError(t, err)
+	assert.Equal(t, content, decoded)
+}
+
+func TestDecodeQRImage_CleanCode(t *testing.T) {
+	// Con este contenido el detector no encuentra los patrones de posición del
+	// PNG que genera GenerateTicketQRPNG; se lee como código sin fondo
+	content := "TKT1.test.1GEnYaGfQ2m_r_LR8fwJ0qAuZlVDTk_inoFqDfBHpzk.KmGtFtn76aAQv4hP1-3KqpVlWFz4uZ4h8gZHS44sgN0"
+	qr, err := qrcode.New(content, qrcode.Medium)
+	require.NoError(t, err)
+	pngData, err := qr.PNG(256)
+	require.NoError(t, err)
+
+	decoded, err := DecodeQRImage(bytes.NewReader(pngData))
+	require.NoError(t, err)
+	assert.Equal(t, content, decoded)
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
+)
+
+const (
+	// qrPayloadVersion identifica el formato del contenido firmado del QR estático
+	qrPayloadVersion = "TKT1"
+	// qrRotatingVersion identifica el formato del QR rotativo, que además lleva
+	// la ventana de tiempo en la que se generó
+	qrRotatingVersion = "TKT2"
+	// qrRotationSkew es cuántas ventanas antes o después de la actual se aceptan,
+	// para tolerar la diferencia de reloj entre el teléfono y el lector
+	qrRotationSkew = 1
+)
+
+// QRRotationStep es la duración de cada ventana de un QR rotativo
+const QRRotationStep = 30 * time.Second
+
+var (
+	ErrQRMalformed    = errors.New("malformed QR payload")
+	ErrQRUnknownKey   = errors.New("unknown QR signing key")
+	ErrQRBadSignature = errors.New("invalid QR signature")
+	ErrQRExpired      = errors.New("rotating QR code outside the accepted time window")
+)
+
+// QRClaims es la información que viaja firmada dentro del QR. No incluye datos
+// personales del titular: solo los identificadores del ticket y del evento.
+type QRClaims struct {
+	KeyID    string
+	TicketID uuid.UUID
+	EventID  uuid.UUID
+	Rotating bool
+	TimeStep int64
+}
+
+// QRSigner firma y verifica el contenido de los QR con HMAC-SHA256. Cada firma
+// lleva el ID de la clave con la que se hizo, de modo que se puede rotar la clave
+// activa y seguir aceptando los QR emitidos con las anteriores mientras estén configuradas.
+type QRSigner struct {
+	keys        map[string][]byte
+	activeKeyID string
+}
+
+// NewQRSigner crea un firmador que firma con activeKeyID y verifica con cualquiera de keys
+func NewQRSigner(activeKeyID string, keys map[string][]byte) (*QRSigner, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("la clave activa %q no está entre las claves configuradas", activeKeyID)
+	}
+	for id, key := range keys {
+		if strings.Contains(id, ".") || id == "" {
+			return nil, fmt.Errorf("ID de clave inválido: %q", id)
+		}
+		if len(key) < 32 {
+			return nil, fmt.Errorf("la clave %q debe tener al menos 32 bytes", id)
+		}
+	}
+	return &QRSigner{keys: keys, activeKeyID: activeKeyID}, nil
+}
+
+// LoadQRSignerFromConfig construye el firmador con las claves de cfg. Sin claves
+// falla, salvo que cfg.AllowTemporaryKey pida una clave aleatoria, válida solo
+// mientras viva el proceso y distinta de la de cualquier otro proceso.
+func LoadQRSignerFromConfig(cfg config.QRConfig) (*QRSigner, error) {
+	keys, err := cfg.Keys()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		if !cfg.AllowTemporaryKey {
+			return nil, errors.New("QR_SIGNING_KEYS no definida; para desarrollo se puede usar QR_ALLOW_TEMPORARY_KEY=true")
+		}
+		log.Println("⚠️  QR_SIGNING_KEYS no definida: se usa una clave temporal y los QR dejarán de validar al reiniciar")
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("error generando clave de firma: %v", err)
+		}
+		return NewQRSigner("dev", map[string][]byte{"dev": key})
+	}
+
+	activeKeyID := cfg.ActiveKeyID
+	if activeKeyID == "" && len(keys) == 1 {
+		for id := range keys {
+			activeKeyID = id
+		}
+	}
+	return NewQRSigner(activeKeyID, keys)
+}
+
+// Sign devuelve el contenido firmado: TKT1.<key id>.<ids en base64>.<firma en base64>
+func (s *QRSigner) Sign(ticketID, eventID uuid.UUID) string {
+	return s.signWith(s.activeKeyID, ticketID, eventID)
+}
+
+// SignAll devuelve el contenido firmado con cada una de las claves configuradas,
+// es decir, todos los QR válidos que puede presentar el titular del ticket
+func (s *QRSigner) SignAll(ticketID, eventID uuid.UUID) []string {
+	keyIDs := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		keyIDs = append(keyIDs, id)
+	}
+	sort.Strings(keyIDs)
+
+	contents := make([]string, 0, len(keyIDs))
+	for _, id := range keyIDs {
+		contents = append(contents, s.signWith(id, ticketID, eventID))
+	}
+	return contents
+}
+
+func (s *QRSigner) signWith(keyID string, ticketID, eventID uuid.UUID) string {
+	signed := qrPayloadVersion + "." + keyID + "." + encodeQRIDs(ticketID, eventID)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(s.mac(s.keys[keyID], signed))
+}
+
+// SignRotating devuelve el contenido del QR rotativo para la ventana que contiene
+// now: TKT2.<key id>.<ids en base64>.<ventana>.<código>. El código se calcula con
+// un secreto propio del ticket, derivado de la clave activa, así que cambia en
+// cada ventana y una captura de pantalla deja de servir a los pocos segundos.
+// Devuelve también el instante en que empieza la siguiente ventana.
+func (s *QRSigner) SignRotating(ticketID, eventID uuid.UUID, now time.Time) (string, time.Time) {
+	step := now.Unix() / int64(QRRotationStep/time.Second)
+	signed := qrRotatingVersion + "." + s.activeKeyID + "." + encodeQRIDs(ticketID, eventID) + "." + strconv.FormatInt(step, 10)
+	secret := s.ticketSecret(s.keys[s.activeKeyID], ticketID)
+	code := base64.RawURLEncoding.EncodeToString(s.mac(secret, signed)[:12])
+
+	nextStep := time.Unix((step+1)*int64(QRRotationStep/time.Second), 0)
+	return signed + "." + code, nextStep
+}
+
+// Verify comprueba la firma del contenido y devuelve sus claims
+func (s *QRSigner) Verify(content string) (*QRClaims, error) {
+	return s.VerifyAt(content, time.Now())
+}
+
+// VerifyAt comprueba la firma como si el QR se hubiera escaneado en now. Para
+// los QR rotativos solo acepta la ventana de now y las adyacentes.
+func (s *QRSigner) VerifyAt(content string, now time.Time) (*QRClaims, error) {
+	parts := strings.Split(content, ".")
+	switch {
+	case len(parts) == 4 && parts[0] == qrPayloadVersion:
+	case len(parts) == 5 && parts[0] == qrRotatingVersion:
+	default:
+		return nil, ErrQRMalformed
+	}
+
+	key, ok := s.keys[parts[1]]
+	if !ok {
+		return nil, ErrQRUnknownKey
+	}
+
+	claims := &QRClaims{KeyID: parts[1]}
+	ids, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(ids) != 32 {
+		return nil, ErrQRMalformed
+	}
+	copy(claims.TicketID[:], ids[:16])
+	copy(claims.EventID[:], ids[16:])
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[len(parts)-1])
+	if err != nil {
+		return nil, ErrQRMalformed
+	}
+	signed := strings.Join(parts[:len(parts)-1], ".")
+
+	if parts[0] == qrPayloadVersion {
+		if !hmac.Equal(signature, s.mac(key, signed)) {
+			return nil, ErrQRBadSignature
+		}
+		return claims, nil
+	}
+
+	step, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return nil, ErrQRMalformed
+	}
+	expected := s.mac(s.ticketSecret(key, claims.TicketID), signed)[:12]
+	if !hmac.Equal(signature, expected) {
+		return nil, ErrQRBadSignature
+	}
+
+	current := now.Unix() / int64(QRRotationStep/time.Second)
+	if step < current-qrRotationSkew || step > current+qrRotationSkew {
+		return nil, ErrQRExpired
+	}
+
+	claims.Rotating = true
+	claims.TimeStep = step
+	return claims, nil
+}
+
+// ticketSecret deriva el secreto de un ticket a partir de la clave de firma,
+// de modo que no hace falta guardarlo y rota junto con la clave
+func (s *QRSigner) ticketSecret(key []byte, ticketID uuid.UUID) []byte {
+	return s.mac(key, "rotating:"+ticketID.String())
+}
+
+func encodeQRIDs(ticketID, eventID uuid.UUID) string {
+	ids := make([]byte, 0, 32)
+	ids = append(ids, ticketID[:]...)
+	ids = append(ids, eventID[:]...)
+	return base64.RawURLEncoding.EncodeToString(ids)
+}
+
+func (s *QRSigner) mac(key []byte, signed string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(signed))
+	return h.Sum(nil)
+}
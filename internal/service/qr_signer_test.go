@@ -0,0 +1,145 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQRSigner_SignAndVerify(t *testing.T) {
+	signer, err := NewQRSigner("k1", map[string][]byte{"k1": bytes.Repeat([]byte("a"), 32)})
+	require.NoError(t, err)
+
+	ticketID, eventID := uuid.New(), uuid.New()
+	content := signer.Sign(ticketID, eventID)
+
+	claims, err := signer.Verify(content)
+	require.NoError(t, err)
+	assert.Equal(t, "k1", claims.KeyID)
+	assert.Equal(t, ticketID, claims.TicketID)
+	assert.Equal(t, eventID, claims.EventID)
+}
+
+func TestQRSigner_RejectsForgedPayload(t *testing.T) {
+	signer, err := NewQRSigner("k1", map[string][]byte{"k1": bytes.Repeat([]byte("a"), 32)})
+	require.NoError(t, err)
+	forger, err := NewQRSigner("k1", map[string][]byte{"k1": bytes.Repeat([]byte("b"), 32)})
+	require.NoError(t, err)
+
+	_, err = signer.Verify(forger.Sign(uuid.New(), uuid.New()))
+	assert.ErrorIs(t, err, ErrQRBadSignature)
+
+	_, err = signer.Verify("TICKET:550e8400-e29b-41d4-a716-446655440003|EMAIL:a@b.c|CODE:550e8400")
+	assert.ErrorIs(t, err, ErrQRMalformed)
+}
+
+func TestQRSigner_KeyRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte("a"), 32)
+	newKey := bytes.Repeat([]byte("b"), 32)
+
+	before, err := NewQRSigner("k1", map[string][]byte{"k1": oldKey})
+	require.NoError(t, err)
+	issued := before.Sign(uuid.New(), uuid.New())
+
+	after, err := NewQRSigner("k2", map[string][]byte{"k1": oldKey, "k2": newKey})
+	require.NoError(t, err)
+	_, err = after.Verify(issued)
+	assert.NoError(t, err)
+
+	retired, err := NewQRSigner("k2", map[string][]byte{"k2": newKey})
+	require.NoError(t, err)
+	_, err = retired.Verify(issued)
+	assert.ErrorIs(t, err, ErrQRUnknownKey)
+}
+
+func TestLoadQRSignerFromConfig_RequiresKeys(t *testing.T) {
+	_, err := LoadQRSignerFromConfig(config.QRConfig{})
+	assert.Error(t, err)
+
+	temporary, err := LoadQRSignerFromConfig(config.QRConfig{AllowTemporaryKey: true})
+	require.NoError(t, err)
+	other, err := LoadQRSignerFromConfig(config.QRConfig{AllowTemporaryKey: true})
+	require.NoError(t, err)
+	_, err = other.Verify(temporary.Sign(uuid.New(), uuid.New()))
+	assert.ErrorIs(t, err, ErrQRBadSignature)
+
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("a"), 32))
+	api, err := LoadQRSignerFromConfig(config.QRConfig{SigningKeys: "k1:" + key})
+	require.NoError(t, err)
+	worker, err := LoadQRSignerFromConfig(config.QRConfig{SigningKeys: "k1:" + key})
+	require.NoError(t, err)
+	_, err = api.Verify(worker.Sign(uuid.New(), uuid.New()))
+	assert.NoError(t, err)
+}
+
+func TestBundleSigner_BuildAndVerify(t *testing.T) {
+	qrSigner, err := NewQRSigner("k2", map[string][]byte{
+		"k1": bytes.Repeat([]byte("a"), 32),
+		"k2": bytes.Repeat([]byte("b"), 32),
+	})
+	require.NoError(t, err)
+	qr := NewQRService(qrSigner)
+
+	bundles, err := NewBundleSigner("b1", bytes.Repeat([]byte("c"), 32))
+	require.NoError(t, err)
+
+	event := model.Event{ID: uuid.New(), EndTime: time.Now().Add(time.Hour)}
+	confirmed := model.Ticket{ID: uuid.New(), EventID: event.ID, Status: model.TicketStatusConfirmed}
+	reserved := model.Ticket{ID: uuid.New(), EventID: event.ID, Status: model.TicketStatusReserved}
+
+	bundle, manifest, err := bundles.BuildCheckinBundle(event, []model.Ticket{confirmed, reserved}, qr, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, manifest.Count)
+	// One entry per configured QR key so tickets issued before a rotation still scan
+	assert.Len(t, manifest.Entries, 2)
+
+	verified, err := VerifyCheckinBundle(*bundle, bundles.PublicKey())
+	require.NoError(t, err)
+	assert.Contains(t, verified.Entries, BundleEntry(qr.GenerateQRContent(confirmed)))
+	assert.NotContains(t, verified.Entries, BundleEntry(qr.GenerateQRContent(reserved)))
+
+	bundle.Payload = base64.StdEncoding.EncodeToString([]byte(`{"version":1,"entries":["forged"]}`))
+	_, err = VerifyCheckinBundle(*bundle, bundles.PublicKey())
+	assert.ErrorIs(t, err, ErrBundleBadSignature)
+}
+
+func TestQRSigner_RotatingWindows(t *testing.T) {
+	signer, err := NewQRSigner("k1", map[string][]byte{"k1": bytes.Repeat([]byte("a"), 32)})
+	require.NoError(t, err)
+
+	ticketID, eventID := uuid.New(), uuid.New()
+	issuedAt := time.Unix(1_800_000_000, 0)
+	content, validUntil := signer.SignRotating(ticketID, eventID, issuedAt)
+	assert.True(t, validUntil.After(issuedAt))
+	assert.LessOrEqual(t, validUntil.Sub(issuedAt), QRRotationStep)
+
+	claims, err := signer.VerifyAt(content, issuedAt)
+	require.NoError(t, err)
+	assert.True(t, claims.Rotating)
+	assert.Equal(t, ticketID, claims.TicketID)
+
+	// Adjacent windows tolerate clock drift between phone and scanner
+	_, err = signer.VerifyAt(content, issuedAt.Add(QRRotationStep))
+	assert.NoError(t, err)
+	_, err = signer.VerifyAt(content, issuedAt.Add(-QRRotationStep))
+	assert.NoError(t, err)
+
+	_, err = signer.VerifyAt(content, issuedAt.Add(3*QRRotationStep))
+	assert.ErrorIs(t, err, ErrQRExpired)
+
+	// The time step cannot be moved forward without the per-ticket secret
+	next, _ := signer.SignRotating(ticketID, eventID, issuedAt.Add(QRRotationStep))
+	parts := strings.Split(content, ".")
+	nextParts := strings.Split(next, ".")
+	parts[3] = nextParts[3]
+	_, err = signer.VerifyAt(strings.Join(parts, "."), issuedAt.Add(QRRotationStep))
+	assert.ErrorIs(t, err, ErrQRBadSignature)
+}
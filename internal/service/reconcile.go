@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+// artifactPrefixes son los prefijos del almacenamiento que pertenecen a tickets
+var artifactPrefixes = []string{"qrcodes/", "tickets/"}
+
+// MissingArtifact es un archivo que un ticket debería tener y no está guardado
+type MissingArtifact struct {
+	TicketID string `json:"ticket_id"`
+	Key      string `json:"key"`
+}
+
+// ArtifactReport resume una pasada de reconciliación
+type ArtifactReport struct {
+	Repair         bool              `json:"repair"`
+	CheckedTickets int               `json:"checked_tickets"`
+	CheckedObjects int               `json:"checked_objects"`
+	Orphans        []string          `json:"orphans"`
+	Missing        []MissingArtifact `json:"missing"`
+	Deleted        []string          `json:"deleted"`
+	Regenerated    []string          `json:"regenerated"`
+	Errors         []string          `json:"errors"`
+}
+
+// DefaultArtifactGracePeriod es la antigüedad mínima de un archivo o ticket
+// para que la reconciliación lo considere, si no se configura otra
+const DefaultArtifactGracePeriod = 10 * time.Minute
+
+// ArtifactReconciler compara los archivos guardados con los tickets: informa (y
+// con repair borra) los archivos sin ticket activo, e informa (y con repair
+// regenera) los tickets a los que les falta algún archivo.
+type ArtifactReconciler struct {
+	DB        db.Repository
+	Artifacts *TicketArtifactService
+	PageSize  int
+	// GracePeriod deja fuera los archivos y tickets más nuevos, que pueden
+	// pertenecer a una reserva que todavía se está guardando
+	GracePeriod time.Duration
+}
+
+// NewArtifactReconciler crea un reconciliador que recorre los tickets de a 100
+func NewArtifactReconciler(db db.Repository, artifacts *TicketArtifactService) *ArtifactReconciler {
+	return &ArtifactReconciler{DB: db, Artifacts: artifacts, PageSize: 100, GracePeriod: DefaultArtifactGracePeriod}
+}
+
+// Run reconcilia cada interval hasta que se cancele el contexto, solo informando
+// salvo que repair sea true
+func (r *ArtifactReconciler) Run(ctx context.Context, interval time.Duration, repair bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := r.Reconcile(ctx, repair)
+		if err != nil {
+			log.Printf("Error reconciliando archivos de tickets: %v", err)
+			continue
+		}
+		if len(report.Orphans) > 0 || len(report.Missing) > 0 {
+			log.Printf("Reconciliación de archivos: %d huérfanos, %d faltantes, %d borrados, %d regenerados",
+				len(report.Orphans), len(report.Missing), len(report.Deleted), len(report.Regenerated))
+		}
+	}
+}
+
+// Reconcile hace una pasada completa sobre los tickets y los archivos guardados
+func (r *ArtifactReconciler) Reconcile(ctx context.Context, repair bool) (*ArtifactReport, error) {
+	report := &ArtifactReport{
+		Repair:      repair,
+		Orphans:     []string{},
+		Missing:     []MissingArtifact{},
+		Deleted:     []string{},
+		Regenerated: []string{},
+		Errors:      []string{},
+	}
+
+	cutoff := time.Now().Add(-r.GracePeriod)
+	objects := map[string]bool{}
+	for _, prefix := range artifactPrefixes {
+		blobs, err := r.Artifacts.Blobs.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range blobs {
+			// Un archivo recién subido puede ser de un ticket que aún no se guardó
+			if blob.LastModified.After(cutoff) {
+				continue
+			}
+			objects[blob.Key] = true
+		}
+	}
+	report.CheckedObjects = len(objects)
+
+	expected := map[string]bool{}
+	events := map[uuid.UUID]*model.Event{}
+	cursor := ""
+	for {
+		tickets, next, err := r.DB.GetTickets(ctx, "", "", r.PageSize, cursor)
+		if err != nil {
+			return nil, fmt.Errorf("error listando tickets: %w", err)
+		}
+
+		for _, ticket := range tickets {
+			report.CheckedTickets++
+			if ticket.Status == model.TicketStatusCancelled || ticket.CreatedAt.After(cutoff) {
+				continue
+			}
+
+			event, err := r.event(ctx, events, ticket.EventID)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("ticket %s: %v", ticket.ID, err))
+				continue
+			}
+
+			keys := expectedArtifactKeys(ticket, event)
+			missing := false
+			for _, key := range keys {
+				expected[key] = true
+				if !objects[key] {
+					missing = true
+					report.Missing = append(report.Missing, MissingArtifact{TicketID: ticket.ID.String(), Key: key})
+				}
+			}
+
+			if missing && repair {
+				if _, _, err := r.Artifacts.Generate(ctx, ticket, event); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("ticket %s: %v", ticket.ID, err))
+					continue
+				}
+				report.Regenerated = append(report.Regenerated, ticket.ID.String())
+			}
+		}
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if expected[key] {
+			continue
+		}
+		if !repair {
+			report.Orphans = append(report.Orphans, key)
+			continue
+		}
+
+		// El ticket pudo crearse después de listarlos: se vuelve a consultar justo antes de borrar
+		orphan, err := r.stillOrphan(ctx, events, key)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		if !orphan {
+			continue
+		}
+		report.Orphans = append(report.Orphans, key)
+		if err := r.Artifacts.Blobs.Delete(ctx, key); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		report.Deleted = append(report.Deleted, key)
+	}
+	return report, nil
+}
+
+// stillOrphan lee de nuevo el ticket al que pertenece key e indica si key sigue
+// sin corresponder a un ticket activo
+func (r *ArtifactReconciler) stillOrphan(ctx context.Context, events map[uuid.UUID]*model.Event, key string) (bool, error) {
+	ticketID, ok := artifactTicketID(key)
+	if !ok {
+		return true, nil
+	}
+	ticket, err := r.DB.GetTicketByID(ctx, ticketID)
+	if err != nil {
+		if errors.Is(err, db.ErrTicketNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+	if ticket.Status == model.TicketStatusCancelled {
+		return true, nil
+	}
+
+	event, err := r.event(ctx, events, ticket.EventID)
+	if err != nil {
+		return false, err
+	}
+	for _, expected := range expectedArtifactKeys(*ticket, event) {
+		if expected == key {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// artifactTicketID extrae el ID del ticket de una clave como qrcodes/<id>.png
+func artifactTicketID(key string) (string, bool) {
+	name := path.Base(key)
+	id, err := uuid.Parse(strings.TrimSuffix(name, path.Ext(name)))
+	if err != nil {
+		return "", false
+	}
+	return id.String(), true
+}
+
+func (r *ArtifactReconciler) event(ctx context.Context, cache map[uuid.UUID]*model.Event, eventID uuid.UUID) (*model.Event, error) {
+	if event, ok := cache[eventID]; ok {
+		return event, nil
+	}
+	event, err := r.DB.GetEventByID(ctx, eventID.String())
+	if err != nil {
+		if !errors.Is(err, db.ErrEventNotFound) {
+			return nil, err
+		}
+		event = nil
+	}
+	cache[eventID] = event
+	return event, nil
+}
+
+// expectedArtifactKeys devuelve los archivos que debería tener un ticket activo:
+// siempre el PDF y el QR estático salvo que el evento use QR rotativos
+func expectedArtifactKeys(ticket model.Ticket, event *model.Event) []string {
+	keys := []string{TicketFileKey(ticket)}
+	if event == nil || !event.RotatingQR {
+		keys = append(keys, QRKey(ticket))
+	}
+	return keys
+}
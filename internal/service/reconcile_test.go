@@ -0,0 +1,141 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactReconciler(t *testing.T) {
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost:8080/api/blobs", bytes.Repeat([]byte("s"), 32))
+	require.NoError(t, err)
+	qrSigner, err := NewQRSigner("k1", map[string][]byte{"k1": bytes.Repeat([]byte("a"), 32)})
+	require.NoError(t, err)
+	artifacts := NewTicketArtifactService(store, NewQRService(qrSigner))
+
+	event := model.Event{ID: uuid.New(), Name: "Concierto", Status: model.EventStatusOpen}
+	require.NoError(t, repo.SaveEvent(ctx, event))
+
+	newTicket := func(status string) model.Ticket {
+		now := time.Now()
+		ticket := model.Ticket{ID: uuid.New(), EventID: event.ID, TicketCode: "TKT-" + status, Status: status, CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, repo.SaveTicket(ctx, ticket))
+		return ticket
+	}
+
+	complete := newTicket(model.TicketStatusConfirmed)
+	_, _, err = artifacts.Generate(ctx, complete, &event)
+	require.NoError(t, err)
+
+	missing := newTicket(model.TicketStatusConfirmed)
+
+	cancelled := newTicket(model.TicketStatusCancelled)
+	_, _, err = artifacts.Generate(ctx, cancelled, &event)
+	require.NoError(t, err)
+
+	deletedKey := "qrcodes/" + uuid.New().String() + ".png"
+	require.NoError(t, store.Put(ctx, deletedKey, strings.NewReader("png"), "image/png"))
+	legacyKey := "tickets/" + complete.ID.String() + ".txt"
+	require.NoError(t, store.Put(ctx, legacyKey, strings.NewReader("txt"), "text/plain"))
+
+	reconciler := NewArtifactReconciler(repo, artifacts)
+	reconciler.GracePeriod = 0
+	report, err := reconciler.Reconcile(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.CheckedTickets)
+	assert.ElementsMatch(t, []string{deletedKey, legacyKey, QRKey(cancelled), TicketFileKey(cancelled)}, report.Orphans)
+	assert.ElementsMatch(t, []MissingArtifact{
+		{TicketID: missing.ID.String(), Key: QRKey(missing)},
+		{TicketID: missing.ID.String(), Key: TicketFileKey(missing)},
+	}, report.Missing)
+	assert.Empty(t, report.Deleted)
+
+	// Sin repair no se toca nada
+	_, err = store.Head(ctx, deletedKey)
+	require.NoError(t, err)
+
+	report, err = reconciler.Reconcile(ctx, true)
+	require.NoError(t, err)
+	assert.Len(t, report.Deleted, 4)
+	assert.Equal(t, []string{missing.ID.String()}, report.Regenerated)
+	assert.Empty(t, report.Errors)
+
+	report, err = reconciler.Reconcile(ctx, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.Orphans)
+	assert.Empty(t, report.Missing)
+}
+
+// lateTicketRepository guarda un ticket justo después de que el reconciliador
+// termina de listar, como una reserva que se confirma durante la pasada
+type lateTicketRepository struct {
+	*db.MemoryRepository
+	late model.Ticket
+}
+
+func (r *lateTicketRepository) GetTickets(ctx context.Context, userEmail, eventID string, limit int, cursor string) ([]model.Ticket, string, error) {
+	tickets, next, err := r.MemoryRepository.GetTickets(ctx, userEmail, eventID, limit, cursor)
+	if err == nil && next == "" {
+		err = r.MemoryRepository.SaveTicket(ctx, r.late)
+	}
+	return tickets, next, err
+}
+
+func TestArtifactReconciler_KeepsInFlightArtifacts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := storage.NewLocalStore(dir, "http://localhost:8080/api/blobs", bytes.Repeat([]byte("s"), 32))
+	require.NoError(t, err)
+	qrSigner, err := NewQRSigner("k1", map[string][]byte{"k1": bytes.Repeat([]byte("a"), 32)})
+	require.NoError(t, err)
+	artifacts := NewTicketArtifactService(store, NewQRService(qrSigner))
+
+	now := time.Now()
+	late := model.Ticket{ID: uuid.New(), EventID: uuid.New(), TicketCode: "TKT-late", Status: model.TicketStatusReserved, CreatedAt: now, UpdatedAt: now}
+	repo := &lateTicketRepository{MemoryRepository: db.NewMemoryRepository(), late: late}
+
+	// Los archivos de una reserva que todavía no se guardó
+	_, _, err = artifacts.Generate(ctx, late, nil)
+	require.NoError(t, err)
+	orphanKey := "qrcodes/" + uuid.New().String() + ".png"
+	require.NoError(t, store.Put(ctx, orphanKey, strings.NewReader("png"), "image/png"))
+
+	// Dentro del margen no se consideran
+	reconciler := NewArtifactReconciler(repo.MemoryRepository, artifacts)
+	reconciler.GracePeriod = time.Hour
+	report, err := reconciler.Reconcile(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.CheckedObjects)
+	assert.Empty(t, report.Deleted)
+
+	// Fuera del margen, los del ticket que aparece durante la pasada se conservan
+	old := now.Add(-time.Hour)
+	for _, key := range []string{QRKey(late), TicketFileKey(late), orphanKey} {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, filepath.FromSlash(key)), old, old))
+	}
+	reconciler = NewArtifactReconciler(repo, artifacts)
+	reconciler.GracePeriod = 30 * time.Minute
+	report, err = reconciler.Reconcile(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{orphanKey}, report.Deleted)
+	assert.Equal(t, []string{orphanKey}, report.Orphans)
+	assert.Empty(t, report.Errors)
+
+	_, err = store.Head(ctx, QRKey(late))
+	assert.NoError(t, err)
+	_, err = store.Head(ctx, TicketFileKey(late))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+// ticketPDFGeometry agrupa las medidas (en mm) de cada diseño de ticket
+type ticketPDFGeometry struct {
+	width, height float64
+	qrSide        float64
+	titleSize     float64
+	textSize      float64
+}
+
+var ticketPDFLayouts = map[string]ticketPDFGeometry{
+	// standard ocupa el ancho útil de la hoja
+	model.TicketLayoutStandard: {width: 180, height: 150, qrSide: 70, titleSize: 20, textSize: 12},
+	// compact es un talón pequeño pensado para recortar
+	model.TicketLayoutCompact: {width: 150, height: 70, qrSide: 45, titleSize: 14, textSize: 9},
+}
+
+// RenderTicketPDF genera el ticket imprimible usando la plantilla del evento.
+// qrPNG es la imagen del QR estático; si está vacía (eventos con QR rotativo)
+// se imprime un aviso para abrir el QR dinámico. No hay código de barras
+// alternativo: el código del ticket no está firmado y se puede adivinar, y el
+// contenido firmado del QR no entra en un Code 128.
+func RenderTicketPDF(ticket model.Ticket, event *model.Event, qrPNG []byte) ([]byte, error) {
+	template := model.DefaultTicketTemplate()
+	if event != nil && event.TicketTemplate != nil {
+		template = *event.TicketTemplate
+	}
+	if msg := template.Validate(); msg != "" {
+		return nil, fmt.Errorf("plantilla de ticket inválida: %s", msg)
+	}
+	geometry := ticketPDFLayouts[template.Layout]
+	red, green, blue, _ := template.RGB()
+
+	pdf := fpdf.New("P", "mm", template.PaperSize, "")
+	pdf.SetTitle(fmt.Sprintf("Ticket %s", ticket.TicketCode), true)
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	// Las fuentes estándar usan cp1252, así que hay que traducir los acentos
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	x, y := 15.0, 15.0
+	pdf.SetDrawColor(red, green, blue)
+	pdf.SetLineWidth(0.6)
+	pdf.Rect(x, y, geometry.width, geometry.height, "D")
+
+	// Cabecera con el nombre del evento
+	headerHeight := geometry.titleSize * 0.7
+	pdf.SetFillColor(red, green, blue)
+	pdf.Rect(x, y, geometry.width, headerHeight, "F")
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetFont("Helvetica", "B", geometry.titleSize)
+	pdf.SetXY(x+4, y)
+	pdf.CellFormat(geometry.width-8, headerHeight, tr(eventName(event)), "", 0, "L", false, 0, "")
+
+	// Datos del ticket a la izquierda, QR a la derecha
+	pdf.SetTextColor(0, 0, 0)
+	lineHeight := geometry.textSize * 0.55
+	detailsWidth := geometry.width - geometry.qrSide - 12
+	pdf.SetXY(x+4, y+headerHeight+4)
+	for _, field := range ticketPDFFields(ticket, event) {
+		pdf.SetX(x + 4)
+		pdf.SetFont("Helvetica", "B", geometry.textSize)
+		pdf.CellFormat(detailsWidth*0.35, lineHeight, tr(field[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", geometry.textSize)
+		pdf.CellFormat(detailsWidth*0.65, lineHeight, tr(field[1]), "", 1, "L", false, 0, "")
+	}
+
+	qrX := x + geometry.width - geometry.qrSide - 4
+	qrY := y + headerHeight + 4
+	if len(qrPNG) > 0 {
+		pdf.RegisterImageOptionsReader("qr", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qrPNG))
+		pdf.ImageOptions("qr", qrX, qrY, geometry.qrSide, geometry.qrSide, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	} else {
+		pdf.SetFont("Helvetica", "I", geometry.textSize)
+		pdf.SetXY(qrX, qrY)
+		pdf.MultiCell(geometry.qrSide, lineHeight,
+			tr(fmt.Sprintf("Este evento usa QR dinámico. Muéstrelo desde /api/tickets/%s/qr al ingresar.", ticket.ID)),
+			"1", "C", false)
+	}
+
+	if template.FooterText != "" {
+		pdf.SetFont("Helvetica", "", geometry.textSize*0.8)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.SetXY(x, y+geometry.height+2)
+		pdf.MultiCell(geometry.width, lineHeight, tr(template.FooterText), "", "L", false)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("error generando PDF del ticket: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func eventName(event *model.Event) string {
+	if event == nil || event.Name == "" {
+		return "Ticket"
+	}
+	return event.Name
+}
+
+// ticketPDFFields devuelve las filas etiqueta/valor que se imprimen en el ticket
+func ticketPDFFields(ticket model.Ticket, event *model.Event) [][2]string {
+	venue, date := "-", "-"
+	if event != nil {
+		if event.Venue != "" {
+			venue = event.Venue
+		}
+		if !event.StartTime.IsZero() {
+			loc, err := time.LoadLocation(event.Timezone)
+			if err != nil {
+				loc = time.UTC
+			}
+			start := event.StartTime.In(loc)
+			date = fmt.Sprintf("%s %s", start.Format("02/01/2006 15:04"), start.Format("MST"))
+		}
+	}
+
+	seat := ticket.Seat
+	if seat == "" {
+		seat = "Admisión general"
+	}
+
+	return [][2]string{
+		{"Lugar", venue},
+		{"Fecha", date},
+		{"Titular", ticket.Name},
+		{"Asiento", seat},
+		{"Precio", fmt.Sprintf("$%.2f", ticket.Price)},
+		{"Ticket", ticket.TicketCode},
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPDFTicket() (model.Ticket, *model.Event) {
+	eventID := uuid.New()
+	ticket := model.Ticket{
+		ID:         uuid.New(),
+		EventID:    eventID,
+		Name:       "María Núñez",
+		TicketCode: "TKT-12345678",
+		Seat:       "Platea B-12",
+		Price:      49.9,
+	}
+	event := &model.Event{
+		ID:        eventID,
+		Name:      "Concierto de Año Nuevo",
+		Venue:     "Teatro Colón",
+		StartTime: time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC),
+		Timezone:  "America/Argentina/Buenos_Aires",
+	}
+	return ticket, event
+}
+
+func TestRenderTicketPDF_Layouts(t *testing.T) {
+	ticket, event := testPDFTicket()
+	qrPNG, err := RenderQR("contenido", DefaultQROptions())
+	require.NoError(t, err)
+
+	for _, template := range []model.TicketTemplate{
+		{Layout: model.TicketLayoutStandard, PaperSize: model.PaperSizeA4},
+		{Layout: model.TicketLayoutCompact, PaperSize: model.PaperSizeLetter, AccentColor: "#AA0000", FooterText: "No se aceptan devoluciones"},
+	} {
+		event.TicketTemplate = &template
+		data, err := RenderTicketPDF(ticket, event, qrPNG)
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(data, []byte("%PDF-")), "layout %s", template.Layout)
+		// Solo el QR: el código del ticket no se imprime como código de barras
+		assert.Equal(t, 1, bytes.Count(data, []byte("/Subtype /Image")), "layout %s", template.Layout)
+	}
+}
+
+func TestRenderTicketPDF_WithoutQRForRotatingEvents(t *testing.T) {
+	ticket, event := testPDFTicket()
+	event.RotatingQR = true
+
+	data, err := RenderTicketPDF(ticket, event, nil)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-")))
+	assert.Zero(t, bytes.Count(data, []byte("/Subtype /Image")))
+}
+
+func TestRenderTicketPDF_InvalidTemplate(t *testing.T) {
+	ticket, event := testPDFTicket()
+	event.TicketTemplate = &model.TicketTemplate{PaperSize: "A3"}
+
+	_, err := RenderTicketPDF(ticket, event, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
+)
+
+// TicketCount es la cantidad de tickets de un evento en un estado
+type TicketCount struct {
+	EventID string
+	Status  string
+}
+
+// TicketStats recuenta periódicamente los tickets por evento y estado y publica
+// el resultado en la métrica metrics.Tickets
+type TicketStats struct {
+	DB       db.Repository
+	Interval time.Duration
+	PageSize int
+}
+
+// NewTicketStats crea un recuento que recorre los tickets de a 100
+func NewTicketStats(db db.Repository, interval time.Duration) *TicketStats {
+	return &TicketStats{DB: db, Interval: interval, PageSize: 100}
+}
+
+// Run actualiza la métrica cada Interval hasta que se cancele el contexto
+func (s *TicketStats) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Publish(ctx); err != nil {
+			log.Printf("Error recontando tickets: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Count recorre todos los tickets y devuelve cuántos hay por evento y estado
+func (s *TicketStats) Count(ctx context.Context) (map[TicketCount]int, error) {
+	counts := map[TicketCount]int{}
+	cursor := ""
+	for {
+		tickets, next, err := s.DB.GetTickets(ctx, "", "", s.PageSize, cursor)
+		if err != nil {
+			return nil, fmt.Errorf("error listando tickets: %w", err)
+		}
+		for _, ticket := range tickets {
+			counts[TicketCount{EventID: ticket.EventID.String(), Status: ticket.Status}]++
+		}
+
+		if next == "" {
+			return counts, nil
+		}
+		cursor = next
+	}
+}
+
+// Publish recuenta los tickets y reemplaza los valores de la métrica. Si el
+// recuento falla se conservan los valores anteriores.
+func (s *TicketStats) Publish(ctx context.Context) error {
+	counts, err := s.Count(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Reset quita las series de eventos que ya no tienen tickets en ese estado
+	metrics.Tickets.Reset()
+	for key, n := range counts {
+		metrics.Tickets.WithLabelValues(key.EventID, key.Status).Set(float64(n))
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTicketStats_Publish(t *testing.T) {
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+	concert, festival := uuid.New(), uuid.New()
+
+	save := func(eventID uuid.UUID, status string) model.Ticket {
+		now := time.Now()
+		ticket := model.Ticket{ID: uuid.New(), EventID: eventID, TicketCode: "TKT-" + status, Status: status, CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, repo.SaveTicket(ctx, ticket))
+		return ticket
+	}
+	save(concert, model.TicketStatusConfirmed)
+	save(concert, model.TicketStatusConfirmed)
+	save(concert, model.TicketStatusUsed)
+	cancelled := save(festival, model.TicketStatusCancelled)
+
+	// Un PageSize chico obliga a recorrer varias páginas
+	stats := NewTicketStats(repo, time.Minute)
+	stats.PageSize = 2
+	require.NoError(t, stats.Publish(ctx))
+
+	assert.Equal(t, 3, testutil.CollectAndCount(metrics.Tickets))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.Tickets.WithLabelValues(concert.String(), model.TicketStatusConfirmed)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Tickets.WithLabelValues(concert.String(), model.TicketStatusUsed)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.Tickets.WithLabelValues(festival.String(), model.TicketStatusCancelled)))
+
+	// Las series que se quedan sin tickets desaparecen en el siguiente recuento
+	require.NoError(t, repo.DeleteTicket(ctx, cancelled))
+	require.NoError(t, stats.Publish(ctx))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.Tickets))
+}
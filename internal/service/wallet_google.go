@@ -0,0 +1,177 @@
+package service
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+)
+
+// GoogleWalletSaveURL es la URL base del botón "Guardar en Google Wallet"
+const GoogleWalletSaveURL = "https://pay.google.com/gp/v/save/"
+
+// GoogleWalletIssuer firma los JWT de "Guardar en Google Wallet" con la clave
+// de la cuenta de servicio del emisor
+type GoogleWalletIssuer struct {
+	IssuerID     string
+	ServiceEmail string
+	Origins      []string
+
+	key *rsa.PrivateKey
+}
+
+// LoadGoogleWalletIssuer lee el archivo JSON de la cuenta de servicio
+// (client_email y private_key) del emisor issuerID
+func LoadGoogleWalletIssuer(keyFile, issuerID string, origins []string) (*GoogleWalletIssuer, error) {
+	if issuerID == "" {
+		return nil, fmt.Errorf("ID de emisor de Google Wallet requerido")
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo cuenta de servicio %s: %v", keyFile, err)
+	}
+
+	var account struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+	}
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("cuenta de servicio inválida en %s: %v", keyFile, err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("la cuenta de servicio %s no tiene client_email o private_key", keyFile)
+	}
+
+	signer, err := parsePrivateKeyPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	key, ok := signer.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Google Wallet requiere una clave RSA")
+	}
+
+	return &GoogleWalletIssuer{
+		IssuerID:     issuerID,
+		ServiceEmail: account.ClientEmail,
+		Origins:      origins,
+		key:          key,
+	}, nil
+}
+
+// LoadGoogleWalletFromConfig carga el emisor con la cuenta de servicio de cfg.
+// Devuelve nil si no hay archivo de clave: Google Wallet queda deshabilitado.
+func LoadGoogleWalletFromConfig(cfg config.GoogleWalletConfig) (*GoogleWalletIssuer, error) {
+	if cfg.KeyFile == "" {
+		return nil, nil
+	}
+	return LoadGoogleWalletIssuer(cfg.KeyFile, cfg.IssuerID, cfg.Origins)
+}
+
+type localizedString struct {
+	DefaultValue struct {
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	} `json:"defaultValue"`
+}
+
+func localized(value string) localizedString {
+	var s localizedString
+	s.DefaultValue.Language = "es"
+	s.DefaultValue.Value = value
+	return s
+}
+
+// ClassID devuelve el ID de la clase de Google Wallet del evento
+func (g *GoogleWalletIssuer) ClassID(eventID string) string {
+	return fmt.Sprintf("%s.event-%s", g.IssuerID, eventID)
+}
+
+// ObjectID devuelve el ID del objeto de Google Wallet del ticket
+func (g *GoogleWalletIssuer) ObjectID(ticket model.Ticket) string {
+	return fmt.Sprintf("%s.ticket-%s", g.IssuerID, ticket.ID)
+}
+
+// SaveJWT genera el JWT firmado (RS256) con la clase del evento y el objeto del
+// ticket. qrContent debe ser el mismo contenido que genera QRService.
+func (g *GoogleWalletIssuer) SaveJWT(ticket model.Ticket, event *model.Event, qrContent string, now time.Time) (string, error) {
+	class := map[string]interface{}{
+		"id":           g.ClassID(ticket.EventID.String()),
+		"issuerName":   g.ServiceEmail,
+		"reviewStatus": "UNDER_REVIEW",
+		"eventName":    localized(eventName(event)),
+	}
+	if event != nil {
+		if event.Venue != "" {
+			class["venue"] = map[string]interface{}{"name": localized(event.Venue)}
+		}
+		if !event.StartTime.IsZero() {
+			class["dateTime"] = map[string]string{"start": event.StartTime.UTC().Format(time.RFC3339)}
+		}
+	}
+
+	object := map[string]interface{}{
+		"id":               g.ObjectID(ticket),
+		"classId":          class["id"],
+		"state":            "ACTIVE",
+		"ticketHolderName": ticket.Name,
+		"ticketNumber":     ticket.TicketCode,
+		"barcode": map[string]string{
+			"type":          "QR_CODE",
+			"value":         qrContent,
+			"alternateText": ticket.TicketCode,
+		},
+	}
+	if ticket.Seat != "" {
+		object["seatInfo"] = map[string]interface{}{"seat": localized(ticket.Seat)}
+	}
+
+	claims := map[string]interface{}{
+		"iss": g.ServiceEmail,
+		"aud": "google",
+		"typ": "savetowallet",
+		"iat": now.Unix(),
+		"payload": map[string]interface{}{
+			"eventTicketClasses": []interface{}{class},
+			"eventTicketObjects": []interface{}{object},
+		},
+	}
+	if len(g.Origins) > 0 {
+		claims["origins"] = g.Origins
+	}
+
+	return g.signJWT(claims)
+}
+
+func (g *GoogleWalletIssuer) signJWT(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("error generando JWT de Google Wallet: %v", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, g.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("error firmando JWT de Google Wallet: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// PublicKey devuelve la clave pública con la que se verifican los JWT
+func (g *GoogleWalletIssuer) PublicKey() *rsa.PublicKey {
+	return &g.key.PublicKey
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"go.mozilla.org/pkcs7"
+)
+
+// PassSigner genera y firma pases .pkpass de Apple Wallet con el certificado
+// del Pass Type ID y el certificado intermedio WWDR de Apple
+type PassSigner struct {
+	PassTypeID   string
+	TeamID       string
+	Organization string
+
+	cert *x509.Certificate
+	key  crypto.Signer
+	wwdr *x509.Certificate
+}
+
+// LoadPassSigner lee el certificado, su clave privada y el certificado WWDR en
+// formato PEM. Para pruebas sin conexión sirven certificados autofirmados.
+func LoadPassSigner(certFile, keyFile, wwdrFile, passTypeID, teamID, organization string) (*PassSigner, error) {
+	if passTypeID == "" || teamID == "" {
+		return nil, fmt.Errorf("passTypeIdentifier y teamIdentifier son requeridos")
+	}
+
+	cert, err := loadCertificatePEM(certFile)
+	if err != nil {
+		return nil, err
+	}
+	wwdr, err := loadCertificatePEM(wwdrFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadPrivateKeyPEM(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if organization == "" {
+		organization = teamID
+	}
+	return &PassSigner{
+		PassTypeID:   passTypeID,
+		TeamID:       teamID,
+		Organization: organization,
+		cert:         cert,
+		key:          key,
+		wwdr:         wwdr,
+	}, nil
+}
+
+// LoadPassSignerFromConfig carga el firmante con los archivos de cfg. Devuelve
+// nil si no hay certificado: Apple Wallet queda deshabilitado.
+func LoadPassSignerFromConfig(cfg config.AppleWalletConfig) (*PassSigner, error) {
+	if cfg.CertFile == "" {
+		return nil, nil
+	}
+	return LoadPassSigner(cfg.CertFile, cfg.KeyFile, cfg.WWDRFile, cfg.PassTypeID, cfg.TeamID, cfg.Organization)
+}
+
+type passField struct {
+	Key       string `json:"key"`
+	Label     string `json:"label"`
+	Value     string `json:"value"`
+	DateStyle string `json:"dateStyle,omitempty"`
+	TimeStyle string `json:"timeStyle,omitempty"`
+}
+
+type passBarcode struct {
+	Format          string `json:"format"`
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+	AltText         string `json:"altText,omitempty"`
+}
+
+type passStructure struct {
+	HeaderFields    []passField `json:"headerFields,omitempty"`
+	PrimaryFields   []passField `json:"primaryFields"`
+	SecondaryFields []passField `json:"secondaryFields,omitempty"`
+	AuxiliaryFields []passField `json:"auxiliaryFields,omitempty"`
+}
+
+type passJSON struct {
+	FormatVersion      int           `json:"formatVersion"`
+	PassTypeIdentifier string        `json:"passTypeIdentifier"`
+	SerialNumber       string        `json:"serialNumber"`
+	TeamIdentifier     string        `json:"teamIdentifier"`
+	OrganizationName   string        `json:"organizationName"`
+	Description        string        `json:"description"`
+	RelevantDate       string        `json:"relevantDate,omitempty"`
+	BackgroundColor    string        `json:"backgroundColor"`
+	ForegroundColor    string        `json:"foregroundColor"`
+	LabelColor         string        `json:"labelColor"`
+	Barcodes           []passBarcode `json:"barcodes"`
+	EventTicket        passStructure `json:"eventTicket"`
+}
+
+// BuildPass arma el .pkpass del ticket: pass.json, íconos, manifest.json con el
+// SHA-1 de cada archivo y signature con la firma PKCS#7 separada del manifiesto.
+// qrContent debe ser el mismo contenido que genera QRService para el ticket.
+func (p *PassSigner) BuildPass(ticket model.Ticket, event *model.Event, qrContent string) ([]byte, error) {
+	template := model.DefaultTicketTemplate()
+	if event != nil && event.TicketTemplate != nil {
+		template = *event.TicketTemplate
+	}
+	if template.Validate() != "" {
+		template = model.DefaultTicketTemplate()
+	}
+	red, green, blue, _ := template.RGB()
+
+	seat := ticket.Seat
+	if seat == "" {
+		seat = "Admisión general"
+	}
+
+	pass := passJSON{
+		FormatVersion:      1,
+		PassTypeIdentifier: p.PassTypeID,
+		SerialNumber:       ticket.ID.String(),
+		TeamIdentifier:     p.TeamID,
+		OrganizationName:   p.Organization,
+		Description:        fmt.Sprintf("Ticket para %s", eventName(event)),
+		BackgroundColor:    fmt.Sprintf("rgb(%d,%d,%d)", red, green, blue),
+		ForegroundColor:    "rgb(255,255,255)",
+		LabelColor:         "rgb(255,255,255)",
+		Barcodes: []passBarcode{{
+			Format:          "PKBarcodeFormatQR",
+			Message:         qrContent,
+			MessageEncoding: "iso-8859-1",
+			AltText:         ticket.TicketCode,
+		}},
+		EventTicket: passStructure{
+			PrimaryFields: []passField{{Key: "event", Label: "Evento", Value: eventName(event)}},
+			AuxiliaryFields: []passField{
+				{Key: "holder", Label: "Titular", Value: ticket.Name},
+				{Key: "seat", Label: "Asiento", Value: seat},
+			},
+		},
+	}
+	if event != nil {
+		if event.Venue != "" {
+			pass.EventTicket.SecondaryFields = append(pass.EventTicket.SecondaryFields,
+				passField{Key: "venue", Label: "Lugar", Value: event.Venue})
+		}
+		if !event.StartTime.IsZero() {
+			start := event.StartTime.UTC().Format(time.RFC3339)
+			pass.RelevantDate = start
+			pass.EventTicket.SecondaryFields = append(pass.EventTicket.SecondaryFields, passField{
+				Key: "date", Label: "Fecha", Value: start,
+				DateStyle: "PKDateStyleMedium", TimeStyle: "PKDateStyleShort",
+			})
+		}
+	}
+
+	passData, err := json.Marshal(pass)
+	if err != nil {
+		return nil, fmt.Errorf("error generando pass.json: %v", err)
+	}
+
+	files := map[string][]byte{"pass.json": passData}
+	for name, side := range map[string]int{"icon.png": 29, "icon@2x.png": 58, "icon@3x.png": 87} {
+		icon, err := renderPassIcon(side, color.RGBA{R: uint8(red), G: uint8(green), B: uint8(blue), A: 255})
+		if err != nil {
+			return nil, err
+		}
+		files[name] = icon
+	}
+
+	manifest := make(map[string]string, len(files))
+	for name, data := range files {
+		sum := sha1.Sum(data)
+		manifest[name] = hex.EncodeToString(sum[:])
+	}
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error generando manifest.json: %v", err)
+	}
+
+	signature, err := p.sign(manifestData)
+	if err != nil {
+		return nil, err
+	}
+	files["manifest.json"] = manifestData
+	files["signature"] = signature
+
+	return zipPassFiles(files)
+}
+
+// sign devuelve la firma PKCS#7 separada (DER) del manifiesto
+func (p *PassSigner) sign(manifest []byte) ([]byte, error) {
+	signedData, err := pkcs7.NewSignedData(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("error preparando firma del pase: %v", err)
+	}
+	signedData.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := signedData.AddSignerChain(p.cert, p.key, []*x509.Certificate{p.wwdr}, pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, fmt.Errorf("error firmando el pase: %v", err)
+	}
+	signedData.Detach()
+
+	signature, err := signedData.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("error firmando el pase: %v", err)
+	}
+	return signature, nil
+}
+
+func zipPassFiles(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("error generando pkpass: %v", err)
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, fmt.Errorf("error generando pkpass: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("error generando pkpass: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderPassIcon dibuja un ícono cuadrado con el color de acento del evento
+func renderPassIcon(side int, accent color.RGBA) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: accent}, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("error generando ícono del pase: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func loadCertificatePEM(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo certificado %s: %v", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s no contiene un certificado PEM", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("certificado inválido en %s: %v", path, err)
+	}
+	return cert, nil
+}
+
+// loadPrivateKeyPEM acepta claves RSA o ECDSA en PKCS#1, SEC 1 o PKCS#8
+func loadPrivateKeyPEM(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo clave privada %s: %v", path, err)
+	}
+	return parsePrivateKeyPEM(data)
+}
+
+func parsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("la clave privada no está en formato PEM")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("clave privada inválida: %v", err)
+		}
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return k, nil
+		case *ecdsa.PrivateKey:
+			return k, nil
+		}
+		return nil, fmt.Errorf("tipo de clave privada no soportado: %T", key)
+	}
+	return nil, fmt.Errorf("bloque PEM no soportado: %s", block.Type)
+}
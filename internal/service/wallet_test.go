@@ -0,0 +1,178 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mozilla.org/pkcs7"
+)
+
+// writeTestPassCerts genera una CA autofirmada (en lugar del WWDR de Apple) y un
+// certificado de pase firmado por ella, y los guarda en PEM en dir
+func writeTestPassCerts(t *testing.T, dir string) (certFile, keyFile, wwdrFile string) {
+	t.Helper()
+
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test WWDR"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	passKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	passTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.com.example.tickets"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	passDER, err := x509.CreateCertificate(rand.Reader, passTemplate, caCert, &passKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "pass.pem")
+	keyFile = filepath.Join(dir, "pass.key")
+	wwdrFile = filepath.Join(dir, "wwdr.pem")
+	writePEM(t, certFile, "CERTIFICATE", passDER)
+	writePEM(t, keyFile, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(passKey))
+	writePEM(t, wwdrFile, "CERTIFICATE", caDER)
+	return certFile, keyFile, wwdrFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+}
+
+func TestPassSigner_BuildPass(t *testing.T) {
+	certFile, keyFile, wwdrFile := writeTestPassCerts(t, t.TempDir())
+	signer, err := LoadPassSigner(certFile, keyFile, wwdrFile, "pass.com.example.tickets", "TEAM123", "Tickets")
+	require.NoError(t, err)
+
+	ticket, event := testPDFTicket()
+	qrSigner, err := NewQRSigner("k1", map[string][]byte{"k1": bytes.Repeat([]byte("a"), 32)})
+	require.NoError(t, err)
+	qr := NewQRService(qrSigner)
+	content := qr.GenerateQRContent(ticket)
+
+	data, err := signer.BuildPass(ticket, event, content)
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+	require.Contains(t, files, "pass.json")
+	require.Contains(t, files, "icon.png")
+
+	var pass passJSON
+	require.NoError(t, json.Unmarshal(files["pass.json"], &pass))
+	assert.Equal(t, ticket.ID.String(), pass.SerialNumber)
+	assert.Equal(t, content, pass.Barcodes[0].Message)
+
+	var manifest map[string]string
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	for name, data := range files {
+		if name == "manifest.json" || name == "signature" {
+			continue
+		}
+		sum := sha1.Sum(data)
+		assert.Equal(t, hex.EncodeToString(sum[:]), manifest[name], name)
+	}
+
+	p7, err := pkcs7.Parse(files["signature"])
+	require.NoError(t, err)
+	p7.Content = files["manifest.json"]
+	assert.NoError(t, p7.Verify())
+	assert.Len(t, p7.Certificates, 2)
+}
+
+func TestLoadPassSigner_MissingFiles(t *testing.T) {
+	_, err := LoadPassSigner("/no/existe.pem", "/no/existe.key", "/no/wwdr.pem", "pass.com.example", "TEAM", "")
+	assert.Error(t, err)
+}
+
+func TestGoogleWalletIssuer_SaveJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	account, err := json.Marshal(map[string]string{
+		"client_email": "wallet@example.iam.gserviceaccount.com",
+		"private_key":  string(keyPEM),
+	})
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "service-account.json")
+	require.NoError(t, os.WriteFile(keyFile, account, 0o600))
+
+	issuer, err := LoadGoogleWalletIssuer(keyFile, "3388000000000000000", []string{"https://tickets.example.com"})
+	require.NoError(t, err)
+
+	ticket, event := testPDFTicket()
+	token, err := issuer.SaveJWT(ticket, event, "TKT1.contenido", time.Now())
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(issuer.PublicKey(), crypto.SHA256, digest[:], signature))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims struct {
+		Aud     string `json:"aud"`
+		Typ     string `json:"typ"`
+		Payload struct {
+			EventTicketObjects []struct {
+				ID      string `json:"id"`
+				ClassID string `json:"classId"`
+				Barcode struct {
+					Value string `json:"value"`
+				} `json:"barcode"`
+			} `json:"eventTicketObjects"`
+		} `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, "google", claims.Aud)
+	assert.Equal(t, "savetowallet", claims.Typ)
+	require.Len(t, claims.Payload.EventTicketObjects, 1)
+	assert.Equal(t, "TKT1.contenido", claims.Payload.EventTicketObjects[0].Barcode.Value)
+	assert.Equal(t, issuer.ClassID(ticket.EventID.String()), claims.Payload.EventTicketObjects[0].ClassID)
+}
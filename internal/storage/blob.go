@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrBlobNotFound indica que la clave pedida no existe en el almacenamiento
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobInfo describe un archivo guardado
+type BlobInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// BlobStore abstrae dónde se guardan los archivos de los tickets (QR, PDF, logos).
+// S3Client y LocalStore la implementan; Get y Head devuelven ErrBlobNotFound
+// si la clave no existe.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+	Head(ctx context.Context, key string) (*BlobInfo, error)
+	Presign(ctx context.Context, key string, opts PresignOptions) (string, time.Time, error)
+	// Ping comprueba que el almacenamiento está accesible, para /readyz
+	Ping(ctx context.Context) error
+}
+
+// PresignOptions ajusta la URL prefirmada. Expiry se limita a la vigencia
+// máxima configurada en el almacenamiento.
+type PresignOptions struct {
+	Expiry      time.Duration
+	ContentType string
+	Filename    string
+}
+
+// DefaultPresignExpiry es la vigencia de las URLs prefirmadas si no se configura otra
+const DefaultPresignExpiry = 15 * time.Minute
+
+// presignExpiry devuelve la vigencia a usar: la pedida si es menor que la máxima
+func presignExpiry(max, requested time.Duration) time.Duration {
+	if max <= 0 {
+		max = DefaultPresignExpiry
+	}
+	if requested > 0 && requested < max {
+		return requested
+	}
+	return max
+}
+
+var (
+	_ BlobStore = (*S3Client)(nil)
+	_ BlobStore = (*LocalStore)(nil)
+)
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
+)
+
+// NewBlobStore crea el almacenamiento indicado en appCfg.Storage.Backend. client solo
+// se usa con el backend s3. El backend local firma sus URLs con SigningKey (base64,
+// al menos 32 bytes) y las sirve desde PublicURL (/api/blobs de la API).
+func NewBlobStore(ctx context.Context, appCfg *config.Config, client *s3.Client) (BlobStore, error) {
+	cfg := appCfg.Storage
+	switch cfg.Backend {
+	case config.StorageS3:
+		store := &S3Client{
+			Client:        client,
+			BucketName:    cfg.Bucket,
+			PresignExpiry: cfg.PresignExpiry,
+			Timeout:       appCfg.AWS.CallTimeout,
+		}
+		log.Println("Verificando bucket S3...")
+		if err := store.EnsureBucketExists(ctx); err != nil {
+			return nil, err
+		}
+		log.Printf("Bucket S3 '%s' listo", store.BucketName)
+		return store, nil
+
+	case config.StorageLocal:
+		var key []byte
+		if cfg.SigningKey != "" {
+			decoded, err := base64.StdEncoding.DecodeString(cfg.SigningKey)
+			if err != nil {
+				return nil, fmt.Errorf("STORAGE_SIGNING_KEY no es base64 válido: %v", err)
+			}
+			key = decoded
+		} else {
+			log.Println("⚠️  STORAGE_SIGNING_KEY no definida: las URLs de descarga dejan de valer al reiniciar")
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return nil, fmt.Errorf("error generando clave de firma: %v", err)
+			}
+		}
+
+		store, err := NewLocalStore(cfg.LocalDir, cfg.PublicURL, key)
+		if err != nil {
+			return nil, err
+		}
+		store.PresignExpiry = cfg.PresignExpiry
+		log.Printf("Usando almacenamiento local en '%s'", cfg.LocalDir)
+		return store, nil
+
+	default:
+		return nil, fmt.Errorf("STORAGE_BACKEND desconocido: %q (use 's3' o 'local')", cfg.Backend)
+	}
+}
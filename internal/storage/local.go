@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidBlobKey       = errors.New("invalid blob key")
+	ErrInvalidBlobSignature = errors.New("invalid blob signature")
+	ErrExpiredBlobSignature = errors.New("expired blob signature")
+)
+
+const (
+	localTempPrefix          = ".tmp-"
+	localPresignSignatureKey = "signature"
+)
+
+// LocalStore guarda los archivos en un directorio local (por ejemplo un volumen
+// montado). Las URLs prefirmadas apuntan a BaseURL, que debe servir la API con
+// ServeBlob, y se firman con HMAC-SHA256.
+type LocalStore struct {
+	Root    string
+	BaseURL string
+	// PresignExpiry es la vigencia máxima de las URLs prefirmadas
+	PresignExpiry time.Duration
+
+	signingKey []byte
+}
+
+// NewLocalStore crea el directorio root si no existe
+func NewLocalStore(root, baseURL string, signingKey []byte) (*LocalStore, error) {
+	if len(signingKey) < 32 {
+		return nil, fmt.Errorf("la clave de firma del almacenamiento local debe tener al menos 32 bytes")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("error creando directorio de almacenamiento '%s': %v", root, err)
+	}
+	return &LocalStore{
+		Root:       root,
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		signingKey: signingKey,
+	}, nil
+}
+
+// path convierte la clave en una ruta dentro de Root, rechazando rutas absolutas y ".."
+func (s *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidBlobKey, key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." || strings.HasPrefix(part, localTempPrefix) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidBlobKey, key)
+		}
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(key)), nil
+}
+
+// Put escribe en un archivo temporal y lo renombra, así un lector nunca ve un archivo a medias
+func (s *LocalStore) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("error creando directorio para '%s': %v", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), localTempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("error guardando archivo '%s': %v", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error guardando archivo '%s': %v", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error guardando archivo '%s': %v", key, err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("error guardando archivo '%s': %v", key, err)
+	}
+	return nil
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: el archivo '%s' no existe", ErrBlobNotFound, key)
+		}
+		return nil, fmt.Errorf("error leyendo archivo '%s': %v", key, err)
+	}
+	return file, nil
+}
+
+// Delete borra el archivo; igual que en S3, borrar una clave inexistente no es un error
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error borrando archivo '%s': %v", key, err)
+	}
+	return nil
+}
+
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]BlobInfo, error) {
+	var blobs []BlobInfo
+	err := filepath.WalkDir(s.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), localTempPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.Root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, localBlobInfo(key, info))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listando archivos en '%s': %v", s.Root, err)
+	}
+	return blobs, nil
+}
+
+func (s *LocalStore) Head(ctx context.Context, key string) (*BlobInfo, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, key)
+		}
+		return nil, fmt.Errorf("error consultando archivo '%s': %v", key, err)
+	}
+	blob := localBlobInfo(key, info)
+	return &blob, nil
+}
+
+// Ping comprueba que el directorio raíz existe
+func (s *LocalStore) Ping(ctx context.Context) error {
+	info, err := os.Stat(s.Root)
+	if err != nil {
+		return fmt.Errorf("error consultando directorio de almacenamiento '%s': %v", s.Root, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' no es un directorio", s.Root)
+	}
+	return nil
+}
+
+func localBlobInfo(key string, info fs.FileInfo) BlobInfo {
+	return BlobInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+		LastModified: info.ModTime(),
+	}
+}
+
+// Presign genera una URL firmada hacia BaseURL/<key>
+func (s *LocalStore) Presign(ctx context.Context, key string, opts PresignOptions) (string, time.Time, error) {
+	if _, err := s.path(key); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(presignExpiry(s.PresignExpiry, opts.Expiry)).Truncate(time.Second)
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	if opts.ContentType != "" {
+		query.Set("content_type", opts.ContentType)
+	}
+	if opts.Filename != "" {
+		query.Set("filename", opts.Filename)
+	}
+	query.Set(localPresignSignatureKey, s.signature(key, query))
+
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return fmt.Sprintf("%s/%s?%s", s.BaseURL, strings.Join(segments, "/"), query.Encode()), expiresAt, nil
+}
+
+// VerifyPresigned comprueba la firma y el vencimiento de una URL generada por Presign
+func (s *LocalStore) VerifyPresigned(key string, query url.Values, now time.Time) error {
+	if _, err := s.path(key); err != nil {
+		return err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(query.Get(localPresignSignatureKey))
+	if err != nil {
+		return ErrInvalidBlobSignature
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(s.signature(key, query))
+	if !hmac.Equal(signature, expected) {
+		return ErrInvalidBlobSignature
+	}
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return ErrInvalidBlobSignature
+	}
+	if now.Unix() > expires {
+		return ErrExpiredBlobSignature
+	}
+	return nil
+}
+
+func (s *LocalStore) signature(key string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", key, query.Get("expires"), query.Get("content_type"), query.Get("filename"))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
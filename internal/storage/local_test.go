@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLocalStore(t *testing.T) *LocalStore {
+	t.Helper()
+	store, err := NewLocalStore(t.TempDir(), "http://localhost:8080/api/blobs/", bytes.Repeat([]byte("s"), 32))
+	require.NoError(t, err)
+	return store
+}
+
+func TestLocalStore_PutGetHeadListDelete(t *testing.T) {
+	store := testLocalStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "qrcodes/a.png", strings.NewReader("png"), "image/png"))
+	require.NoError(t, store.Put(ctx, "tickets/a.pdf", strings.NewReader("pdf"), "application/pdf"))
+
+	body, err := store.Get(ctx, "qrcodes/a.png")
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "png", string(data))
+
+	info, err := store.Head(ctx, "tickets/a.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), info.Size)
+	assert.Equal(t, "application/pdf", info.ContentType)
+
+	blobs, err := store.List(ctx, "qrcodes/")
+	require.NoError(t, err)
+	require.Len(t, blobs, 1)
+	assert.Equal(t, "qrcodes/a.png", blobs[0].Key)
+
+	require.NoError(t, store.Delete(ctx, "qrcodes/a.png"))
+	require.NoError(t, store.Delete(ctx, "qrcodes/a.png"))
+	_, err = store.Get(ctx, "qrcodes/a.png")
+	assert.True(t, errors.Is(err, ErrBlobNotFound))
+	_, err = store.Head(ctx, "qrcodes/a.png")
+	assert.True(t, errors.Is(err, ErrBlobNotFound))
+}
+
+func TestLocalStore_RejectsPathTraversal(t *testing.T) {
+	store := testLocalStore(t)
+
+	for _, key := range []string{"../secret", "/etc/passwd", "a/../../b", "a//b", ""} {
+		err := store.Put(context.Background(), key, strings.NewReader("x"), "")
+		assert.True(t, errors.Is(err, ErrInvalidBlobKey), key)
+	}
+}
+
+func TestLocalStore_Presign(t *testing.T) {
+	store := testLocalStore(t)
+	store.PresignExpiry = time.Minute
+
+	rawURL, expiresAt, err := store.Presign(context.Background(), "tickets/a b.pdf", PresignOptions{Filename: "ticket.pdf"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(rawURL, "http://localhost:8080/api/blobs/tickets/a%20b.pdf?"))
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	query := u.Query()
+	assert.NoError(t, store.VerifyPresigned("tickets/a b.pdf", query, time.Now()))
+	assert.ErrorIs(t, store.VerifyPresigned("tickets/a b.pdf", query, expiresAt.Add(time.Second)), ErrExpiredBlobSignature)
+	assert.ErrorIs(t, store.VerifyPresigned("tickets/otro.pdf", query, time.Now()), ErrInvalidBlobSignature)
+
+	query.Set("filename", "cambiado.pdf")
+	assert.ErrorIs(t, store.VerifyPresigned("tickets/a b.pdf", query, time.Now()), ErrInvalidBlobSignature)
+}
@@ -2,67 +2,219 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
 )
 
 type S3Client struct {
 	Client     *s3.Client
 	BucketName string
+	// PresignExpiry es la vigencia máxima de las URLs prefirmadas
+	PresignExpiry time.Duration
+	// Timeout limita cada llamada a S3; en Get incluye la lectura del cuerpo
+	Timeout time.Duration
 }
 
-func (s *S3Client) UploadTicketFile(ctx context.Context, key string, body io.Reader) error {
-	_, err := s.Client.PutObject(ctx, &s3.PutObjectInput{
+// callContext deriva del contexto del llamador el contexto de una llamada a S3
+func (s *S3Client) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
+
+// cancelOnClose libera el contexto de la llamada cuando se cierra el cuerpo
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func (s *S3Client) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	ctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(s.BucketName),
 		Key:    aws.String(key),
 		Body:   body,
-	})
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	_, err := s.Client.PutObject(ctx, input)
 	if err != nil {
 		// Proporcionar mensajes de error más específicos
 		var errorMsg string
 		switch {
 		case strings.Contains(err.Error(), "NoSuchBucket"):
-			errorMsg = fmt.Sprintf("El bucket S3 '%s' no existe. Verifique que LocalStack esté ejecutándose y el bucket haya sido creado.", s.BucketName)
+			errorMsg = fmt.Sprintf("El bucket S3 '%s' no existe. Verifique que el bucket haya sido creado.", s.BucketName)
 		case strings.Contains(err.Error(), "RequestCanceled"):
-			errorMsg = "Error de conexión con S3. Verifique que LocalStack esté ejecutándose en http://localhost:4566."
+			errorMsg = "Error de conexión con S3. Verifique el endpoint configurado."
 		case strings.Contains(err.Error(), "AccessDenied"):
 			errorMsg = fmt.Sprintf("Acceso denegado al bucket S3 '%s'. Verifique los permisos.", s.BucketName)
 		default:
 			errorMsg = fmt.Sprintf("Error subiendo archivo a S3: %v", err)
 		}
-		return fmt.Errorf(errorMsg)
+		return errors.New(errorMsg)
 	}
 	return nil
 }
 
-func (s *S3Client) DownloadTicketFile(ctx context.Context, key string) (io.ReadCloser, error) {
+func (s *S3Client) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	ctx, cancel := s.callContext(ctx)
+
 	resp, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.BucketName),
 		Key:    aws.String(key),
 	})
 	if err != nil {
+		cancel()
+		var noSuchKey *types.NoSuchKey
 		var errorMsg string
 		switch {
+		case errors.As(err, &noSuchKey) || strings.Contains(err.Error(), "NoSuchKey"):
+			return nil, fmt.Errorf("%w: el archivo '%s' no existe en el bucket S3 '%s'", ErrBlobNotFound, key, s.BucketName)
 		case strings.Contains(err.Error(), "NoSuchBucket"):
 			errorMsg = fmt.Sprintf("El bucket S3 '%s' no existe.", s.BucketName)
-		case strings.Contains(err.Error(), "NoSuchKey"):
-			errorMsg = fmt.Sprintf("El archivo '%s' no existe en el bucket S3 '%s'.", key, s.BucketName)
 		case strings.Contains(err.Error(), "RequestCanceled"):
-			errorMsg = "Error de conexión con S3. Verifique que LocalStack esté ejecutándose."
+			errorMsg = "Error de conexión con S3. Verifique el endpoint configurado."
 		default:
 			errorMsg = fmt.Sprintf("Error descargando archivo de S3: %v", err)
 		}
-		return nil, fmt.Errorf(errorMsg)
+		return nil, errors.New(errorMsg)
+	}
+	return &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+func (s *S3Client) Delete(ctx context.Context, key string) error {
+	ctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	_, err := s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("error borrando archivo '%s' de S3: %v", key, err)
+	}
+	return nil
+}
+
+// List devuelve todos los archivos cuya clave empieza con prefix
+func (s *S3Client) List(ctx context.Context, prefix string) ([]BlobInfo, error) {
+	ctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	paginator := s3.NewListObjectsV2Paginator(s.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.BucketName),
+		Prefix: aws.String(prefix),
+	})
+
+	var blobs []BlobInfo
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listando archivos de S3: %v", err)
+		}
+		for _, obj := range page.Contents {
+			blobs = append(blobs, BlobInfo{
+				Key:          aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
+		}
 	}
-	return resp.Body, nil
+	return blobs, nil
+}
+
+// Head devuelve los metadatos del archivo sin descargarlo
+func (s *S3Client) Head(ctx context.Context, key string) (*BlobInfo, error) {
+	ctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	resp, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) || strings.Contains(err.Error(), "NotFound") {
+			return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, key)
+		}
+		return nil, fmt.Errorf("error consultando archivo '%s' en S3: %v", key, err)
+	}
+	return &BlobInfo{
+		Key:          key,
+		Size:         aws.ToInt64(resp.ContentLength),
+		ContentType:  aws.ToString(resp.ContentType),
+		LastModified: aws.ToTime(resp.LastModified),
+	}, nil
+}
+
+// Presign genera una URL prefirmada para descargar key directamente de S3.
+// Devuelve la URL y el momento en que deja de ser válida.
+func (s *S3Client) Presign(ctx context.Context, key string, opts PresignOptions) (string, time.Time, error) {
+	expiry := presignExpiry(s.PresignExpiry, opts.Expiry)
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	}
+	if opts.ContentType != "" {
+		input.ResponseContentType = aws.String(opts.ContentType)
+	}
+	if opts.Filename != "" {
+		input.ResponseContentDisposition = aws.String(fmt.Sprintf("inline; filename=%s", opts.Filename))
+	}
+
+	expiresAt := time.Now().Add(expiry)
+	// La firma es local: no se cuenta como llamada a S3 ni abre un span
+	presigner := s3.NewPresignClient(s.Client, func(o *s3.PresignOptions) {
+		o.ClientOptions = append(o.ClientOptions, func(so *s3.Options) {
+			so.APIOptions = append(so.APIOptions, metrics.RemoveAWSMiddleware, tracing.RemoveAWSMiddleware)
+		})
+	})
+	req, err := presigner.PresignGetObject(ctx, input, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("error generando URL prefirmada para '%s': %v", key, err)
+	}
+	return req.URL, expiresAt, nil
+}
+
+// Ping comprueba que el bucket existe y es accesible con las credenciales actuales
+func (s *S3Client) Ping(ctx context.Context) error {
+	ctx, cancel := s.callContext(ctx)
+	defer cancel()
+
+	_, err := s.Client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s.BucketName),
+	})
+	if err != nil {
+		return fmt.Errorf("error consultando bucket S3 '%s': %w", s.BucketName, err)
+	}
+	return nil
 }
 
 // EnsureBucketExists verifica que el bucket existe y lo crea si es necesario
 func (s *S3Client) EnsureBucketExists(ctx context.Context) error {
+	ctx, cancel := s.callContext(ctx)
+	defer cancel()
+
 	_, err := s.Client.HeadBucket(ctx, &s3.HeadBucketInput{
 		Bucket: aws.String(s.BucketName),
 	})
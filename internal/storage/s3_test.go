@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jhonathanssegura/ticket-reservation/internal/metrics"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func testS3Client(expiry time.Duration) *S3Client {
+	return testS3ClientAt("http://localhost:4566", expiry)
+}
+
+func testS3ClientAt(endpoint string, expiry time.Duration) *S3Client {
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(endpoint),
+		UsePathStyle: true,
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"}, nil
+		}),
+	})
+	return &S3Client{Client: client, BucketName: "ticket-bucket", PresignExpiry: expiry}
+}
+
+func TestS3Presign(t *testing.T) {
+	s := testS3Client(10 * time.Minute)
+
+	rawURL, expiresAt, err := s.Presign(context.Background(), "qrcodes/abc.png", PresignOptions{
+		ContentType: "image/png",
+		Filename:    "qr-abc.png",
+	})
+	require.NoError(t, err)
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	assert.Equal(t, "/ticket-bucket/qrcodes/abc.png", u.Path)
+	assert.Equal(t, "600", u.Query().Get("X-Amz-Expires"))
+	assert.Equal(t, "image/png", u.Query().Get("response-content-type"))
+	assert.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), expiresAt, 5*time.Second)
+}
+
+func TestS3Presign_ExpiryCappedByConfig(t *testing.T) {
+	s := testS3Client(time.Minute)
+
+	rawURL, _, err := s.Presign(context.Background(), "tickets/abc.pdf", PresignOptions{Expiry: time.Hour})
+	require.NoError(t, err)
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	assert.Equal(t, "60", u.Query().Get("X-Amz-Expires"))
+
+	rawURL, _, err = s.Presign(context.Background(), "tickets/abc.pdf", PresignOptions{Expiry: 30 * time.Second})
+	require.NoError(t, err)
+	u, err = url.Parse(rawURL)
+	require.NoError(t, err)
+	assert.Equal(t, "30", u.Query().Get("X-Amz-Expires"))
+}
+
+func TestS3Presign_NotInstrumented(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	s := testS3Client(time.Minute)
+	s.Client = s3.New(s.Client.Options(), func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, metrics.AddAWSMiddleware, tracing.AddAWSMiddleware)
+	})
+
+	before := testutil.ToFloat64(metrics.AWSCalls.WithLabelValues("S3", "GetObject"))
+	_, _, err := s.Presign(context.Background(), "tickets/abc.pdf", PresignOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, before, testutil.ToFloat64(metrics.AWSCalls.WithLabelValues("S3", "GetObject")))
+	assert.Empty(t, recorder.Ended())
+}
+
+func TestS3Client_CallTimeout(t *testing.T) {
+	// Un S3 que no responde: la llamada debe cortarse al vencer Timeout
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	s := testS3ClientAt(server.URL, time.Minute)
+	s.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := s.Head(context.Background(), "qrcodes/abc.png")
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestS3Client_CallerCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	s := testS3ClientAt(server.URL, time.Minute)
+	s.Timeout = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := s.Put(ctx, "tickets/abc.pdf", nil, "application/pdf")
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
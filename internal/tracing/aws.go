@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+)
+
+// AddAWSMiddleware agrega un span por operación a un cliente del SDK y propaga
+// el contexto en la petición. Se usa en APIOptions:
+// o.APIOptions = append(o.APIOptions, tracing.AddAWSMiddleware)
+func AddAWSMiddleware(stack *middleware.Stack) error {
+	var options []func(*middleware.Stack) error
+	otelaws.AppendMiddlewares(&options)
+	for _, add := range options {
+		if err := add(stack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveAWSMiddleware quita los spans de una operación que no llega a AWS,
+// como las URLs prefirmadas, que se firman en local.
+func RemoveAWSMiddleware(stack *middleware.Stack) error {
+	// IDs de los pasos que agrega otelaws
+	for _, id := range []string{"OTelInitializeMiddlewareBefore", "OTelInitializeMiddlewareAfter"} {
+		if _, ok := stack.Initialize.Get(id); ok {
+			if _, err := stack.Initialize.Remove(id); err != nil {
+				return err
+			}
+		}
+	}
+	if _, ok := stack.Finalize.Get("OTelFinalizeMiddleware"); ok {
+		if _, err := stack.Finalize.Remove("OTelFinalizeMiddleware"); err != nil {
+			return err
+		}
+	}
+	if _, ok := stack.Deserialize.Get("OTelDeserializeMiddleware"); ok {
+		if _, err := stack.Deserialize.Remove("OTelDeserializeMiddleware"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSetup_StdoutToFile(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	path := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Setup(context.Background(), config.TracingConfig{
+		Exporter:    config.TracingStdout,
+		File:        path,
+		SampleRatio: 1,
+	}, "ticket-reservation-test")
+	require.NoError(t, err)
+
+	ctx, parent := Start(context.Background(), "reservation")
+	_, child := Start(ctx, "qr.render")
+	End(child, errors.New("tamaño inválido"))
+	End(parent, nil)
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name": "qr.render"`)
+	assert.Contains(t, string(data), "tamaño inválido")
+	assert.Contains(t, string(data), "ticket-reservation-test")
+}
+
+func TestSetup_NoneStillPropagates(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: config.TracingNone}, "ticket-reservation-test")
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	// Un contexto recibido de otro servicio se reenvía aunque no se exporte nada
+	incoming := map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	ctx := Extract(context.Background(), incoming)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.SpanContextFromContext(ctx).TraceID().String())
+	assert.Equal(t, incoming, Inject(ctx))
+
+	assert.Nil(t, Inject(context.Background()))
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), config.TracingConfig{Exporter: "jaeger"}, "ticket-reservation-test")
+	assert.Error(t, err)
+}
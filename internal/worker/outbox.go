@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/jhonathanssegura/ticket-reservation/internal/queue"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultOutboxMaxAttempts es cuántas publicaciones fallidas se toleran antes
+// de apartar un mensaje
+const DefaultOutboxMaxAttempts = 10
+
+// OutboxRelay publica en SQS los mensajes de outbox pendientes y los marca como
+// enviados. Si el proceso cae entre la publicación y la marca, el mensaje se
+// vuelve a publicar: la entrega es al menos una vez y el consumidor descarta duplicados.
+// Los mensajes que no se pueden publicar (payload inválido o MaxAttempts fallos)
+// se apartan como failed para que no se reintenten para siempre.
+type OutboxRelay struct {
+	DB          db.OutboxRepository
+	SQS         *queue.SQSClient
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+}
+
+func NewOutboxRelay(db db.OutboxRepository, sqs *queue.SQSClient, interval time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		DB:          db,
+		SQS:         sqs,
+		Interval:    interval,
+		BatchSize:   50,
+		MaxAttempts: DefaultOutboxMaxAttempts,
+	}
+}
+
+// Run publica los mensajes pendientes cada Interval hasta que se cancele ctx
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.RelayPending(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Error publicando mensajes de outbox: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending publica un lote de mensajes pendientes y devuelve cuántos publicó
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	pending, err := r.DB.GetPendingOutbox(ctx, r.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, outbox := range pending {
+		if r.relay(ctx, outbox) {
+			published++
+		}
+	}
+
+	return published, nil
+}
+
+// relay publica un mensaje de outbox y lo marca como enviado. La publicación
+// continúa la traza de la petición que creó el mensaje.
+func (r *OutboxRelay) relay(ctx context.Context, outbox model.OutboxMessage) bool {
+	ctx, span := tracing.Start(tracing.Extract(ctx, outbox.TraceContext), "outbox.relay",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("outbox.id", outbox.ID.String())))
+	defer span.End()
+
+	var msg queue.TicketReservationMessage
+	if err := json.Unmarshal([]byte(outbox.Payload), &msg); err != nil {
+		log.Printf("Mensaje de outbox %s con payload inválido: %v", outbox.ID, err)
+		span.SetStatus(codes.Error, err.Error())
+		r.park(ctx, outbox, fmt.Sprintf("payload inválido: %v", err))
+		return false
+	}
+
+	if err := r.SQS.SendReservationMessage(ctx, msg); err != nil {
+		log.Printf("Error publicando mensaje de outbox %s: %v", outbox.ID, err)
+		span.SetStatus(codes.Error, err.Error())
+		attempts, recordErr := r.DB.RecordOutboxAttempt(ctx, outbox.ID.String())
+		if recordErr != nil {
+			log.Printf("Error registrando intento del mensaje de outbox %s: %v", outbox.ID, recordErr)
+		} else if attempts >= r.MaxAttempts {
+			r.park(ctx, outbox, fmt.Sprintf("%d intentos fallidos, el último: %v", attempts, err))
+		}
+		return false
+	}
+
+	if err := r.DB.MarkOutboxSent(ctx, outbox.ID.String(), time.Now()); err != nil {
+		log.Printf("Error marcando mensaje de outbox %s como enviado: %v", outbox.ID, err)
+		span.SetStatus(codes.Error, err.Error())
+		return false
+	}
+	return true
+}
+
+// park aparta un mensaje que no se puede publicar
+func (r *OutboxRelay) park(ctx context.Context, outbox model.OutboxMessage, reason string) {
+	if err := r.DB.ParkOutbox(ctx, outbox.ID.String(), reason); err != nil {
+		log.Printf("Error apartando mensaje de outbox %s: %v", outbox.ID, err)
+		return
+	}
+	log.Printf("⚠️  Mensaje de outbox %s apartado como %s: %s", outbox.ID, model.OutboxStatusFailed, reason)
+}
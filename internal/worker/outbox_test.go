@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxRelay_ParksInvalidPayload(t *testing.T) {
+	ctx := context.Background()
+	repo := db.NewMemoryRepository()
+	now := time.Now()
+	event := model.Event{ID: uuid.New(), Name: "Concierto", TotalCapacity: 1, AvailableCapacity: 1, Status: model.EventStatusOpen, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.SaveEvent(ctx, event))
+
+	ticket := model.Ticket{ID: uuid.New(), EventID: event.ID, Status: model.TicketStatusReserved, CreatedAt: now, UpdatedAt: now}
+	outbox := model.OutboxMessage{ID: uuid.New(), AggregateID: ticket.ID.String(), Payload: "{no es json", Status: model.OutboxStatusPending, CreatedAt: now}
+	require.NoError(t, repo.CreateReservation(ctx, ticket, outbox))
+
+	// El payload se rechaza antes de llegar a SQS, así que el relay no necesita cliente
+	relay := NewOutboxRelay(repo, nil, time.Second)
+	published, err := relay.RelayPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	stored, err := repo.GetOutboxMessage(ctx, outbox.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.OutboxStatusFailed, stored.Status)
+	assert.Contains(t, stored.LastError, "payload inválido")
+
+	pending, err := repo.GetPendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
@@ -0,0 +1,237 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/jhonathanssegura/ticket-reservation/internal/queue"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+	"github.com/jhonathanssegura/ticket-reservation/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReservationWorker consume los mensajes de reserva de SQS: genera los archivos
+// del ticket y avisa al titular. No confirma la reserva: eso lo hace el cliente
+// con POST /api/reservations/:id/confirm antes de que venza.
+type ReservationWorker struct {
+	SQS         queue.ReservationQueue
+	DB          db.Repository
+	Artifacts   *service.TicketArtifactService
+	Notifier    service.Notifier
+	MaxMessages int32
+	// MaxReceiveCount es el número de entregas tras el cual un mensaje que
+	// sigue fallando se mueve a la cola de mensajes fallidos.
+	MaxReceiveCount int
+	// VisibilityTimeout es cuánto se extiende la visibilidad de un mensaje
+	// cada vez que su procesamiento se alarga. Debe coincidir con el de la
+	// recepción (sqs.visibility_timeout) para extenderla antes de que venza.
+	VisibilityTimeout time.Duration
+}
+
+// defaultVisibilityTimeout es el de una cola SQS recién creada
+const defaultVisibilityTimeout = 30 * time.Second
+
+// NewReservationWorker crea el worker. Un visibilityTimeout no positivo usa el
+// valor por defecto de SQS, ya que el heartbeat necesita un intervalo válido.
+func NewReservationWorker(sqs queue.ReservationQueue, db db.Repository, artifacts *service.TicketArtifactService, notifier service.Notifier, visibilityTimeout time.Duration) *ReservationWorker {
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = defaultVisibilityTimeout
+	}
+	return &ReservationWorker{
+		SQS:               sqs,
+		DB:                db,
+		Artifacts:         artifacts,
+		Notifier:          notifier,
+		MaxMessages:       10,
+		MaxReceiveCount:   5,
+		VisibilityTimeout: visibilityTimeout,
+	}
+}
+
+// Run consume la cola hasta que se cancele ctx. Al cancelarse deja de pedir
+// mensajes nuevos pero termina los que ya estaban en proceso antes de volver.
+func (w *ReservationWorker) Run(ctx context.Context) {
+	var inFlight sync.WaitGroup
+
+	for {
+		if ctx.Err() != nil {
+			log.Println("Deteniendo consumo de reservas, esperando mensajes en proceso...")
+			return
+		}
+
+		deliveries, err := w.SQS.ReceiveReservationMessages(ctx, w.MaxMessages)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			log.Printf("Error recibiendo mensajes de SQS: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for _, delivery := range deliveries {
+			inFlight.Add(1)
+			go func(d queue.ReservationDelivery) {
+				defer inFlight.Done()
+				// El procesamiento no hereda la cancelación de ctx para que un
+				// SIGTERM no deje un mensaje a medio procesar.
+				w.handle(context.WithoutCancel(ctx), d)
+			}(delivery)
+		}
+		inFlight.Wait()
+	}
+}
+
+func (w *ReservationWorker) handle(ctx context.Context, delivery queue.ReservationDelivery) {
+	// El procesamiento continúa la traza de la reserva que originó el mensaje
+	ctx, span := tracing.Start(delivery.ContextWithTrace(ctx), "reservation.process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "aws_sqs"),
+			attribute.String("messaging.message.id", delivery.MessageID),
+			attribute.Int("messaging.aws_sqs.receive_count", delivery.ReceiveCount),
+			attribute.String("reservation.id", delivery.Message.ReservationID),
+		))
+	defer span.End()
+
+	if delivery.DecodeErr != nil {
+		span.SetStatus(codes.Error, delivery.DecodeErr.Error())
+		log.Printf("Mensaje %s con formato inválido, se mueve a la DLQ: %v", delivery.MessageID, delivery.DecodeErr)
+		w.deadLetter(ctx, delivery, delivery.DecodeErr.Error())
+		return
+	}
+
+	stopHeartbeat := w.keepInvisible(ctx, delivery)
+	err := w.Process(ctx, delivery.Message)
+	stopHeartbeat()
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if delivery.ReceiveCount >= w.MaxReceiveCount {
+			log.Printf("Reserva %s falló %d veces, se mueve a la DLQ: %v", delivery.Message.ReservationID, delivery.ReceiveCount, err)
+			w.deadLetter(ctx, delivery, err.Error())
+			return
+		}
+		// El mensaje no se borra: SQS lo volverá a entregar al vencer su visibilidad
+		log.Printf("Error procesando reserva %s (mensaje %s, intento %d): %v",
+			delivery.Message.ReservationID, delivery.MessageID, delivery.ReceiveCount, err)
+		return
+	}
+
+	if err := w.SQS.DeleteReservationMessage(ctx, delivery.ReceiptHandle); err != nil {
+		log.Printf("Error borrando mensaje %s: %v", delivery.MessageID, err)
+	}
+}
+
+// deadLetter copia el mensaje a la DLQ y solo entonces lo borra de la cola principal,
+// de modo que un fallo al copiarlo no lo pierde.
+func (w *ReservationWorker) deadLetter(ctx context.Context, delivery queue.ReservationDelivery, reason string) {
+	if err := w.SQS.SendToDeadLetter(ctx, delivery, reason); err != nil {
+		log.Printf("Error moviendo mensaje %s a la DLQ: %v", delivery.MessageID, err)
+		return
+	}
+	if err := w.SQS.DeleteReservationMessage(ctx, delivery.ReceiptHandle); err != nil {
+		log.Printf("Error borrando mensaje %s tras moverlo a la DLQ: %v", delivery.MessageID, err)
+	}
+}
+
+// keepInvisible extiende periódicamente la visibilidad del mensaje mientras se
+// procesa para que un trabajo lento no se entregue a otro consumidor.
+func (w *ReservationWorker) keepInvisible(ctx context.Context, delivery queue.ReservationDelivery) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(w.VisibilityTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				seconds := int32(w.VisibilityTimeout / time.Second)
+				if err := w.SQS.ExtendVisibility(ctx, delivery.ReceiptHandle, seconds); err != nil {
+					log.Printf("Error extendiendo visibilidad del mensaje %s: %v", delivery.MessageID, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Process genera los archivos del ticket de la reserva y avisa al titular según
+// su estado: reserva apartada o, si ya la confirmó, ticket confirmado. Los
+// mensajes que vienen del outbox se marcan como procesados al terminar, así una
+// segunda entrega del mismo mensaje se descarta. Si la reserva ya no es válida
+// (cancelada o vencida) el mensaje se da por procesado.
+func (w *ReservationWorker) Process(ctx context.Context, msg queue.TicketReservationMessage) error {
+	if msg.MessageID != "" {
+		outbox, err := w.DB.GetOutboxMessage(ctx, msg.MessageID)
+		if err != nil && !errors.Is(err, db.ErrOutboxNotFound) {
+			return fmt.Errorf("error obteniendo mensaje de outbox: %w", err)
+		}
+		if outbox != nil && outbox.Status == model.OutboxStatusProcessed {
+			log.Printf("Mensaje %s ya procesado, se descarta el duplicado", msg.MessageID)
+			return nil
+		}
+	}
+
+	ticket, err := w.DB.GetTicketByID(ctx, msg.ReservationID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			log.Printf("Reserva %s no existe, se descarta el mensaje", msg.ReservationID)
+			return nil
+		}
+		return fmt.Errorf("error obteniendo ticket: %w", err)
+	}
+
+	notify := w.Notifier.NotifyTicketConfirmed
+	switch ticket.Status {
+	case model.TicketStatusReserved:
+		// La reserva vencida la cancela el barrido de reservas
+		if ticket.ExpiresAt != nil && !time.Now().Before(*ticket.ExpiresAt) {
+			log.Printf("Reserva %s vencida, no se generan sus archivos", ticket.ID)
+			return nil
+		}
+		notify = w.Notifier.NotifyReservationReceived
+	case model.TicketStatusConfirmed:
+		// El cliente confirmó antes de que llegara el mensaje
+	default:
+		log.Printf("Reserva %s en estado '%s', no se procesa", ticket.ID, ticket.Status)
+		return nil
+	}
+
+	// Sin el evento se genera el QR estático, como para cualquier evento sin QR rotativos
+	event, err := w.DB.GetEventByID(ctx, ticket.EventID.String())
+	if err != nil {
+		if !errors.Is(err, db.ErrEventNotFound) {
+			return fmt.Errorf("error obteniendo evento: %w", err)
+		}
+		event = nil
+	}
+
+	if _, _, err := w.Artifacts.Generate(ctx, *ticket, event); err != nil {
+		return fmt.Errorf("error generando archivos del ticket: %w", err)
+	}
+
+	if err := notify(ctx, *ticket); err != nil {
+		return fmt.Errorf("error notificando al titular: %w", err)
+	}
+
+	if msg.MessageID != "" {
+		if err := w.DB.MarkOutboxProcessed(ctx, msg.MessageID, time.Now()); err != nil && !errors.Is(err, db.ErrOutboxAlreadyProcessed) {
+			log.Printf("Error marcando mensaje %s como procesado: %v", msg.MessageID, err)
+		}
+	}
+
+	log.Printf("✅ Reserva %s procesada (%s)", ticket.ID, ticket.Status)
+	return nil
+}
@@ -0,0 +1,221 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhonathanssegura/ticket-reservation/internal/db"
+	"github.com/jhonathanssegura/ticket-reservation/internal/model"
+	"github.com/jhonathanssegura/ticket-reservation/internal/queue"
+	"github.com/jhonathanssegura/ticket-reservation/internal/service"
+	"github.com/jhonathanssegura/ticket-reservation/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueue registra las operaciones del worker sobre la cola
+type fakeQueue struct {
+	mu           sync.Mutex
+	deleted      []string
+	deadLettered []string
+}
+
+func (q *fakeQueue) ReceiveReservationMessages(ctx context.Context, maxMessages int32) ([]queue.ReservationDelivery, error) {
+	return nil, nil
+}
+
+func (q *fakeQueue) DeleteReservationMessage(ctx context.Context, receiptHandle string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deleted = append(q.deleted, receiptHandle)
+	return nil
+}
+
+func (q *fakeQueue) ExtendVisibility(ctx context.Context, receiptHandle string, seconds int32) error {
+	return nil
+}
+
+func (q *fakeQueue) SendToDeadLetter(ctx context.Context, delivery queue.ReservationDelivery, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deadLettered = append(q.deadLettered, delivery.MessageID)
+	return nil
+}
+
+// fakeNotifier registra los avisos enviados y puede fallar a pedido
+type fakeNotifier struct {
+	received  []uuid.UUID
+	confirmed []uuid.UUID
+	err       error
+}
+
+func (n *fakeNotifier) NotifyReservationReceived(ctx context.Context, ticket model.Ticket) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.received = append(n.received, ticket.ID)
+	return nil
+}
+
+func (n *fakeNotifier) NotifyTicketConfirmed(ctx context.Context, ticket model.Ticket) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.confirmed = append(n.confirmed, ticket.ID)
+	return nil
+}
+
+type workerFixture struct {
+	worker   *ReservationWorker
+	repo     *db.MemoryRepository
+	queue    *fakeQueue
+	notifier *fakeNotifier
+	store    storage.BlobStore
+	event    model.Event
+}
+
+func newWorkerFixture(t *testing.T) *workerFixture {
+	t.Helper()
+	signer, err := service.NewQRSigner("test", map[string][]byte{"test": bytes.Repeat([]byte("k"), 32)})
+	require.NoError(t, err)
+	store, err := storage.NewLocalStore(t.TempDir(), "http://localhost:8080/api/blobs", bytes.Repeat([]byte("s"), 32))
+	require.NoError(t, err)
+	artifacts := service.NewTicketArtifactService(store, service.NewQRService(signer))
+
+	repo := db.NewMemoryRepository()
+	now := time.Now()
+	event := model.Event{ID: uuid.New(), Name: "Concierto", Venue: "Estadio", StartTime: now.Add(24 * time.Hour), EndTime: now.Add(27 * time.Hour),
+		Timezone: "UTC", TotalCapacity: 5, AvailableCapacity: 5, Status: model.EventStatusOpen, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.SaveEvent(context.Background(), event))
+
+	q := &fakeQueue{}
+	notifier := &fakeNotifier{}
+	return &workerFixture{
+		worker:   NewReservationWorker(q, repo, artifacts, notifier, 30*time.Second),
+		repo:     repo,
+		queue:    q,
+		notifier: notifier,
+		store:    store,
+		event:    event,
+	}
+}
+
+// reserve crea una reserva con su mensaje de outbox y devuelve el mensaje que
+// el relay publicaría en la cola
+func (f *workerFixture) reserve(t *testing.T, expiresAt time.Time) (model.Ticket, queue.TicketReservationMessage) {
+	t.Helper()
+	now := time.Now()
+	ticket := model.Ticket{ID: uuid.New(), EventID: f.event.ID, UserID: uuid.New(), TicketCode: "TCK-" + uuid.NewString()[:8], Name: "Ana", Email: "ana@example.com",
+		Status: model.TicketStatusReserved, ReservedAt: now, ExpiresAt: &expiresAt, CreatedAt: now, UpdatedAt: now}
+	outbox := model.OutboxMessage{ID: uuid.New(), AggregateID: ticket.ID.String(), Status: model.OutboxStatusPending, CreatedAt: now}
+	require.NoError(t, f.repo.CreateReservation(context.Background(), ticket, outbox))
+	return ticket, queue.TicketReservationMessage{
+		MessageID:     outbox.ID.String(),
+		ReservationID: ticket.ID.String(),
+		UserID:        ticket.UserID.String(),
+		EventID:       f.event.ID.String(),
+		NumTickets:    1,
+	}
+}
+
+func TestReservationWorker_ProcessKeepsReservationHeld(t *testing.T) {
+	ctx := context.Background()
+	f := newWorkerFixture(t)
+	ticket, msg := f.reserve(t, time.Now().Add(10*time.Minute))
+
+	require.NoError(t, f.worker.Process(ctx, msg))
+
+	stored, err := f.repo.GetTicketByID(ctx, ticket.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusReserved, stored.Status, "la confirmación es del cliente, no del worker")
+	assert.Equal(t, []uuid.UUID{ticket.ID}, f.notifier.received)
+	assert.Empty(t, f.notifier.confirmed)
+
+	_, err = f.store.Head(ctx, service.QRKey(ticket))
+	assert.NoError(t, err)
+	_, err = f.store.Head(ctx, service.TicketFileKey(ticket))
+	assert.NoError(t, err)
+
+	outbox, err := f.repo.GetOutboxMessage(ctx, msg.MessageID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OutboxStatusProcessed, outbox.Status)
+
+	// Una segunda entrega del mismo mensaje se descarta
+	require.NoError(t, f.worker.Process(ctx, msg))
+	assert.Len(t, f.notifier.received, 1)
+}
+
+func TestReservationWorker_ProcessNotifiesConfirmedTicket(t *testing.T) {
+	ctx := context.Background()
+	f := newWorkerFixture(t)
+	ticket, msg := f.reserve(t, time.Now().Add(10*time.Minute))
+	require.NoError(t, f.repo.TransitionTicketStatus(ctx, ticket.ID.String(), model.TicketStatusReserved, model.TicketStatusConfirmed, time.Now()))
+
+	require.NoError(t, f.worker.Process(ctx, msg))
+
+	assert.Empty(t, f.notifier.received)
+	assert.Equal(t, []uuid.UUID{ticket.ID}, f.notifier.confirmed)
+}
+
+func TestReservationWorker_ProcessSkipsInvalidReservations(t *testing.T) {
+	ctx := context.Background()
+	f := newWorkerFixture(t)
+
+	cancelled, cancelledMsg := f.reserve(t, time.Now().Add(10*time.Minute))
+	require.NoError(t, f.repo.TransitionTicketStatus(ctx, cancelled.ID.String(), model.TicketStatusReserved, model.TicketStatusCancelled, time.Now()))
+	require.NoError(t, f.worker.Process(ctx, cancelledMsg))
+
+	expired, expiredMsg := f.reserve(t, time.Now().Add(-time.Minute))
+	require.NoError(t, f.worker.Process(ctx, expiredMsg))
+
+	stored, err := f.repo.GetTicketByID(ctx, expired.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.TicketStatusReserved, stored.Status, "la reserva vencida la cancela el barrido, no el worker")
+	assert.Empty(t, f.notifier.received)
+	assert.Empty(t, f.notifier.confirmed)
+}
+
+func TestNewReservationWorker_DefaultsVisibilityTimeout(t *testing.T) {
+	w := NewReservationWorker(&fakeQueue{}, db.NewMemoryRepository(), nil, &fakeNotifier{}, 0)
+	assert.Equal(t, defaultVisibilityTimeout, w.VisibilityTimeout)
+}
+
+func TestReservationWorker_HandleDeletesProcessedMessage(t *testing.T) {
+	f := newWorkerFixture(t)
+	_, msg := f.reserve(t, time.Now().Add(10*time.Minute))
+
+	f.worker.handle(context.Background(), queue.ReservationDelivery{MessageID: "m-1", ReceiptHandle: "rh-1", ReceiveCount: 1, Message: msg})
+
+	assert.Equal(t, []string{"rh-1"}, f.queue.deleted)
+	assert.Empty(t, f.queue.deadLettered)
+}
+
+func TestReservationWorker_HandleDeadLettersInvalidMessage(t *testing.T) {
+	f := newWorkerFixture(t)
+
+	f.worker.handle(context.Background(), queue.ReservationDelivery{MessageID: "m-1", ReceiptHandle: "rh-1", ReceiveCount: 1,
+		Body: "{no es json", DecodeErr: errors.New("cuerpo inválido")})
+
+	assert.Equal(t, []string{"m-1"}, f.queue.deadLettered)
+	assert.Equal(t, []string{"rh-1"}, f.queue.deleted)
+}
+
+func TestReservationWorker_HandleRetriesUntilMaxReceiveCount(t *testing.T) {
+	f := newWorkerFixture(t)
+	f.notifier.err = errors.New("smtp caído")
+	_, msg := f.reserve(t, time.Now().Add(10*time.Minute))
+
+	// Antes del límite el mensaje se deja en la cola para que SQS lo reentregue
+	f.worker.handle(context.Background(), queue.ReservationDelivery{MessageID: "m-1", ReceiptHandle: "rh-1", ReceiveCount: 1, Message: msg})
+	assert.Empty(t, f.queue.deleted)
+	assert.Empty(t, f.queue.deadLettered)
+
+	f.worker.handle(context.Background(), queue.ReservationDelivery{MessageID: "m-1", ReceiptHandle: "rh-2", ReceiveCount: f.worker.MaxReceiveCount, Message: msg})
+	assert.Equal(t, []string{"m-1"}, f.queue.deadLettered)
+	assert.Equal(t, []string{"rh-2"}, f.queue.deleted)
+}
@@ -23,14 +23,115 @@ if [ -z "$table_exists" ]; then
   echo "📝 Creando tabla DynamoDB 'tickets'..."
   aws $AWS_ENDPOINT dynamodb create-table \
     --table-name tickets \
-    --attribute-definitions AttributeName=id,AttributeType=S \
+    --attribute-definitions \
+      AttributeName=id,AttributeType=S \
+      AttributeName=event_id,AttributeType=S \
+      AttributeName=email,AttributeType=S \
+      AttributeName=created_at,AttributeType=S \
+      AttributeName=status,AttributeType=S \
+      AttributeName=expires_at,AttributeType=S \
     --key-schema AttributeName=id,KeyType=HASH \
-    --provisioned-throughput ReadCapacityUnits=5,WriteCapacityUnits=5
+    --provisioned-throughput ReadCapacityUnits=5,WriteCapacityUnits=5 \
+    --global-secondary-indexes \
+      "IndexName=event_id-index,KeySchema=[{AttributeName=event_id,KeyType=HASH},{AttributeName=created_at,KeyType=RANGE}],Projection={ProjectionType=ALL},ProvisionedThroughput={ReadCapacityUnits=5,WriteCapacityUnits=5}" \
+      "IndexName=email-index,KeySchema=[{AttributeName=email,KeyType=HASH},{AttributeName=created_at,KeyType=RANGE}],Projection={ProjectionType=ALL},ProvisionedThroughput={ReadCapacityUnits=5,WriteCapacityUnits=5}" \
+      "IndexName=status-expires_at-index,KeySchema=[{AttributeName=status,KeyType=HASH},{AttributeName=expires_at,KeyType=RANGE}],Projection={ProjectionType=ALL},ProvisionedThroughput={ReadCapacityUnits=5,WriteCapacityUnits=5}"
   echo "✅ Tabla DynamoDB 'tickets' creada exitosamente"
 else
   echo "✅ La tabla DynamoDB 'tickets' ya existe."
 fi
 
+# Las tablas creadas antes de los índices secundarios se actualizan en el lugar
+for index in event_id email; do
+  index_exists=$(aws $AWS_ENDPOINT dynamodb describe-table --table-name tickets 2>/dev/null | grep "\"$index-index\"" || true)
+  if [ -z "$index_exists" ]; then
+    echo "📝 Creando índice '$index-index' en la tabla 'tickets'..."
+    aws $AWS_ENDPOINT dynamodb update-table \
+      --table-name tickets \
+      --attribute-definitions AttributeName=$index,AttributeType=S AttributeName=created_at,AttributeType=S \
+      --global-secondary-index-updates \
+        "[{\"Create\":{\"IndexName\":\"$index-index\",\"KeySchema\":[{\"AttributeName\":\"$index\",\"KeyType\":\"HASH\"},{\"AttributeName\":\"created_at\",\"KeyType\":\"RANGE\"}],\"Projection\":{\"ProjectionType\":\"ALL\"},\"ProvisionedThroughput\":{\"ReadCapacityUnits\":5,\"WriteCapacityUnits\":5}}}]"
+    echo "✅ Índice '$index-index' creado exitosamente"
+  fi
+done
+
+# El barrido de reservas vencidas consulta este índice; solo incluye los tickets con expires_at
+expiry_index_exists=$(aws $AWS_ENDPOINT dynamodb describe-table --table-name tickets 2>/dev/null | grep '"status-expires_at-index"' || true)
+if [ -z "$expiry_index_exists" ]; then
+  echo "📝 Creando índice 'status-expires_at-index' en la tabla 'tickets'..."
+  aws $AWS_ENDPOINT dynamodb update-table \
+    --table-name tickets \
+    --attribute-definitions AttributeName=status,AttributeType=S AttributeName=expires_at,AttributeType=S \
+    --global-secondary-index-updates \
+      "[{\"Create\":{\"IndexName\":\"status-expires_at-index\",\"KeySchema\":[{\"AttributeName\":\"status\",\"KeyType\":\"HASH\"},{\"AttributeName\":\"expires_at\",\"KeyType\":\"RANGE\"}],\"Projection\":{\"ProjectionType\":\"ALL\"},\"ProvisionedThroughput\":{\"ReadCapacityUnits\":5,\"WriteCapacityUnits\":5}}}]"
+  echo "✅ Índice 'status-expires_at-index' creado exitosamente"
+fi
+
+events_table_exists=$(aws $AWS_ENDPOINT dynamodb list-tables 2>/dev/null | grep '"events"' || true)
+if [ -z "$events_table_exists" ]; then
+  echo "📝 Creando tabla DynamoDB 'events'..."
+  aws $AWS_ENDPOINT dynamodb create-table \
+    --table-name events \
+    --attribute-definitions AttributeName=id,AttributeType=S \
+    --key-schema AttributeName=id,KeyType=HASH \
+    --provisioned-throughput ReadCapacityUnits=5,WriteCapacityUnits=5
+  echo "✅ Tabla DynamoDB 'events' creada exitosamente"
+else
+  echo "✅ La tabla DynamoDB 'events' ya existe."
+fi
+
+outbox_table_exists=$(aws $AWS_ENDPOINT dynamodb list-tables 2>/dev/null | grep '"outbox"' || true)
+if [ -z "$outbox_table_exists" ]; then
+  echo "📝 Creando tabla DynamoDB 'outbox'..."
+  aws $AWS_ENDPOINT dynamodb create-table \
+    --table-name outbox \
+    --attribute-definitions AttributeName=id,AttributeType=S AttributeName=pending,AttributeType=S AttributeName=created_at,AttributeType=S \
+    --key-schema AttributeName=id,KeyType=HASH \
+    --global-secondary-indexes \
+      "[{\"IndexName\":\"pending-created_at-index\",\"KeySchema\":[{\"AttributeName\":\"pending\",\"KeyType\":\"HASH\"},{\"AttributeName\":\"created_at\",\"KeyType\":\"RANGE\"}],\"Projection\":{\"ProjectionType\":\"ALL\"},\"ProvisionedThroughput\":{\"ReadCapacityUnits\":5,\"WriteCapacityUnits\":5}}]" \
+    --provisioned-throughput ReadCapacityUnits=5,WriteCapacityUnits=5
+  echo "✅ Tabla DynamoDB 'outbox' creada exitosamente"
+else
+  echo "✅ La tabla DynamoDB 'outbox' ya existe."
+fi
+
+# El relay consulta solo los mensajes pendientes con este índice disperso
+pending_index_exists=$(aws $AWS_ENDPOINT dynamodb describe-table --table-name outbox 2>/dev/null | grep '"pending-created_at-index"' || true)
+if [ -z "$pending_index_exists" ]; then
+  echo "📝 Creando índice 'pending-created_at-index' en la tabla 'outbox'..."
+  aws $AWS_ENDPOINT dynamodb update-table \
+    --table-name outbox \
+    --attribute-definitions AttributeName=pending,AttributeType=S AttributeName=created_at,AttributeType=S \
+    --global-secondary-index-updates \
+      "[{\"Create\":{\"IndexName\":\"pending-created_at-index\",\"KeySchema\":[{\"AttributeName\":\"pending\",\"KeyType\":\"HASH\"},{\"AttributeName\":\"created_at\",\"KeyType\":\"RANGE\"}],\"Projection\":{\"ProjectionType\":\"ALL\"},\"ProvisionedThroughput\":{\"ReadCapacityUnits\":5,\"WriteCapacityUnits\":5}}}]"
+  echo "✅ Índice 'pending-created_at-index' creado exitosamente"
+fi
+
+# Los mensajes procesados se borran solos pasados 7 días
+outbox_ttl=$(aws $AWS_ENDPOINT dynamodb describe-time-to-live --table-name outbox 2>/dev/null | grep '"ENABLED"' || true)
+if [ -z "$outbox_ttl" ]; then
+  aws $AWS_ENDPOINT dynamodb update-time-to-live \
+    --table-name outbox \
+    --time-to-live-specification Enabled=true,AttributeName=expires_at
+  echo "✅ TTL de la tabla 'outbox' activado"
+fi
+
+idempotency_table_exists=$(aws $AWS_ENDPOINT dynamodb list-tables 2>/dev/null | grep '"idempotency"' || true)
+if [ -z "$idempotency_table_exists" ]; then
+  echo "📝 Creando tabla DynamoDB 'idempotency'..."
+  aws $AWS_ENDPOINT dynamodb create-table \
+    --table-name idempotency \
+    --attribute-definitions AttributeName=id,AttributeType=S \
+    --key-schema AttributeName=id,KeyType=HASH \
+    --provisioned-throughput ReadCapacityUnits=5,WriteCapacityUnits=5
+  aws $AWS_ENDPOINT dynamodb update-time-to-live \
+    --table-name idempotency \
+    --time-to-live-specification Enabled=true,AttributeName=expires_at
+  echo "✅ Tabla DynamoDB 'idempotency' creada exitosamente"
+else
+  echo "✅ La tabla DynamoDB 'idempotency' ya existe."
+fi
+
 # Crear bucket S3 solo si no existe
 echo "☁️ Configurando bucket S3..."
 # Intentar listar el bucket específico
@@ -42,9 +143,23 @@ else
   echo "✅ Bucket S3 'ticket-bucket' creado exitosamente"
 fi
 
+# Crear cola de mensajes fallidos (DLQ) solo si no existe
+echo "📬 Configurando colas SQS..."
+dlq_exists=$(aws $AWS_ENDPOINT sqs list-queues 2>/dev/null | grep 'ticket-queue-dlq' || true)
+if [ -z "$dlq_exists" ]; then
+  echo "📝 Creando cola SQS 'ticket-queue-dlq'..."
+  aws $AWS_ENDPOINT sqs create-queue --queue-name ticket-queue-dlq
+  echo "✅ Cola SQS 'ticket-queue-dlq' creada exitosamente"
+else
+  echo "✅ La cola SQS 'ticket-queue-dlq' ya existe."
+fi
+
+dlq_arn=$(aws $AWS_ENDPOINT sqs get-queue-attributes \
+  --queue-url http://localhost:4566/000000000000/ticket-queue-dlq \
+  --attribute-names QueueArn --query 'Attributes.QueueArn' --output text)
+
 # Crear cola SQS solo si no existe
-echo "📬 Configurando cola SQS..."
-queue_exists=$(aws $AWS_ENDPOINT sqs list-queues 2>/dev/null | grep 'ticket-queue' || true)
+queue_exists=$(aws $AWS_ENDPOINT sqs list-queues 2>/dev/null | grep 'ticket-queue"' || true)
 if [ -z "$queue_exists" ]; then
   echo "📝 Creando cola SQS 'ticket-queue'..."
   aws $AWS_ENDPOINT sqs create-queue --queue-name ticket-queue
@@ -53,6 +168,12 @@ else
   echo "✅ La cola SQS 'ticket-queue' ya existe."
 fi
 
+# Tras 5 entregas fallidas SQS mueve el mensaje a la DLQ
+echo "📝 Configurando redrive policy de 'ticket-queue'..."
+aws $AWS_ENDPOINT sqs set-queue-attributes \
+  --queue-url http://localhost:4566/000000000000/ticket-queue \
+  --attributes "{\"RedrivePolicy\":\"{\\\"deadLetterTargetArn\\\":\\\"$dlq_arn\\\",\\\"maxReceiveCount\\\":\\\"5\\\"}\",\"VisibilityTimeout\":\"30\"}"
+
 # Verificar configuración
 echo "🔍 Verificando configuración..."
 echo "📊 Tablas DynamoDB:"
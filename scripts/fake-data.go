@@ -11,18 +11,23 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
 	"github.com/jhonathanssegura/ticket-reservation/internal/awsconfig"
+	"github.com/jhonathanssegura/ticket-reservation/internal/config"
 	"github.com/jhonathanssegura/ticket-reservation/internal/model"
 )
 
 func main() {
-	// Cargar configuración AWS
-	cfg, err := awsconfig.LoadAWSConfig()
+	// Cargar configuración (mismas variables y CONFIG_FILE que la API)
+	appCfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Error cargando configuración: %v", err)
+	}
+	cfg, err := awsconfig.LoadAWSConfig(context.TODO(), appCfg.AWS)
 	if err != nil {
 		log.Fatalf("Error cargando configuración AWS: %v", err)
 	}
 
 	// Crear cliente DynamoDB
-	dynamoClient := dynamodb.NewFromConfig(cfg)
+	dynamoClient := awsconfig.NewDynamoDBClient(cfg, appCfg.AWS)
 
 	// Generar UUIDs para eventos
 	eventIDs := map[string]uuid.UUID{
@@ -188,7 +193,7 @@ func main() {
 		}
 
 		_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
-			TableName: aws.String("tickets"),
+			TableName: aws.String(appCfg.DynamoDB.TicketsTable),
 			Item:      item,
 		})
 
@@ -199,6 +204,42 @@ func main() {
 		}
 	}
 
+	// Insertar eventos con su capacidad ya descontada por los tickets de prueba
+	eventNames := map[string]string{
+		"evt-concierto-rock":   "Concierto de Rock",
+		"evt-teatro-clasico":   "Teatro Clásico",
+		"evt-deportes-futbol":  "Partido de Fútbol",
+		"evt-cine-estreno":     "Estreno de Cine",
+		"evt-conferencia-tech": "Conferencia Tech",
+	}
+	fmt.Printf("📊 Insertando %d eventos...\n", len(eventIDs))
+	for key, eventID := range eventIDs {
+		start := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Hour)
+		item := map[string]types.AttributeValue{
+			"id":                 &types.AttributeValueMemberS{Value: eventID.String()},
+			"name":               &types.AttributeValueMemberS{Value: eventNames[key]},
+			"venue":              &types.AttributeValueMemberS{Value: "Auditorio Central"},
+			"start_time":         &types.AttributeValueMemberS{Value: start.Format(time.RFC3339)},
+			"end_time":           &types.AttributeValueMemberS{Value: start.Add(3 * time.Hour).Format(time.RFC3339)},
+			"timezone":           &types.AttributeValueMemberS{Value: "America/Bogota"},
+			"total_capacity":     &types.AttributeValueMemberN{Value: "100"},
+			"available_capacity": &types.AttributeValueMemberN{Value: "98"},
+			"hold_minutes":       &types.AttributeValueMemberN{Value: "15"},
+			"status":             &types.AttributeValueMemberS{Value: model.EventStatusOpen},
+			"created_at":         &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+			"updated_at":         &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		}
+		_, err = dynamoClient.PutItem(context.TODO(), &dynamodb.PutItemInput{
+			TableName: aws.String(appCfg.DynamoDB.EventsTable),
+			Item:      item,
+		})
+		if err != nil {
+			log.Printf("Error insertando evento %s: %v", key, err)
+		} else {
+			fmt.Printf("✅ Evento %s insertado correctamente\n", key)
+		}
+	}
+
 	fmt.Println("\n🎉 Datos de prueba cargados exitosamente!")
 	fmt.Println("\n📋 Resumen de datos cargados:")
 	fmt.Println("   • 10 tickets de prueba")
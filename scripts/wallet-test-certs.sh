@@ -0,0 +1,43 @@
+#!/bin/bash
+set -euo pipefail
+
+# Genera certificados autofirmados para probar Apple Wallet y Google Wallet sin
+# conexión. Los pases firmados así no se instalan en un iPhone real, pero la
+# estructura y la firma del .pkpass se pueden verificar localmente.
+
+OUT_DIR="${1:-certs/wallet}"
+mkdir -p "$OUT_DIR"
+
+echo "🔐 Generando CA de prueba (reemplaza al certificado WWDR de Apple)..."
+openssl req -x509 -newkey rsa:2048 -nodes -days 365 \
+    -subj "/CN=Test WWDR" \
+    -keyout "$OUT_DIR/wwdr.key" -out "$OUT_DIR/wwdr.pem"
+
+echo "🎫 Generando certificado del Pass Type ID..."
+openssl req -newkey rsa:2048 -nodes \
+    -subj "/CN=Pass Type ID: pass.com.example.tickets" \
+    -keyout "$OUT_DIR/pass.key" -out "$OUT_DIR/pass.csr"
+openssl x509 -req -days 365 -in "$OUT_DIR/pass.csr" \
+    -CA "$OUT_DIR/wwdr.pem" -CAkey "$OUT_DIR/wwdr.key" -CAcreateserial \
+    -out "$OUT_DIR/pass.pem"
+
+echo "🤖 Generando cuenta de servicio de prueba para Google Wallet..."
+openssl genpkey -algorithm RSA -pkeyopt rsa_keygen_bits:2048 -out "$OUT_DIR/google.key"
+PRIVATE_KEY=$(awk '{printf "%s\\n", $0}' "$OUT_DIR/google.key")
+cat > "$OUT_DIR/google-service-account.json" <<JSON
+{
+  "type": "service_account",
+  "client_email": "wallet-test@example.iam.gserviceaccount.com",
+  "private_key": "$PRIVATE_KEY"
+}
+JSON
+
+echo "✅ Certificados generados en $OUT_DIR"
+echo ""
+echo "export PKPASS_CERT_FILE=$OUT_DIR/pass.pem"
+echo "export PKPASS_KEY_FILE=$OUT_DIR/pass.key"
+echo "export PKPASS_WWDR_FILE=$OUT_DIR/wwdr.pem"
+echo "export PKPASS_TYPE_ID=pass.com.example.tickets"
+echo "export PKPASS_TEAM_ID=TEAM123456"
+echo "export GOOGLE_WALLET_KEY_FILE=$OUT_DIR/google-service-account.json"
+echo "export GOOGLE_WALLET_ISSUER_ID=3388000000000000000"